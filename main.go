@@ -32,6 +32,7 @@ var templateFiles = []TemplateFile{
 	{"main.go", "main.go"},
 	{"go.mod", "go.mod"},
 	{"cmd_root.go", "cmd/root.go"},
+	{"cmd_commands.go", "cmd/commands.go"},
 	{"env_local", ".env.local"},
 	{"gitignore", ".gitignore"},
 	{"github_yml", ".github/workflows/ci.yml"},
@@ -40,9 +41,31 @@ var templateFiles = []TemplateFile{
 
 	// Configuration
 	{"internal_conf_vars.go", "internal/conf/vars.go"},
+	{"internal_conf_loader.go", "internal/conf/loader.go"},
+	{"internal_conf_sources.go", "internal/conf/sources.go"},
+	{"internal_conf_secrets.go", "internal/conf/secrets.go"},
+	{"internal_conf_reload.go", "internal/conf/reload.go"},
+	{"internal_conf_cors.go", "internal/conf/cors.go"},
+	{"internal_conf_audit.go", "internal/conf/audit.go"},
 	{"internal_conf_pg.go", "internal/conf/pg.go"},
+	{"internal_conf_gorm_logger.go", "internal/conf/gorm_logger.go"},
 	{"internal_conf_dependencies.go", "internal/conf/dependencies.go"},
 
+	// Database migrations
+	{"internal_migrations_migrations.go", "internal/migrations/migrations.go"},
+	{"internal_migrations_sql_000001_create_organizations.up.sql", "internal/migrations/sql/000001_create_organizations.up.sql"},
+	{"internal_migrations_sql_000001_create_organizations.down.sql", "internal/migrations/sql/000001_create_organizations.down.sql"},
+	{"internal_migrations_sql_000002_create_users.up.sql", "internal/migrations/sql/000002_create_users.up.sql"},
+	{"internal_migrations_sql_000002_create_users.down.sql", "internal/migrations/sql/000002_create_users.down.sql"},
+	{"internal_migrations_sql_000003_soft_delete.up.sql", "internal/migrations/sql/000003_soft_delete.up.sql"},
+	{"internal_migrations_sql_000003_soft_delete.down.sql", "internal/migrations/sql/000003_soft_delete.down.sql"},
+	{"internal_migrations_sql_000004_create_organization_memberships.up.sql", "internal/migrations/sql/000004_create_organization_memberships.up.sql"},
+	{"internal_migrations_sql_000004_create_organization_memberships.down.sql", "internal/migrations/sql/000004_create_organization_memberships.down.sql"},
+	{"internal_migrations_sql_000005_create_audit_events.up.sql", "internal/migrations/sql/000005_create_audit_events.up.sql"},
+	{"internal_migrations_sql_000005_create_audit_events.down.sql", "internal/migrations/sql/000005_create_audit_events.down.sql"},
+	{"internal_migrations_sql_000006_add_row_versions.up.sql", "internal/migrations/sql/000006_add_row_versions.up.sql"},
+	{"internal_migrations_sql_000006_add_row_versions.down.sql", "internal/migrations/sql/000006_add_row_versions.down.sql"},
+
 	// Shared utilities
 	{"internal_shared_logger_logger.go", "internal/shared/logger/logger.go"},
 	{"internal_shared_validation_validation.go", "internal/shared/validation/validation.go"},
@@ -51,6 +74,38 @@ var templateFiles = []TemplateFile{
 	{"internal_shared_uuid_uuid.go", "internal/shared/uuid/uuid.go"},
 	{"internal_shared_assertions_assertions.go", "internal/shared/assertions/assertions.go"},
 	{"internal_shared_middleware_middleware.go", "internal/shared/middleware/middleware.go"},
+	{"internal_shared_lifecycle_lifecycle.go", "internal/shared/lifecycle/lifecycle.go"},
+	{"internal_shared_tlsconfig_tlsconfig.go", "internal/shared/tlsconfig/tlsconfig.go"},
+	{"internal_shared_admin_admin.go", "internal/shared/admin/admin.go"},
+	{"internal_shared_buildinfo_buildinfo.go", "internal/shared/buildinfo/buildinfo.go"},
+	{"internal_shared_replicas_replicas.go", "internal/shared/replicas/replicas.go"},
+	{"internal_shared_transaction_transaction.go", "internal/shared/transaction/transaction.go"},
+	{"internal_shared_pagination_pagination.go", "internal/shared/pagination/pagination.go"},
+	{"internal_shared_concurrency_concurrency.go", "internal/shared/concurrency/concurrency.go"},
+
+	// Configuration tests
+	{"internal_tests_conf_loader_test.go", "internal/tests/conf/loader_test.go"},
+	{"internal_tests_conf_sources_test.go", "internal/tests/conf/sources_test.go"},
+	{"internal_tests_conf_secrets_test.go", "internal/tests/conf/secrets_test.go"},
+	{"internal_tests_conf_reload_test.go", "internal/tests/conf/reload_test.go"},
+	{"internal_tests_conf_cors_test.go", "internal/tests/conf/cors_test.go"},
+	{"internal_tests_conf_audit_test.go", "internal/tests/conf/audit_test.go"},
+	{"internal_tests_conf_pg_test.go", "internal/tests/conf/pg_test.go"},
+	{"internal_tests_conf_gorm_logger_test.go", "internal/tests/conf/gorm_logger_test.go"},
+
+	// Migration tests
+	{"internal_tests_migrations_migrations_test.go", "internal/tests/migrations/migrations_test.go"},
+	{"internal_tests_users_datasource_soft_delete_test.go", "internal/tests/users/datasource/soft_delete_test.go"},
+	{"internal_tests_users_datasource_list_test.go", "internal/tests/users/datasource/list_test.go"},
+	{"internal_tests_users_datasource_profile_test.go", "internal/tests/users/datasource/profile_test.go"},
+	{"internal_tests_users_datasource_update_test.go", "internal/tests/users/datasource/update_test.go"},
+	{"internal_tests_users_models_profile_test.go", "internal/tests/users/models/profile_test.go"},
+	{"internal_tests_organizations_models_slug_test.go", "internal/tests/organizations/models/slug_test.go"},
+	{"internal_tests_organizations_service_management_test.go", "internal/tests/organizations/service/management_test.go"},
+	{"internal_tests_memberships_datasource_datasource_test.go", "internal/tests/memberships/datasource/datasource_test.go"},
+	{"internal_tests_memberships_service_service_test.go", "internal/tests/memberships/service/service_test.go"},
+	{"internal_tests_audit_models_audit_test.go", "internal/tests/audit/models/audit_test.go"},
+	{"internal_tests_audit_service_service_test.go", "internal/tests/audit/service/service_test.go"},
 
 	// Shared utilities tests
 	{"internal_tests_shared_assertions_assertions_test.go", "internal/tests/shared/assertions/assertions_test.go"},
@@ -60,6 +115,16 @@ var templateFiles = []TemplateFile{
 	{"internal_tests_shared_http_http_test.go", "internal/tests/shared/http/http_test.go"},
 	{"internal_tests_shared_uuid_uuid_test.go", "internal/tests/shared/uuid/uuid_test.go"},
 	{"internal_tests_shared_middleware_middleware_test.go", "internal/tests/shared/middleware/middleware_test.go"},
+	{"internal_tests_shared_lifecycle_lifecycle_test.go", "internal/tests/shared/lifecycle/lifecycle_test.go"},
+	{"internal_tests_shared_tlsconfig_tlsconfig_test.go", "internal/tests/shared/tlsconfig/tlsconfig_test.go"},
+	{"internal_tests_shared_admin_admin_test.go", "internal/tests/shared/admin/admin_test.go"},
+	{"internal_tests_shared_buildinfo_buildinfo_test.go", "internal/tests/shared/buildinfo/buildinfo_test.go"},
+	{"internal_tests_shared_replicas_replicas_test.go", "internal/tests/shared/replicas/replicas_test.go"},
+	{"internal_tests_shared_transaction_transaction_test.go", "internal/tests/shared/transaction/transaction_test.go"},
+	{"internal_tests_shared_pagination_pagination_test.go", "internal/tests/shared/pagination/pagination_test.go"},
+	{"internal_tests_shared_concurrency_concurrency_test.go", "internal/tests/shared/concurrency/concurrency_test.go"},
+	{"internal_tests_testutil_pool.go", "internal/tests/testutil/pool.go"},
+	{"internal_tests_health_service_status_test.go", "internal/tests/health/service/status_test.go"},
 
 	// Handlers
 	{"internal_handlers_handlers.go", "internal/handlers/handlers.go"},
@@ -77,9 +142,24 @@ var templateFiles = []TemplateFile{
 
 	// Organizations module
 	{"internal_organizations_models_organizations.go", "internal/organizations/models/organizations.go"},
+	{"internal_organizations_models_slug.go", "internal/organizations/models/slug.go"},
+	{"internal_organizations_identity_identity.go", "internal/organizations/identity/identity.go"},
 	{"internal_organizations_datasource_datasource.go", "internal/organizations/datasource/datasource.go"},
 	{"internal_organizations_service_service.go", "internal/organizations/service/service.go"},
+	{"internal_organizations_service_management.go", "internal/organizations/service/management.go"},
 	{"internal_organizations_controller_controller.go", "internal/organizations/controller/controller.go"},
+
+	// Memberships module
+	{"internal_memberships_models_memberships.go", "internal/memberships/models/memberships.go"},
+	{"internal_memberships_datasource_datasource.go", "internal/memberships/datasource/datasource.go"},
+	{"internal_memberships_service_service.go", "internal/memberships/service/service.go"},
+	{"internal_memberships_controller_controller.go", "internal/memberships/controller/controller.go"},
+
+	// Audit module
+	{"internal_audit_models_audit.go", "internal/audit/models/audit.go"},
+	{"internal_audit_datasource_datasource.go", "internal/audit/datasource/datasource.go"},
+	{"internal_audit_service_service.go", "internal/audit/service/service.go"},
+	{"internal_audit_controller_controller.go", "internal/audit/controller/controller.go"},
 }
 
 func main() {
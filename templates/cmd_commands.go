@@ -0,0 +1,386 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"{{.Module}}/internal/conf"
+	"{{.Module}}/internal/migrations"
+	"{{.Module}}/internal/shared/buildinfo"
+	"{{.Module}}/internal/shared/uuid"
+
+	organizationsModels "{{.Module}}/internal/organizations/models"
+	usersModels "{{.Module}}/internal/users/models"
+
+	"github.com/gorilla/mux"
+	"github.com/joho/godotenv"
+)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) int
+}
+
+var commands = []command{
+	{"serve", "serve [config flags]", "Start the HTTP server (default)", serveCommand},
+	{"migrate", "migrate up|down|status|create [flags]", "Apply, revert, inspect or create SQL migrations", migrateCommand},
+	{"seed", "seed [config flags]", "Insert sample data for local development", seedCommand},
+	{"config", "config print|validate|audit|encrypt-secrets|decrypt-secrets [flags]", "Print, validate or audit the configuration, or manage the encrypted secrets file", configCommand},
+	{"version", "version", "Print build information", versionCommand},
+	{"routes", "routes [config flags]", "List every registered HTTP route", routesCommand},
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: {{.Name}} <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, command := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", command.usage, command.summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Every config variable can be overridden with a flag, e.g. --server-port 9090.")
+	fmt.Fprintln(w, "Run '{{.Name}} <command> -h' for the flags of a command.")
+}
+
+// subcommand splits `<sub> [flags]` and prints usage when the subcommand is not one of valid
+func subcommand(name string, args []string, valid ...string) (string, []string, bool) {
+	if len(args) > 0 {
+		for _, sub := range valid {
+			if args[0] == sub {
+				return sub, args[1:], true
+			}
+		}
+	}
+	fmt.Fprintf(os.Stderr, "usage: {{.Name}} %s %s\n", name, strings.Join(valid, "|"))
+	return "", nil, false
+}
+
+func serveCommand(args []string) int {
+	flags, configFlags := newConfigFlags("serve")
+	root, code, ok := loadCommandConfig(flags, configFlags, args)
+	if !ok {
+		return code
+	}
+	return root.serve()
+}
+
+func migrateCommand(args []string) int {
+	action, args, ok := subcommand("migrate", args, "up", "down", "status", "create")
+	if !ok {
+		return exitUsage
+	}
+	if action == "create" {
+		return migrateCreate(args)
+	}
+
+	flags, configFlags := newConfigFlags("migrate " + action)
+	steps := 1
+	if action == "down" {
+		flags.IntVar(&steps, "steps", 1, "number of migrations to revert")
+	}
+	root, code, ok := loadCommandConfig(flags, configFlags, args)
+	if !ok {
+		return code
+	}
+	if steps < 1 {
+		fmt.Fprintln(os.Stderr, "--steps must be at least 1")
+		return exitUsage
+	}
+
+	embedded, err := migrations.Embedded()
+	if err != nil {
+		root.Logger.Error("failed to load migrations", "error", err)
+		return exitError
+	}
+	if err := root.loadDatabase(context.Background()); err != nil {
+		return exitError
+	}
+	defer root.closeDatabase()
+
+	sqlDB, err := root.DB.DB()
+	if err != nil {
+		root.Logger.Error("failed to get sql db", "error", err)
+		return exitError
+	}
+	migrator := migrations.NewMigrator(sqlDB, root.Logger, embedded)
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			root.Logger.Error("migration failed", "error", err)
+			return exitError
+		}
+		root.Logger.Info("migrations applied", "count", len(applied))
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			root.Logger.Error("rollback failed", "error", err)
+			return exitError
+		}
+		root.Logger.Info("migrations rolled back", "count", len(reverted))
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			root.Logger.Error("failed to read migration status", "error", err)
+			return exitError
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state, appliedAt := "pending", ""
+			if status.Applied {
+				state, appliedAt = "applied", status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Modified {
+				state = "modified"
+			}
+			fmt.Fprintf(tw, "%06d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		tw.Flush()
+	}
+
+	return exitOK
+}
+
+// migrateCreate writes the next numbered up/down pair; it needs no configuration
+func migrateCreate(args []string) int {
+	flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := flags.String("dir", migrations.Dir, "directory holding the migration files")
+
+	// Accept the name before or after the flags
+	name := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if name == "" && flags.NArg() > 0 {
+		name = flags.Arg(0)
+	}
+	if name == "" {
+		fmt.Fprintln(os.Stderr, "usage: {{.Name}} migrate create <name> [--dir path]")
+		return exitUsage
+	}
+
+	upPath, downPath, err := migrations.Create(*dir, name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	fmt.Println("created", upPath)
+	fmt.Println("created", downPath)
+	return exitOK
+}
+
+func seedCommand(args []string) int {
+	flags, configFlags := newConfigFlags("seed")
+	root, code, ok := loadCommandConfig(flags, configFlags, args)
+	if !ok {
+		return code
+	}
+	if err := root.loadDatabase(context.Background()); err != nil {
+		return exitError
+	}
+	defer root.closeDatabase()
+
+	ctx := context.Background()
+	organization := organizationsModels.Organization{
+		ID:         uuid.GenerateNamespaceUUID("org"),
+		ClerkOrgID: "org_seed",
+		Name:       "Example Organization",
+		Slug:       "example-organization",
+	}
+	if err := root.DB.WithContext(ctx).Where(organizationsModels.Organization{ClerkOrgID: organization.ClerkOrgID}).FirstOrCreate(&organization).Error; err != nil {
+		root.Logger.Error("failed to seed organization", "error", err)
+		return exitError
+	}
+
+	user := usersModels.User{
+		ID:             uuid.GenerateNamespaceUUID("usr"),
+		ClerkUserID:    "user_seed",
+		Email:          "example@example.com",
+		FirstName:      "Example",
+		LastName:       "User",
+		OrganizationID: organization.ID,
+	}
+	if err := root.DB.WithContext(ctx).Where(usersModels.User{ClerkUserID: user.ClerkUserID}).FirstOrCreate(&user).Error; err != nil {
+		root.Logger.Error("failed to seed user", "error", err)
+		return exitError
+	}
+
+	root.Logger.Info("seed data inserted", "organization_id", organization.ID, "user_id", user.ID)
+	return exitOK
+}
+
+func configCommand(args []string) int {
+	action, args, ok := subcommand("config", args, "print", "validate", "audit", "encrypt-secrets", "decrypt-secrets")
+	if !ok {
+		return exitUsage
+	}
+	if action == "encrypt-secrets" || action == "decrypt-secrets" {
+		return configSecrets(action, args)
+	}
+
+	flags, configFlags := newConfigFlags("config " + action)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	vars, err := conf.LoadConfigVars(context.Background(), conf.LoadOptions{Flags: configFlags})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	switch action {
+	case "print":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(vars.Redacted()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+	case "validate":
+		fmt.Printf("configuration is valid (%s)\n", vars.Server.Environment)
+	case "audit":
+		// Exits non-zero on any critical finding, whatever the environment
+		report := conf.Audit(vars, conf.DefaultAuditRules())
+		report.Print(os.Stdout)
+		if len(report.Critical()) > 0 {
+			return exitError
+		}
+	}
+
+	return exitOK
+}
+
+// configSecrets encrypts dotenv lines into the secrets file, or decrypts it back,
+// with the key in <PREFIX>MASTER_KEY; it needs no configuration
+func configSecrets(action string, args []string) int {
+	encrypt := action == "encrypt-secrets"
+	in, out := "-", conf.SecretsFileName
+	if !encrypt {
+		in, out = conf.SecretsFileName, "-"
+	}
+
+	flags := flag.NewFlagSet("config "+action, flag.ContinueOnError)
+	flags.StringVar(&in, "in", in, "file to read, - for stdin")
+	flags.StringVar(&out, "out", out, "file to write, - for stdout")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	key, err := conf.ParseMasterKey(os.Getenv(conf.EnvPrefix + "MASTER_KEY"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%sMASTER_KEY: %v\n", conf.EnvPrefix, err)
+		return exitError
+	}
+
+	var input []byte
+	if in == "-" {
+		input, err = io.ReadAll(os.Stdin)
+	} else {
+		input, err = os.ReadFile(in)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	var output []byte
+	if encrypt {
+		// Refuse input the loader could not read back
+		if _, err := godotenv.Unmarshal(string(input)); err != nil {
+			fmt.Fprintf(os.Stderr, "%s does not hold dotenv lines: %v\n", in, err)
+			return exitError
+		}
+		output, err = conf.EncryptSecrets(input, key)
+		output = append(output, '\n')
+	} else {
+		output, err = conf.DecryptSecrets(input, key)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to %s: %v\n", strings.TrimSuffix(action, "-secrets"), err)
+		return exitError
+	}
+
+	if out == "-" {
+		_, err = os.Stdout.Write(output)
+	} else {
+		err = os.WriteFile(out, output, 0o600)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if out != "-" {
+		fmt.Fprintln(os.Stderr, "wrote", out)
+	}
+	return exitOK
+}
+
+func versionCommand(args []string) int {
+	flags := flag.NewFlagSet("version", flag.ContinueOnError)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	info := buildinfo.Get()
+	fmt.Printf("{{.Name}} %s\n", info.Version)
+	fmt.Printf("  commit:     %s\n", info.Commit)
+	fmt.Printf("  committed:  %s\n", info.CommitTime)
+	fmt.Printf("  built:      %s\n", info.BuildTime)
+	fmt.Printf("  go:         %s\n", info.GoVersion)
+	if info.Modified {
+		fmt.Println("  modified:   true")
+	}
+	return exitOK
+}
+
+func routesCommand(args []string) int {
+	flags, configFlags := newConfigFlags("routes")
+	root, code, ok := loadCommandConfig(flags, configFlags, args)
+	if !ok {
+		return code
+	}
+	// Only errors, so log lines do not interleave with the route table
+	root.Logger.SetLevel(slog.LevelError)
+	root.loadDependencies(nil)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH")
+	err := root.Router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Subrouter prefixes have no methods of their own
+			return nil
+		}
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", strings.Join(methods, ","), path)
+		return nil
+	})
+	tw.Flush()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
@@ -2,179 +2,589 @@ package cmd
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
+	"sync/atomic"
 	"time"
 
 	"{{.Module}}/internal/conf"
 	"{{.Module}}/internal/handlers"
+	"{{.Module}}/internal/shared/admin"
 	"{{.Module}}/internal/shared/constants"
+	"{{.Module}}/internal/shared/lifecycle"
 	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/replicas"
+	"{{.Module}}/internal/shared/tlsconfig"
 
-	"github.com/rs/cors"
+	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
+// Exit codes shared by every command
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
 type RootConfig struct {
-	Logger *logger.Logger
-	Config *conf.ConfigVars
-	DB     *gorm.DB
+	Logger       *logger.Logger
+	Config       *conf.ConfigVars
+	LoadOptions  conf.LoadOptions
+	DB           *gorm.DB
+	Replicas     *replicas.Router // nil without DATABASE_REPLICA_DSNS
+	Reloader     *conf.Reloader
+	Dependencies *conf.Dependencies
+	Handler      *handlers.Handler
+	Router       *mux.Router
+
+	// dbConnected turns true once the database has answered; database-backed
+	// routes return 503 until then
+	dbConnected atomic.Bool
 }
 
-func loadRootConfig() *RootConfig {
-	vars, err := conf.LoadConfigVarsFromEnv()
+func loadRootConfig(configFlags *conf.FlagSource) (*RootConfig, error) {
+	loadOptions := conf.LoadOptions{Flags: configFlags}
+	vars, err := conf.LoadConfigVars(context.Background(), loadOptions)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	appLogger := logger.NewLogger(logger.DevelopmentConfig(vars.Server.Name, vars.Server.Version))
+	if level, err := logger.ParseLevel(vars.Logging.Level); err == nil {
+		appLogger.SetLevel(level)
+	}
+	appLogger.Debug("configuration loaded", "sources", vars.Origins.Sorted())
 
 	return &RootConfig{
-		Logger: appLogger,
-		Config: vars,
+		Logger:      appLogger,
+		Config:      vars,
+		LoadOptions: loadOptions,
+	}, nil
+}
+
+// reloadLogLevel is a conf.ReloadHook that swaps the logger level
+func (root *RootConfig) reloadLogLevel(cfg *conf.ConfigVars) (func(), error) {
+	level, err := logger.ParseLevel(cfg.Logging.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", cfg.Logging.Level, err)
 	}
+	return func() { root.Logger.SetLevel(level) }, nil
 }
 
-func (root *RootConfig) loadDatabase() *RootConfig {
-	db, err := conf.InitConnectionPool(conf.PGConfig{
-		Host:     root.Config.Database.DatabaseHost,
-		Port:     root.Config.Database.DatabasePort,
-		User:     root.Config.Database.DatabaseUser,
-		DBName:   root.Config.Database.DatabaseName,
-		Password: root.Config.Database.DatabasePassword,
-		SSLMode:  root.Config.Database.DatabaseSSLMode,
-		MaxConns: 10,
-		Logger:   root.Logger,
-	})
+// audit logs every config audit finding and refuses a production boot on critical ones
+func (root *RootConfig) audit() error {
+	report := conf.Audit(root.Config, conf.DefaultAuditRules())
+	for _, finding := range report.Findings {
+		root.Logger.Warn("config audit finding",
+			"rule", finding.Rule,
+			"key", finding.Key,
+			"severity", finding.Severity,
+			"message", finding.Message,
+		)
+	}
+
+	if err := report.Enforce(root.Config.Audit.AllowUnsafe); err != nil {
+		return err
+	}
+	if len(report.Critical()) > 0 {
+		root.Logger.Warn("starting with critical config audit findings", "environment", report.Environment)
+	}
+	return nil
+}
+
+// retryPolicy is how connecting to the database is retried at startup
+func (root *RootConfig) retryPolicy() conf.RetryPolicy {
+	return conf.RetryPolicy{
+		Attempts:       root.Config.Database.ConnectAttempts,
+		InitialBackoff: root.Config.Database.ConnectBackoff,
+		MaxBackoff:     root.Config.Database.ConnectMaxBackoff,
+	}
+}
+
+// loadDatabase opens the connection pool and retries connecting until the
+// database answers or DATABASE_CONNECT_ATTEMPTS / DATABASE_CONNECT_TIMEOUT run out.
+// root.DB is set even when connecting fails, so a caller may keep retrying.
+func (root *RootConfig) loadDatabase(ctx context.Context) error {
+	db, err := conf.OpenConnectionPool(root.pgConfig())
 	if err != nil {
 		root.Logger.Error("database connection failed", "error", err)
-		panic(err)
+		return err
 	}
-
 	root.DB = db
-	root.Logger.Info("database connected")
-	return root
-}
 
-func (root *RootConfig) exec() {
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, cancel := context.WithTimeout(ctx, root.Config.Database.ConnectTimeout)
 	defer cancel()
-	root.Logger.Info("application context created")
+	if err := conf.ConnectDatabase(ctx, db, root.retryPolicy(), root.Logger); err != nil {
+		return err
+	}
+
+	root.dbConnected.Store(true)
+	return nil
+}
+
+// pgConfig builds the primary pool settings; replicas share all but the connection string
+func (root *RootConfig) pgConfig() conf.PGConfig {
+	vars := root.Config.Database
+	applicationName := vars.ApplicationName
+	if applicationName == "" {
+		applicationName = root.Config.Server.Name
+	}
+
+	return conf.PGConfig{
+		Host:     vars.DatabaseHost,
+		Port:     vars.DatabasePort,
+		User:     vars.DatabaseUser,
+		DBName:   vars.DatabaseName,
+		Password: vars.DatabasePassword,
+		SSLMode:  vars.DatabaseSSLMode,
+		Logger:   root.Logger,
+
+		MaxOpenConns:    vars.MaxOpenConns,
+		MaxIdleConns:    vars.MaxIdleConns,
+		ConnMaxLifetime: vars.ConnMaxLifetime,
+		ConnMaxIdleTime: vars.ConnMaxIdleTime,
+
+		ApplicationName:        applicationName,
+		StatementTimeout:       vars.StatementTimeout,
+		StatementCacheCapacity: vars.StatementCacheCapacity,
+
+		PrepareStmt:            vars.PrepareStmt,
+		SkipDefaultTransaction: vars.SkipDefaultTransaction,
+		SlowQueryThreshold:     vars.SlowQueryThreshold,
+		LogQueryParams:         vars.LogQueryParams,
+	}
+}
+
+// loadReplicas opens a pool per DATABASE_REPLICA_DSNS entry and routes reads on
+// root.DB to them. Only serve uses replicas; commands read from the primary.
+func (root *RootConfig) loadReplicas() error {
+	dsns := root.Config.Database.ReplicaDSNs
+	if len(dsns) == 0 || root.DB == nil {
+		return nil
+	}
+
+	pool := make([]*replicas.Replica, 0, len(dsns))
+	// closePool releases the replica pools opened so far when a later step fails
+	closePool := func() {
+		if err := replicas.NewRouter(root.Logger, pool).Close(); err != nil {
+			root.Logger.Error("failed to close database replicas", "error", err)
+		}
+	}
+	for _, dsn := range dsns {
+		config := root.pgConfig()
+		config.URL = dsn
+		db, err := conf.OpenConnectionPool(config)
+		if err != nil {
+			root.Logger.Error("database replica configuration failed", "replica", conf.DSNHost(dsn), "error", err)
+			closePool()
+			return err
+		}
+		pool = append(pool, replicas.NewReplica(conf.DSNHost(dsn), db))
+	}
+
+	router := replicas.NewRouter(root.Logger, pool)
+	if err := root.DB.Use(router); err != nil {
+		closePool()
+		return err
+	}
+	root.Replicas = router
+	root.Logger.Info("database read replicas configured", "count", len(pool))
+	return nil
+}
+
+// reconnectDatabase retries connecting until it succeeds or ctx is done, for degraded mode
+func (root *RootConfig) reconnectDatabase(ctx context.Context) {
+	policy := root.retryPolicy()
+	policy.Attempts = 0
+	if err := conf.ConnectDatabase(ctx, root.DB, policy, root.Logger); err != nil {
+		return
+	}
 
-	root.loadDatabase()
-	root.Logger.Info("database loaded")
+	root.dbConnected.Store(true)
+	root.Logger.Info("database reconnected, leaving degraded mode")
+}
 
-	dependencies := conf.LoadDependencies(root.Logger, root.Config, root.DB)
+// closeDatabase releases the connection pool opened by loadDatabase
+func (root *RootConfig) closeDatabase() error {
+	if root.DB == nil {
+		return nil
+	}
+	if root.Replicas != nil {
+		if err := root.Replicas.Close(); err != nil {
+			root.Logger.Error("failed to close database replicas", "error", err)
+		}
+	}
+
+	root.Logger.Info("closing database connection")
+	sqlDB, err := root.DB.DB()
+	if err != nil {
+		root.Logger.Error("failed to get sql db", "error", err)
+		return err
+	}
+	if err := sqlDB.Close(); err != nil {
+		root.Logger.Error("failed to close database connection", "error", err)
+		return err
+	}
+	root.Logger.Info("database connection closed")
+	return nil
+}
+
+// loadDependencies builds the dependency container and registers every route.
+// The database and ready may be nil for commands that only inspect the router.
+func (root *RootConfig) loadDependencies(ready func() bool) {
+	root.Reloader = conf.NewReloader(root.Logger, root.Config, root.LoadOptions)
+	root.Dependencies = conf.LoadDependencies(root.Logger, root.Reloader, root.DB, ready, root.dbConnected.Load)
 	root.Logger.Info("dependencies loaded")
 
-	handler := handlers.NewHandler(root.Logger, dependencies).Register()
+	root.Handler = handlers.NewHandler(root.Logger, root.Dependencies)
+	root.Router = root.Handler.Register()
 	root.Logger.Info("handler registered")
+}
 
-	crossOrigin := cors.New(cors.Options{
-		AllowedOrigins: func() []string {
-			env := os.Getenv("{{.Name | upper}}_ENVIRONMENT")
-			switch env {
-			case "production":
-				return []string{
-					"https://yourdomain.com",
-					"https://www.yourdomain.com",
+// components lists everything serve runs; the lifecycle manager orders them by DependsOn
+func (root *RootConfig) components(app *lifecycle.Manager) []lifecycle.Component {
+	var server *http.Server
+
+	components := []lifecycle.Component{
+		{
+			Name: "database",
+			Start: func(ctx context.Context) error {
+				// The manager only stops components that started, so a failed start
+				// closes the pools it already opened
+				err := root.loadDatabase(ctx)
+				if err != nil && (!root.Config.Database.DegradedStart || root.DB == nil) {
+					root.closeDatabase()
+					return err
 				}
-			case "staging":
-				return []string{
-					"https://staging.yourdomain.com",
-					"https://staging-app.yourdomain.com",
+
+				if replicaErr := root.loadReplicas(); replicaErr != nil {
+					root.closeDatabase()
+					return replicaErr
 				}
-			default:
-				return []string{
-					constants.ServerAllowedOriginLocal,
-					constants.ServerAllowedOriginVite,
-					constants.ServerAllowedOriginReact,
-					constants.ServerAllowedOriginReactNative,
-					constants.ServerAllowedOriginPostman,
+				if root.Replicas != nil {
+					app.Go("database-replica-health", func(ctx context.Context) {
+						root.Replicas.Watch(ctx, root.Config.Database.ReplicaHealthInterval)
+					})
 				}
-			}
-		}(),
-		AllowedMethods: []string{
-			string(constants.AllowedMethodGET),
-			string(constants.AllowedMethodPOST),
-			string(constants.AllowedMethodPUT),
-			string(constants.AllowedMethodPATCH),
-			string(constants.AllowedMethodDELETE),
+
+				if err != nil {
+					root.Logger.Warn("starting in degraded mode, database-backed routes return 503 until the database is reachable", "error", err)
+					app.Go("database-reconnect", root.reconnectDatabase)
+				}
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				return root.closeDatabase()
+			},
+			// Leave time for the last attempt to fail before the hook is abandoned
+			StartTimeout: root.Config.Database.ConnectTimeout + 10*time.Second,
 		},
-		AllowCredentials: true,
-	})
-	root.Logger.Info("cors middleware generated")
-
-	appHandler := crossOrigin.Handler(handler)
-	root.Logger.Info("app handler generated")
-
-	server := &http.Server{
-		Addr:           fmt.Sprintf(":%s", root.Config.Server.Port),
-		Handler:        appHandler,
-		WriteTimeout:   constants.WriteTimeout,
-		ReadTimeout:    constants.ReadTimeout,
-		IdleTimeout:    constants.IdleTimeout,
-		MaxHeaderBytes: int(root.Config.RequestLimits.MaxHeaderSize),
-	}
-
-	root.Logger.Info("starting server",
-		"port", root.Config.Server.Port,
-		"maxRequestSize", root.Config.RequestLimits.MaxRequestSize,
-		"readTimeout", root.Config.RequestLimits.ReadTimeout,
-		"writeTimeout", root.Config.RequestLimits.WriteTimeout,
-	)
-
-	defer func() {
-		root.Logger.Info("closing database connection")
-		sqlDB, err := root.DB.DB()
-		if err != nil {
-			root.Logger.Error("failed to get sql db", "error", err)
-		}
-		if err := sqlDB.Close(); err != nil {
-			root.Logger.Error("failed to close database connection", "error", err)
+		{
+			Name:      "http",
+			DependsOn: []string{"database"},
+			Start: func(ctx context.Context) error {
+				root.loadDependencies(app.Ready)
+
+				mw := root.Dependencies.Middleware
+				server = &http.Server{
+					Addr:           fmt.Sprintf(":%s", root.Config.Server.Port),
+					Handler:        mw.InFlightMiddleware(app.Requests())(mw.CORSMiddleware(root.Router)),
+					WriteTimeout:   constants.WriteTimeout,
+					ReadTimeout:    constants.ReadTimeout,
+					IdleTimeout:    constants.IdleTimeout,
+					MaxHeaderBytes: int(root.Config.RequestLimits.MaxHeaderSize),
+				}
+
+				serveTLS := root.Config.Server.Protocol == "https"
+				if serveTLS {
+					tlsConfig, err := root.tlsConfig(app)
+					if err != nil {
+						return err
+					}
+					server.TLSConfig = tlsConfig
+				}
+
+				// Bind before returning so a taken port fails the start instead of the running app
+				listener, err := net.Listen("tcp", server.Addr)
+				if err != nil {
+					return err
+				}
+
+				root.Logger.Info("starting server",
+					"port", root.Config.Server.Port,
+					"protocol", root.Config.Server.Protocol,
+					"maxRequestSize", root.Config.RequestLimits.MaxRequestSize,
+					"readTimeout", root.Config.RequestLimits.ReadTimeout,
+					"writeTimeout", root.Config.RequestLimits.WriteTimeout,
+				)
+				go func() {
+					root.Logger.Info("{{.Name}} service running", "port", root.Config.Server.Port)
+					serve := server.Serve
+					if serveTLS {
+						// Certificates come from TLSConfig.GetCertificate; ServeTLS also enables HTTP/2
+						serve = func(listener net.Listener) error { return server.ServeTLS(listener, "", "") }
+					}
+					if err := serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+						app.Fail("http", err)
+					}
+				}()
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				// Doesn't block if no connections, but will otherwise wait
+				// until the timeout deadline
+				return server.Shutdown(ctx)
+			},
+			StopTimeout: root.Config.Shutdown.Timeout,
+		},
+		{
+			Name:      "config-reloader",
+			DependsOn: []string{"http"},
+			Start: func(ctx context.Context) error {
+				root.Reloader.OnReload(root.reloadLogLevel)
+				root.Reloader.OnReload(root.Handler.Reload)
+				app.Go("config-watch", root.Reloader.Watch)
+				return nil
+			},
+		},
+	}
+
+	if root.Config.Server.Protocol == "https" && root.Config.TLS.RedirectPort != "" {
+		components = append(components, root.redirectComponent(app))
+	}
+	if root.Config.Admin.Enabled {
+		components = append(components, root.adminComponent(app))
+	}
+	if root.Config.Database.SoftDeleteRetention > 0 {
+		components = append(components, lifecycle.Component{
+			Name:      "soft-delete-purge",
+			DependsOn: []string{"http"},
+			Start: func(ctx context.Context) error {
+				app.Go("soft-delete-purge", root.purgeDeleted)
+				return nil
+			},
+		})
+	}
+	return components
+}
+
+// purgeDeleted permanently deletes the users and organizations soft-deleted
+// longer than DATABASE_SOFT_DELETE_RETENTION ago, every DATABASE_PURGE_INTERVAL
+func (root *RootConfig) purgeDeleted(ctx context.Context) {
+	retention := root.Config.Database.SoftDeleteRetention
+	services := root.Dependencies.Services
+	ticker := time.NewTicker(root.Config.Database.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		// Skipped in degraded mode until the database is back
+		if root.dbConnected.Load() {
+			users, err := services.Users.PurgeDeletedUsers(ctx, retention)
+			if err != nil && ctx.Err() == nil {
+				root.Logger.Error("failed to purge deleted users", "error", err)
+			}
+			organizations, err := services.Organizations.PurgeDeletedOrganizations(ctx, retention)
+			if err != nil && ctx.Err() == nil {
+				root.Logger.Error("failed to purge deleted organizations", "error", err)
+			}
+			if users+organizations > 0 {
+				root.Logger.Info("purged soft-deleted records",
+					"users", users,
+					"organizations", organizations,
+					"retention", retention.String(),
+				)
+			}
 		}
-		root.Logger.Info("database connection closed")
-	}()
-
-	var wait time.Duration
-	flag.DurationVar(
-		&wait,
-		"graceful-timeout",
-		constants.ShutdownGracePeriod,
-		"duration for which the server gracefully waits for existing connections to finish",
-	)
-	flag.Parse()
-
-	// run server in goroutine to prevent blocking
-	go func() {
-		root.Logger.Info("{{.Name}} service running", "port", root.Config.Server.Port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			root.Logger.Error("unexpected server error", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
-	}()
+	}
+}
 
-	// Block until we receive our signal
-	<-ctx.Done()
-	root.Logger.Info("received shutdown signal, shutting down {{.Name}} service gracefully")
+// tlsConfig loads the certificate and keeps reloading it while the app runs
+func (root *RootConfig) tlsConfig(app *lifecycle.Manager) (*tls.Config, error) {
+	vars := root.Config.TLS
+	certificates, err := tlsconfig.NewCertReloader(root.Logger, vars.CertFile, vars.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	app.Go("cert-watch", func(ctx context.Context) {
+		certificates.Watch(ctx, vars.ReloadInterval)
+	})
 
-	// Create a deadline to wait for
-	cx, cancel := context.WithTimeout(ctx, wait)
-	defer cancel()
+	return tlsconfig.NewServerConfig(tlsconfig.Config{
+		CertFile:     vars.CertFile,
+		KeyFile:      vars.KeyFile,
+		MinVersion:   vars.MinVersion,
+		CipherPolicy: vars.CipherPolicy,
+	}, certificates)
+}
+
+// redirectComponent serves plain HTTP on TLS_REDIRECT_PORT, redirecting to HTTPS
+func (root *RootConfig) redirectComponent(app *lifecycle.Manager) lifecycle.Component {
+	redirect := &http.Server{
+		Addr:              fmt.Sprintf(":%s", root.Config.TLS.RedirectPort),
+		Handler:           tlsconfig.RedirectHandler(root.Config.Server.Port),
+		ReadHeaderTimeout: constants.ReadTimeout,
+		IdleTimeout:       constants.IdleTimeout,
+	}
+
+	return lifecycle.Component{
+		Name:      "http-redirect",
+		DependsOn: []string{"http"},
+		Start: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", redirect.Addr)
+			if err != nil {
+				return err
+			}
+			root.Logger.Info("redirecting plain HTTP to HTTPS", "port", root.Config.TLS.RedirectPort)
+			go func() {
+				if err := redirect.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					app.Fail("http-redirect", err)
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			return redirect.Shutdown(ctx)
+		},
+	}
+}
+
+// adminComponent serves pprof, expvar, runtime stats and the admin API on ADMIN_ADDR,
+// away from the public router
+func (root *RootConfig) adminComponent(app *lifecycle.Manager) lifecycle.Component {
+	var server *http.Server
+
+	return lifecycle.Component{
+		Name:      "admin",
+		DependsOn: []string{"http"},
+		Start: func(ctx context.Context) error {
+			sqlDB, err := root.DB.DB()
+			if err != nil {
+				return err
+			}
+
+			server = &http.Server{
+				Addr: root.Config.Admin.Addr,
+				Handler: admin.NewHandler(admin.Options{
+					Token:   root.Config.Admin.Token,
+					DBStats: sqlDB.Stats,
+					API:     root.Handler.RegisterAdmin(),
+				}),
+				ReadHeaderTimeout: constants.ReadTimeout,
+				IdleTimeout:       constants.IdleTimeout,
+			}
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+
+			root.Logger.Info("admin listener running", "addr", root.Config.Admin.Addr)
+			go func() {
+				if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					app.Fail("admin", err)
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			return server.Shutdown(ctx)
+		},
+	}
+}
+
+func (root *RootConfig) serve() int {
+	if err := root.audit(); err != nil {
+		root.Logger.Error("refusing to start", "error", err)
+		return exitError
+	}
+	root.Logger.Info("config audited")
+
+	app := lifecycle.NewManager(root.Logger, lifecycle.Options{
+		DrainDelay:      root.Config.Shutdown.DrainDelay,
+		ShutdownTimeout: root.Config.Shutdown.Timeout,
+	})
+	for _, component := range root.components(app) {
+		if err := app.Register(component); err != nil {
+			root.Logger.Error("failed to register component", "error", err)
+			return exitError
+		}
+	}
 
-	// Doesn't block if no connections, but will otherwise wait
-	// until the timeout deadline
-	if err := server.Shutdown(cx); err != nil {
-		root.Logger.Error("error during server shutdown")
+	if err := app.Run(context.Background()); err != nil {
+		root.Logger.Error("{{.Name}} service stopped with errors", "error", err)
+		return exitError
 	}
 
 	root.Logger.Info("application successfully shutdown")
-	os.Exit(0)
+	return exitOK
 }
 
+// Run dispatches to the command named by the first argument, defaulting to serve,
+// and exits with the command's exit code.
 func Run() {
-	root := loadRootConfig()
-	root.exec()
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	// No command, or only flags, keeps `go run main.go [--flags]` starting the server
+	if len(args) == 0 || (len(args[0]) > 0 && args[0][0] == '-') {
+		return serveCommand(args)
+	}
+
+	for _, command := range commands {
+		if command.name == args[0] {
+			return command.run(args[1:])
+		}
+	}
+
+	if args[0] == "help" {
+		printUsage(os.Stdout)
+		return exitOK
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	printUsage(os.Stderr)
+	return exitUsage
+}
+
+// newConfigFlags creates the flag set for a command with a flag for every config variable
+func newConfigFlags(name string) (*flag.FlagSet, *conf.FlagSource) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	return flags, conf.RegisterFlags(flags, conf.EnvPrefix, &conf.ConfigVars{})
+}
+
+// parseFlags parses args and reports the exit code to return when parsing stopped the command
+func parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// loadCommandConfig parses the command flags and loads the configuration
+func loadCommandConfig(flags *flag.FlagSet, configFlags *conf.FlagSource, args []string) (*RootConfig, int, bool) {
+	if code, ok := parseFlags(flags, args); !ok {
+		return nil, code, false
+	}
+
+	root, err := loadRootConfig(configFlags)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, exitError, false
+	}
+	return root, exitOK, true
 }
@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"net/http"
+
+	"{{.Module}}/internal/audit/models"
+	audit "{{.Module}}/internal/audit/service"
+	httpHelpers "{{.Module}}/internal/shared/http"
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/pagination"
+)
+
+const (
+	pkgName = "audit"
+	layer   = "controller"
+)
+
+type AuditController interface {
+	ListEvents(w http.ResponseWriter, r *http.Request) error
+}
+
+type ControllerImpl struct {
+	log     *logger.Logger
+	service audit.AuditService
+}
+
+func NewController(logger *logger.Logger, service audit.AuditService) AuditController {
+	ctrlLogger := logger.With("package", pkgName, "layer", layer)
+	return &ControllerImpl{log: ctrlLogger, service: service}
+}
+
+// ListEvents returns a page of audit events, newest first by default
+func (c *ControllerImpl) ListEvents(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "ListEvents")
+
+	page, err := pagination.Parse(r.URL.Query(), models.EventListOptions)
+	if err != nil {
+		l.Debug("invalid list audit events query", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+	filter, err := models.NewEventFilter(page.Filters)
+	if err != nil {
+		l.Debug("invalid list audit events filter", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	events, next, err := c.service.ListEvents(ctx, filter, page)
+	if err != nil {
+		l.Error("failed to list audit events", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, pagination.NewPage(r, events, page.Limit, next))
+}
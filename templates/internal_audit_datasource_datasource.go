@@ -0,0 +1,89 @@
+//go:generate mockgen -destination=../../mocks/mock_audit_datasource.go -package=mocks {{.Module}}/internal/audit/datasource AuditDatasource
+
+package datasource
+
+import (
+	"context"
+
+	"{{.Module}}/internal/audit/models"
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/pagination"
+	"{{.Module}}/internal/shared/transaction"
+
+	"gorm.io/gorm"
+)
+
+const (
+	pkgName = "audit"
+	layer   = "datasource"
+)
+
+type AuditDatasource interface {
+	CreateEvent(ctx context.Context, event *models.Event) error
+	ListEvents(ctx context.Context, filter models.EventFilter, page pagination.Request) ([]models.Event, string, error)
+}
+
+type DatasourceImpl struct {
+	log *logger.Logger
+	db  *gorm.DB
+}
+
+func NewDatasource(logger *logger.Logger, db *gorm.DB) AuditDatasource {
+	dsLogger := logger.With("package", pkgName, "layer", layer)
+	return &DatasourceImpl{log: dsLogger, db: db}
+}
+
+// CreateEvent inserts event in the transaction carried by ctx, if any, so the
+// event is kept only when the change it records is
+func (d *DatasourceImpl) CreateEvent(ctx context.Context, event *models.Event) error {
+	l := d.log.WithContext(ctx).With("operation", "CreateEvent")
+
+	if err := transaction.DB(ctx, d.db).Create(event).Error; err != nil {
+		l.Error("failed to create audit event", "error", err)
+		return err
+	}
+
+	l.Debug("audit event created successfully", "event_id", event.ID, "action", event.Action)
+	return nil
+}
+
+// ListEvents returns a page of events and the cursor of the next page, empty on the last one
+func (d *DatasourceImpl) ListEvents(ctx context.Context, filter models.EventFilter, page pagination.Request) ([]models.Event, string, error) {
+	l := d.log.WithContext(ctx).With("operation", "ListEvents")
+
+	query := transaction.DB(ctx, d.db).Model(&models.Event{})
+	if filter.ResourceType != "" {
+		query = query.Where("audit_events.resource_type = ?", filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		query = query.Where("audit_events.resource_id = ?", filter.ResourceID)
+	}
+	if filter.ActorType != "" {
+		query = query.Where("audit_events.actor_type = ?", filter.ActorType)
+	}
+	if filter.ActorID != "" {
+		query = query.Where("audit_events.actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("audit_events.action = ?", filter.Action)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("audit_events.created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("audit_events.created_at < ?", *filter.CreatedBefore)
+	}
+
+	var events []models.Event
+	if err := page.Apply(query).Find(&events).Error; err != nil {
+		l.Error("failed to list audit events", "error", err)
+		return nil, "", err
+	}
+
+	events, next := pagination.Trim(events, page, func(e models.Event) (interface{}, string) {
+		return e.CreatedAt, e.ID
+	})
+
+	l.Debug("audit events listed successfully", "count", len(events), "has_next", next != "")
+	return events, next, nil
+}
@@ -0,0 +1,240 @@
+package models
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/pagination"
+)
+
+// Actor types
+const (
+	ActorUser   = "user"   // a signed-in user, by Clerk user ID
+	ActorClerk  = "clerk"  // a Clerk webhook, by its svix-id
+	ActorAdmin  = "admin"  // a caller of the admin API
+	ActorSystem = "system" // anything else, such as a background job
+)
+
+// Resource types
+const (
+	ResourceUser         = "user"
+	ResourceOrganization = "organization"
+)
+
+// Actions
+const (
+	ActionUserCreated          = "user.created"
+	ActionUserUpdated          = "user.updated"
+	ActionUserProfileUpdated   = "user.profile_updated"
+	ActionUserBanned           = "user.banned"
+	ActionUserUnbanned         = "user.unbanned"
+	ActionUserDeleted          = "user.deleted"
+	ActionUserRestored         = "user.restored"
+	ActionUserPurged           = "user.purged"
+	ActionOrganizationCreated  = "organization.created"
+	ActionOrganizationUpdated  = "organization.updated"
+	ActionOrganizationDeleted  = "organization.deleted"
+	ActionOrganizationRestored = "organization.restored"
+	ActionOrganizationPurged   = "organization.purged"
+)
+
+// ignoredFields change on every write and would only add noise to a diff
+var ignoredFields = map[string]bool{
+	"updated_at": true,
+	"version":    true,
+}
+
+// Event is one recorded change. Events are only ever inserted.
+type Event struct {
+	ID           string    `json:"id" gorm:"primaryKey"`
+	ActorType    string    `json:"actor_type"`
+	ActorID      string    `json:"actor_id"`
+	Action       string    `json:"action"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id"`
+	Changes      Changes   `json:"changes" gorm:"type:jsonb"`
+	RequestID    string    `json:"request_id"`
+	ClientIP     string    `json:"client_ip"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (Event) TableName() string {
+	return "audit_events"
+}
+
+// Entry describes a change for AuditService.Record; the actor, request ID and
+// client IP come from the context
+type Entry struct {
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Before       interface{} // nil for a created resource
+	After        interface{} // nil for a deleted resource
+	Personal     []string    // JSON names of personal-data fields, recorded without their values
+}
+
+// Change holds the JSON values of a field before and after a change. A redacted
+// change records only that a personal-data field changed.
+type Change struct {
+	Before   interface{} `json:"before"`
+	After    interface{} `json:"after"`
+	Redacted bool        `json:"redacted,omitempty"`
+}
+
+// Changes maps the JSON name of each changed field to its change
+type Changes map[string]Change
+
+// Redact drops the values of the named fields, keeping only that they changed
+func (c Changes) Redact(names ...string) {
+	for _, name := range names {
+		if _, ok := c[name]; ok {
+			c[name] = Change{Redacted: true}
+		}
+	}
+}
+
+// Value stores Changes as JSON
+func (c Changes) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// Scan reads Changes stored as JSON
+func (c *Changes) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	case nil:
+		*c = Changes{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into audit changes", src)
+	}
+	return json.Unmarshal(raw, c)
+}
+
+// Diff compares the JSON forms of before and after, field by field. A nil
+// side, for a created or deleted resource, makes every field of the other a change.
+func Diff(before, after interface{}) (Changes, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := Changes{}
+	for name, value := range beforeFields {
+		if ignoredFields[name] {
+			continue
+		}
+		if other, ok := afterFields[name]; !ok || !reflect.DeepEqual(value, other) {
+			changes[name] = Change{Before: value, After: other}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok && !ignoredFields[name] {
+			changes[name] = Change{After: value}
+		}
+	}
+	return changes, nil
+}
+
+func fields(v interface{}) (map[string]interface{}, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return map[string]interface{}{}, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("audited value %T is not a JSON object: %w", v, err)
+	}
+	return out, nil
+}
+
+// Actor is who made a change
+type Actor struct {
+	Type string
+	ID   string
+}
+
+type actorKey struct{}
+
+// ContextWithActor sets the actor of the changes made with ctx
+func ContextWithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by ContextWithActor, else the
+// signed-in user, else an anonymous system actor
+func ActorFromContext(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	if userID, ok := ctx.Value(logger.UserIDKey).(string); ok && userID != "" {
+		return Actor{Type: ActorUser, ID: userID}
+	}
+	return Actor{Type: ActorSystem}
+}
+
+// EventListOptions are the sort fields and filters of the audit events list
+var EventListOptions = pagination.Options{
+	Table: "audit_events",
+	SortFields: map[string]pagination.Field{
+		"created_at": {Column: "created_at", Time: true},
+	},
+	DefaultSort: "-created_at",
+	Filters:     []string{"resource_type", "resource_id", "actor_type", "actor_id", "action", "created_after", "created_before"},
+}
+
+// EventFilter narrows the audit events list; zero fields match every event
+type EventFilter struct {
+	ResourceType  string
+	ResourceID    string
+	ActorType     string
+	ActorID       string
+	Action        string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// NewEventFilter reads the filters allowed by EventListOptions
+func NewEventFilter(filters url.Values) (EventFilter, error) {
+	filter := EventFilter{
+		ResourceType: strings.TrimSpace(filters.Get("resource_type")),
+		ResourceID:   strings.TrimSpace(filters.Get("resource_id")),
+		ActorType:    strings.TrimSpace(filters.Get("actor_type")),
+		ActorID:      strings.TrimSpace(filters.Get("actor_id")),
+		Action:       strings.TrimSpace(filters.Get("action")),
+	}
+
+	var err error
+	if filter.CreatedAfter, err = pagination.Time(filters, "created_after"); err != nil {
+		return EventFilter{}, err
+	}
+	if filter.CreatedBefore, err = pagination.Time(filters, "created_before"); err != nil {
+		return EventFilter{}, err
+	}
+	return filter, nil
+}
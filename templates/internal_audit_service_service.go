@@ -0,0 +1,84 @@
+//go:generate mockgen -destination=../../mocks/mock_audit_service.go -package=mocks {{.Module}}/internal/audit/service AuditService
+
+package audit
+
+import (
+	"context"
+	"time"
+
+	auditDatasource "{{.Module}}/internal/audit/datasource"
+	"{{.Module}}/internal/audit/models"
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/pagination"
+	"{{.Module}}/internal/shared/uuid"
+)
+
+const (
+	pkgName = "audit"
+	layer   = "service"
+)
+
+type AuditService interface {
+	Record(ctx context.Context, entry models.Entry) error
+	ListEvents(ctx context.Context, filter models.EventFilter, page pagination.Request) ([]models.Event, string, error)
+}
+
+type ServiceImpl struct {
+	log  *logger.Logger
+	data auditDatasource.AuditDatasource
+}
+
+func NewService(logger *logger.Logger, datasource auditDatasource.AuditDatasource) AuditService {
+	serviceLogger := logger.With("package", pkgName, "layer", layer)
+	return &ServiceImpl{log: serviceLogger, data: datasource}
+}
+
+// Record stores an event for entry, with the actor, request ID and client IP
+// carried by ctx. Services call it in the transaction of the change, so a
+// failed write undoes the change. An update that changed nothing is skipped.
+func (s *ServiceImpl) Record(ctx context.Context, entry models.Entry) error {
+	l := s.log.WithContext(ctx).With("operation", "Record")
+
+	changes, err := models.Diff(entry.Before, entry.After)
+	if err != nil {
+		l.Error("failed to diff audited values", "action", entry.Action, "error", err)
+		return err
+	}
+	if entry.Before != nil && entry.After != nil && len(changes) == 0 {
+		l.Debug("nothing changed, skipping audit event", "action", entry.Action, "resource_id", entry.ResourceID)
+		return nil
+	}
+
+	changes.Redact(entry.Personal...)
+
+	actor := models.ActorFromContext(ctx)
+	requestID, _ := ctx.Value(logger.RequestIDKey).(string)
+	clientIP, _ := ctx.Value(logger.ClientIPKey).(string)
+
+	event := &models.Event{
+		ID:           uuid.GenerateNamespaceUUID("aud"),
+		ActorType:    actor.Type,
+		ActorID:      actor.ID,
+		Action:       entry.Action,
+		ResourceType: entry.ResourceType,
+		ResourceID:   entry.ResourceID,
+		Changes:      changes,
+		RequestID:    requestID,
+		ClientIP:     clientIP,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := s.data.CreateEvent(ctx, event); err != nil {
+		return err
+	}
+
+	l.LogAudit(entry.Action, entry.ResourceType, actor.ID, "success", map[string]interface{}{
+		"actor_type":  actor.Type,
+		"resource_id": entry.ResourceID,
+		"event_id":    event.ID,
+	})
+	return nil
+}
+
+func (s *ServiceImpl) ListEvents(ctx context.Context, filter models.EventFilter, page pagination.Request) ([]models.Event, string, error) {
+	return s.data.ListEvents(ctx, filter, page)
+}
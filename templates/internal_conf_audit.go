@@ -0,0 +1,249 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Severity ranks how unsafe an audit finding is.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// EnvironmentProduction is the SERVER_ENV value that blocks boots on critical findings.
+const EnvironmentProduction = "production"
+
+// auditedEnvironments are the SERVER_ENV values the rules are written for. Any
+// other value, e.g. "prod", is audited and enforced as production, so a typo
+// can't skip the rules.
+var auditedEnvironments = map[string]bool{
+	"development":         true,
+	"test":                true,
+	"staging":             true,
+	EnvironmentProduction: true,
+}
+
+var ErrUnsafeConfig = errors.New("configuration is not safe for production")
+
+// AuditRule checks one unsafe setting. Severities gives the severity per environment;
+// environments without an entry skip the rule, and unknown ones use production's.
+type AuditRule struct {
+	ID         string
+	Key        string
+	Message    string
+	Severities map[string]Severity
+	Violated   func(cfg *ConfigVars) bool
+}
+
+// Finding is a rule that failed for the audited configuration.
+type Finding struct {
+	Rule     string
+	Key      string
+	Severity Severity
+	Message  string
+}
+
+// AuditReport holds every finding for one environment.
+type AuditReport struct {
+	Environment string
+	Findings    []Finding
+}
+
+// strictSeverities flags a rule as critical in production and a warning in staging.
+var strictSeverities = map[string]Severity{
+	EnvironmentProduction: SeverityCritical,
+	"staging":             SeverityWarning,
+}
+
+// DefaultAuditRules returns the rule set run before the server starts.
+func DefaultAuditRules() []AuditRule {
+	return []AuditRule{
+		{
+			ID:         "database-ssl-disabled",
+			Key:        "DATABASE_SSL_MODE",
+			Message:    "database connections may fall back to plain text",
+			Severities: strictSeverities,
+			Violated: func(cfg *ConfigVars) bool {
+				// allow and prefer connect without TLS when the server doesn't offer it
+				switch cfg.Database.DatabaseSSLMode {
+				case "disable", "allow", "prefer":
+					return true
+				}
+				return false
+			},
+		},
+		{
+			ID:      "database-sample-password",
+			Key:     "DATABASE_PASSWORD",
+			Message: "database password is a well-known sample value",
+			Severities: map[string]Severity{
+				EnvironmentProduction: SeverityCritical,
+				"staging":             SeverityCritical,
+				"development":         SeverityInfo,
+			},
+			Violated: func(cfg *ConfigVars) bool {
+				switch strings.ToLower(cfg.Database.DatabasePassword) {
+				case "root", "postgres", "password", "changeme":
+					return true
+				}
+				return false
+			},
+		},
+		{
+			ID:         "csrf-generated-key",
+			Key:        "CSRF_AUTH_KEY",
+			Message:    "CSRF key is unset or the sample value, so tokens change on every restart or are guessable",
+			Severities: strictSeverities,
+			Violated: func(cfg *ConfigVars) bool {
+				return len(cfg.CSRF.AuthKey) < 32 || strings.HasPrefix(cfg.CSRF.AuthKey, "your_")
+			},
+		},
+		{
+			ID:         "csrf-insecure-cookie",
+			Key:        "CSRF_SECURE",
+			Message:    "CSRF cookie is sent over plain HTTP",
+			Severities: strictSeverities,
+			Violated: func(cfg *ConfigVars) bool {
+				return !cfg.CSRF.Secure
+			},
+		},
+		{
+			ID:         "security-empty-csp",
+			Key:        "SECURITY_CSP_POLICY",
+			Message:    "no Content-Security-Policy header is sent",
+			Severities: strictSeverities,
+			Violated: func(cfg *ConfigVars) bool {
+				return strings.TrimSpace(cfg.Security.CSPPolicy) == ""
+			},
+		},
+		{
+			ID:         "clerk-sample-keys",
+			Key:        "CLERK_SECRET",
+			Message:    "Clerk keys are the sample placeholders",
+			Severities: strictSeverities,
+			Violated: func(cfg *ConfigVars) bool {
+				return strings.HasPrefix(cfg.Clerk.Key, "your_") || strings.HasPrefix(cfg.Clerk.Secret, "your_")
+			},
+		},
+		{
+			ID:         "clerk-webhook-secret-missing",
+			Key:        "CLERK_WEBHOOK_SECRET",
+			Message:    "no Clerk webhook signing secret is set, so Clerk webhooks are refused",
+			Severities: strictSeverities,
+			Violated: func(cfg *ConfigVars) bool {
+				return cfg.Clerk.WebhookSecret == ""
+			},
+		},
+		{
+			ID:      "security-hsts-disabled",
+			Key:     "SECURITY_HSTS_MAX_AGE",
+			Message: "Strict-Transport-Security is disabled",
+			Severities: map[string]Severity{
+				EnvironmentProduction: SeverityWarning,
+			},
+			Violated: func(cfg *ConfigVars) bool {
+				return cfg.Security.HSTSMaxAge <= 0
+			},
+		},
+		{
+			ID:      "cors-local-origins",
+			Key:     "CORS_ALLOWED_ORIGINS",
+			Message: "CORS allows localhost or Postman origins",
+			Severities: map[string]Severity{
+				EnvironmentProduction: SeverityWarning,
+			},
+			Violated: func(cfg *ConfigVars) bool {
+				for _, origin := range cfg.CORS.AllowedOrigins {
+					if strings.Contains(origin, "localhost") || strings.Contains(origin, "127.0.0.1") || strings.Contains(origin, "postman.com") {
+						return true
+					}
+				}
+				return false
+			},
+		},
+		{
+			ID:      "logging-debug-level",
+			Key:     "LOG_LEVEL",
+			Message: "debug logging may write request data to logs",
+			Severities: map[string]Severity{
+				EnvironmentProduction: SeverityWarning,
+			},
+			Violated: func(cfg *ConfigVars) bool {
+				return cfg.Logging.Level == "debug"
+			},
+		},
+	}
+}
+
+// Audit runs every rule that applies to the configured environment.
+func Audit(cfg *ConfigVars, rules []AuditRule) AuditReport {
+	report := AuditReport{Environment: cfg.Server.Environment}
+	environment := auditedEnvironment(cfg.Server.Environment)
+	for _, rule := range rules {
+		severity, ok := rule.Severities[environment]
+		if !ok || !rule.Violated(cfg) {
+			continue
+		}
+		report.Findings = append(report.Findings, Finding{
+			Rule:     rule.ID,
+			Key:      EnvPrefix + rule.Key,
+			Severity: severity,
+			Message:  rule.Message,
+		})
+	}
+	return report
+}
+
+// Critical returns the findings with critical severity.
+func (ar AuditReport) Critical() []Finding {
+	critical := make([]Finding, 0)
+	for _, finding := range ar.Findings {
+		if finding.Severity == SeverityCritical {
+			critical = append(critical, finding)
+		}
+	}
+	return critical
+}
+
+// Enforce returns ErrUnsafeConfig when a production config, or one for an unknown
+// environment, has critical findings, unless allowUnsafe is set.
+func (ar AuditReport) Enforce(allowUnsafe bool) error {
+	critical := ar.Critical()
+	if auditedEnvironment(ar.Environment) != EnvironmentProduction || len(critical) == 0 || allowUnsafe {
+		return nil
+	}
+
+	rules := make([]string, 0, len(critical))
+	for _, finding := range critical {
+		rules = append(rules, finding.Rule)
+	}
+	return fmt.Errorf("%w: %d critical findings (%s); set %sAUDIT_ALLOW_UNSAFE=true to start anyway",
+		ErrUnsafeConfig, len(critical), strings.Join(rules, ", "), EnvPrefix)
+}
+
+// auditedEnvironment returns the environment whose severities apply to environment
+func auditedEnvironment(environment string) string {
+	if auditedEnvironments[environment] {
+		return environment
+	}
+	return EnvironmentProduction
+}
+
+// Print writes one line per finding to w.
+func (ar AuditReport) Print(w io.Writer) {
+	if len(ar.Findings) == 0 {
+		fmt.Fprintf(w, "config audit (%s): no findings\n", ar.Environment)
+		return
+	}
+
+	fmt.Fprintf(w, "config audit (%s): %d findings\n", ar.Environment, len(ar.Findings))
+	for _, finding := range ar.Findings {
+		fmt.Fprintf(w, "  [%-8s] %-26s %s: %s\n", finding.Severity, finding.Rule, finding.Key, finding.Message)
+	}
+}
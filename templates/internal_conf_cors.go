@@ -0,0 +1,78 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+var ErrInsecureCORS = errors.New("credentials cannot be allowed for a wildcard origin")
+
+// Validate checks that every origin is "*", a scheme://host origin, or a subdomain
+// pattern such as https://*.example.com, and that credentials are not combined
+// with any wildcard origin.
+func (cv CORSVars) Validate() error {
+	loadErr := &LoadError{}
+	addError := func(key, field string, err error) {
+		loadErr.Errors = append(loadErr.Errors, FieldError{Key: EnvPrefix + key, Field: "ConfigVars.CORS." + field, Err: err})
+	}
+
+	for _, origin := range cv.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			addError("CORS_ALLOWED_ORIGINS", "AllowedOrigins", err)
+		}
+		if strings.Contains(origin, "*") && cv.AllowCredentials {
+			addError("CORS_ALLOWED_ORIGINS", "AllowedOrigins", ErrInsecureCORS)
+		}
+	}
+	for _, origin := range cv.WebhookAllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			addError("CORS_WEBHOOK_ALLOWED_ORIGINS", "WebhookAllowedOrigins", err)
+		}
+	}
+
+	if len(loadErr.Errors) > 0 {
+		return loadErr
+	}
+	return nil
+}
+
+// validateOrigin accepts a wildcard only as the leftmost label of the host, in
+// front of at least a registrable domain: https://*.example.com, but not
+// https://* or https://*.co.uk
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+
+	scheme, host, wildcard := strings.Cut(origin, "://*.")
+	if wildcard {
+		origin = scheme + "://" + host
+	}
+	if strings.Contains(origin, "*") {
+		return fmt.Errorf("%w: %q may only use a wildcard as the leftmost label, e.g. https://*.example.com", ErrMalformedValue, origin)
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("%w: %q is not a scheme://host origin", ErrMalformedValue, origin)
+	}
+	if parsed.Path != "" || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return fmt.Errorf("%w: %q must not have a path", ErrMalformedValue, origin)
+	}
+
+	if wildcard {
+		domain := parsed.Hostname()
+		if net.ParseIP(domain) != nil {
+			return fmt.Errorf("%w: %q puts a wildcard in front of an IP address", ErrMalformedValue, origin)
+		}
+		if _, err := publicsuffix.EffectiveTLDPlusOne(domain); err != nil {
+			return fmt.Errorf("%w: %q puts a wildcard in front of a public suffix, not a registrable domain", ErrMalformedValue, origin)
+		}
+	}
+	return nil
+}
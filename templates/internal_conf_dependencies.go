@@ -1,13 +1,21 @@
 package conf
 
 import (
+	auditController "{{.Module}}/internal/audit/controller"
+	auditDatasource "{{.Module}}/internal/audit/datasource"
+	auditService "{{.Module}}/internal/audit/service"
 	healthController "{{.Module}}/internal/health/controller"
 	healthService "{{.Module}}/internal/health/service"
+	membershipsController "{{.Module}}/internal/memberships/controller"
+	membershipsDatasource "{{.Module}}/internal/memberships/datasource"
+	membershipsService "{{.Module}}/internal/memberships/service"
 	organizationsController "{{.Module}}/internal/organizations/controller"
 	organizationsDatasource "{{.Module}}/internal/organizations/datasource"
+	"{{.Module}}/internal/organizations/identity"
 	organizationsService "{{.Module}}/internal/organizations/service"
 	"{{.Module}}/internal/shared/logger"
 	"{{.Module}}/internal/shared/middleware"
+	"{{.Module}}/internal/shared/transaction"
 	usersController "{{.Module}}/internal/users/controller"
 	usersDatasource "{{.Module}}/internal/users/datasource"
 	usersService "{{.Module}}/internal/users/service"
@@ -23,47 +31,98 @@ type ExternalDependencies struct {
 type Controllers struct {
 	Users         usersController.UsersController
 	Organizations organizationsController.OrganizationsController
+	Memberships   membershipsController.MembershipsController
+	Audit         auditController.AuditController
 	Health        healthController.HealthController
 }
 
+type Services struct {
+	Users         usersService.UsersService
+	Organizations organizationsService.OrganizationsService
+	Memberships   membershipsService.MembershipsService
+	Audit         auditService.AuditService
+}
+
 type Dependencies struct {
 	Config               *ConfigVars
+	Reloader             *Reloader
 	ExternalDependencies ExternalDependencies
+	Services             Services
 	Controllers          Controllers
 	Middleware           *middleware.Middleware
+	// Transactions runs units of work spanning several datasources
+	Transactions *transaction.Manager
+	// DatabaseAvailable reports whether the database is reachable; nil means always
+	DatabaseAvailable func() bool
 }
 
-func LoadDependencies(logger *logger.Logger, config *ConfigVars, db *gorm.DB) *Dependencies {
-	// Initialize Clerk client
-	clerkClient, _ := clerk.NewClient(config.Clerk.Key)
+// LoadDependencies wires every module. ready reports readiness for the health
+// endpoint and may be nil when nothing will serve traffic. databaseAvailable
+// gates the database-backed routes and may be nil when the database is always up.
+func LoadDependencies(logger *logger.Logger, reloader *Reloader, db *gorm.DB, ready func() bool, databaseAvailable func() bool) *Dependencies {
+	config := reloader.Current()
+
+	// Initialize Clerk client; verifying session tokens needs the secret key
+	clerkClient, err := clerk.NewClient(config.Clerk.Secret)
+	if err != nil {
+		logger.Error("failed to create clerk client, authenticated routes return 503", "error", err)
+	}
 
 	// Initialize datasources
 	usersDS := usersDatasource.NewDatasource(logger, db)
 	organizationsDS := organizationsDatasource.NewDatasource(logger, db)
+	membershipsDS := membershipsDatasource.NewDatasource(logger, db)
+	auditDS := auditDatasource.NewDatasource(logger, db)
+	transactions := transaction.NewManager(logger, db, transaction.Options{
+		MaxAttempts: config.Database.TxMaxAttempts,
+		Backoff:     config.Database.TxRetryBackoff,
+	})
+
+	// Organization changes made through the API stay local unless pushed to Clerk
+	var organizationsIdentity identity.Provider
+	if config.Clerk.SyncOrganizations && clerkClient != nil {
+		organizationsIdentity = identity.NewClerkProvider(clerkClient)
+	}
 
 	// Initialize services
-	usersSvc := usersService.NewService(logger, usersDS)
-	organizationsSvc := organizationsService.NewService(logger, organizationsDS)
-	healthSvc := healthService.NewService(logger, db)
+	auditSvc := auditService.NewService(logger, auditDS)
+	usersSvc := usersService.NewService(logger, usersDS, membershipsDS, transactions, auditSvc)
+	organizationsSvc := organizationsService.NewService(logger, organizationsDS, usersDS, membershipsDS, transactions, organizationsIdentity, auditSvc)
+	membershipsSvc := membershipsService.NewService(logger, membershipsDS, usersDS, organizationsDS)
+	healthSvc := healthService.NewService(logger, db, reloader.Version, ready)
 
 	// Initialize controllers
 	usersCtrl := usersController.NewController(logger, usersSvc)
 	organizationsCtrl := organizationsController.NewController(logger, organizationsSvc)
+	membershipsCtrl := membershipsController.NewController(logger, membershipsSvc)
+	auditCtrl := auditController.NewController(logger, auditSvc)
 	healthCtrl := healthController.NewController(logger, healthSvc)
 
 	// Initialize middleware
 	mw := middleware.NewMiddleware(clerkClient, config.Clerk.Secret)
+	mw.ClerkWebhookSecret = config.Clerk.WebhookSecret
 
 	return &Dependencies{
-		Config: config,
+		Config:   config,
+		Reloader: reloader,
 		ExternalDependencies: ExternalDependencies{
 			Clerk: clerkClient,
 		},
+		Services: Services{
+			Users:         usersSvc,
+			Organizations: organizationsSvc,
+			Memberships:   membershipsSvc,
+			Audit:         auditSvc,
+		},
 		Controllers: Controllers{
 			Users:         usersCtrl,
 			Organizations: organizationsCtrl,
+			Memberships:   membershipsCtrl,
+			Audit:         auditCtrl,
 			Health:        healthCtrl,
 		},
-		Middleware: mw,
+		Middleware:        mw,
+		Transactions:      transactions,
+		DatabaseAvailable: databaseAvailable,
 	}
 }
@@ -0,0 +1,131 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"{{.Module}}/internal/shared/logger"
+
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// tablePattern finds the table a statement reads from or writes to
+var tablePattern = regexp.MustCompile(`(?i)\b(?:from|into|update|table)\s+"?([a-zA-Z0-9_.]+)"?`)
+
+type GormLoggerConfig struct {
+	// SlowThreshold logs queries at or above it as warnings; 0 disables it
+	SlowThreshold time.Duration
+	// LogParams includes bound parameter values in the logged SQL. They are
+	// replaced by their placeholders by default since they may hold personal data.
+	LogParams bool
+}
+
+// GormLogger sends GORM logs to the application logger as structured entries,
+// tagged with the trace and request IDs of the query context.
+type GormLogger struct {
+	log    *logger.Logger
+	config GormLoggerConfig
+	level  gormLogger.LogLevel
+}
+
+var (
+	_ gormLogger.Interface = (*GormLogger)(nil)
+	_ gorm.ParamsFilter    = (*GormLogger)(nil)
+)
+
+func NewGormLogger(log *logger.Logger, config GormLoggerConfig) *GormLogger {
+	return &GormLogger{
+		log:    log.With("component", "gorm"),
+		config: config,
+		level:  gormLogger.Info,
+	}
+}
+
+// LogMode returns a copy logging at level; the application log level still applies.
+func (gl *GormLogger) LogMode(level gormLogger.LogLevel) gormLogger.Interface {
+	copied := *gl
+	copied.level = level
+	return &copied
+}
+
+func (gl *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if gl.level >= gormLogger.Info {
+		gl.log.WithContext(ctx).Info(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (gl *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if gl.level >= gormLogger.Warn {
+		gl.log.WithContext(ctx).Warn(fmt.Sprintf(msg, data...))
+	}
+}
+
+func (gl *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if gl.level >= gormLogger.Error {
+		gl.log.WithContext(ctx).Error(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace logs every executed statement through LogDBOperation, and slow ones as warnings.
+// gorm.ErrRecordNotFound is an expected outcome, not a failure.
+func (gl *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if gl.level <= gormLogger.Silent {
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+
+	elapsed := time.Since(begin)
+	slow := gl.config.SlowThreshold > 0 && elapsed >= gl.config.SlowThreshold
+	switch {
+	case err != nil && gl.level >= gormLogger.Error:
+	case slow && gl.level >= gormLogger.Warn:
+	case gl.level >= gormLogger.Info:
+	default:
+		return
+	}
+
+	sql, rows := fc()
+	operation, table := describeSQL(sql)
+	log := gl.log.WithContext(ctx).With("sql", sql)
+
+	log.LogDBOperation(operation, table, elapsed, rows, err)
+	if slow {
+		log.Warn("Slow database query",
+			"operation", operation,
+			"table", table,
+			"duration_ms", elapsed.Milliseconds(),
+			"threshold_ms", gl.config.SlowThreshold.Milliseconds(),
+			"rows_affected", rows,
+		)
+	}
+}
+
+// ParamsFilter drops bound values unless LogParams is set, so the logged SQL
+// keeps its $n placeholders.
+func (gl *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if gl.config.LogParams {
+		return sql, params
+	}
+	return sql, nil
+}
+
+// describeSQL returns the statement verb and the first table it names.
+func describeSQL(sql string) (string, string) {
+	operation := "UNKNOWN"
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	table := ""
+	if match := tablePattern.FindStringSubmatch(sql); match != nil {
+		table = match[1]
+	}
+	return operation, table
+}
@@ -0,0 +1,199 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LookupFunc resolves a fully qualified configuration key and reports whether it was set.
+type LookupFunc func(key string) (string, bool)
+
+// FieldError describes a single configuration value that could not be loaded.
+type FieldError struct {
+	Key   string
+	Field string
+	Err   error
+}
+
+func (fe FieldError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", fe.Key, fe.Field, fe.Err)
+}
+
+func (fe FieldError) Unwrap() error {
+	return fe.Err
+}
+
+// LoadError aggregates every FieldError found while loading a config struct.
+type LoadError struct {
+	Errors []FieldError
+}
+
+func (le *LoadError) Error() string {
+	messages := make([]string, 0, len(le.Errors))
+	for _, fe := range le.Errors {
+		messages = append(messages, fe.Error())
+	}
+	return fmt.Sprintf("invalid configuration (%d errors): %s", len(le.Errors), strings.Join(messages, "; "))
+}
+
+// Unwrap exposes every FieldError so errors.Is can match their causes.
+func (le *LoadError) Unwrap() []error {
+	errs := make([]error, 0, len(le.Errors))
+	for _, fe := range le.Errors {
+		errs = append(errs, fe)
+	}
+	return errs
+}
+
+var (
+	ErrMissingValue    = errors.New("value is required")
+	ErrMalformedValue  = errors.New("value is malformed")
+	ErrUnsupportedType = errors.New("unsupported field type")
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// envTag is the parsed form of an `env:"KEY,required"` struct tag.
+type envTag struct {
+	key      string
+	required bool
+}
+
+func parseEnvTag(tag string) envTag {
+	parts := strings.Split(tag, ",")
+	parsed := envTag{key: strings.TrimSpace(parts[0])}
+	for _, option := range parts[1:] {
+		if strings.TrimSpace(option) == "required" {
+			parsed.required = true
+		}
+	}
+	return parsed
+}
+
+// LoadStruct populates target, which must be a pointer to a struct, from lookup.
+// Fields are read from the key in their `env` tag joined to prefix, fall back to
+// their `default` tag, and nested structs without an `env` tag are walked
+// recursively. Every missing or malformed value is collected into a single *LoadError.
+func LoadStruct(prefix string, target interface{}, lookup LookupFunc) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config target must be a pointer to a struct, got %T", target)
+	}
+
+	loadErr := &LoadError{}
+	loadFields(prefix, value.Elem(), value.Elem().Type().Name(), lookup, loadErr)
+
+	if len(loadErr.Errors) > 0 {
+		return loadErr
+	}
+	return nil
+}
+
+func loadFields(prefix string, value reflect.Value, path string, lookup LookupFunc, loadErr *LoadError) {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		fieldPath := path + "." + field.Name
+
+		tag, hasTag := field.Tag.Lookup("env")
+		if !hasTag {
+			if field.Type.Kind() == reflect.Struct {
+				loadFields(prefix, fieldValue, fieldPath, lookup, loadErr)
+			}
+			continue
+		}
+
+		env := parseEnvTag(tag)
+		key := prefix + env.key
+
+		raw, ok := lookup(key)
+		if !ok || raw == "" {
+			raw, ok = field.Tag.Lookup("default")
+		}
+
+		if !ok {
+			if env.required {
+				loadErr.Errors = append(loadErr.Errors, FieldError{Key: key, Field: fieldPath, Err: ErrMissingValue})
+			}
+			continue
+		}
+
+		if err := setFieldValue(fieldValue, raw); err != nil {
+			loadErr.Errors = append(loadErr.Errors, FieldError{Key: key, Field: fieldPath, Err: err})
+		}
+	}
+}
+
+func setFieldValue(field reflect.Value, raw string) error {
+	if field.Type() == durationType {
+		parsed, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a duration", ErrMalformedValue, raw)
+		}
+		field.SetInt(int64(parsed))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a boolean", ErrMalformedValue, raw)
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(strings.TrimSpace(raw), 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %q is not an integer", ErrMalformedValue, raw)
+		}
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(strings.TrimSpace(raw), 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %q is not an unsigned integer", ErrMalformedValue, raw)
+		}
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(raw), field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%w: %q is not a number", ErrMalformedValue, raw)
+		}
+		field.SetFloat(parsed)
+	case reflect.Slice:
+		return setSliceValue(field, raw)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, field.Type())
+	}
+	return nil
+}
+
+// setSliceValue splits raw on commas and parses each element with setFieldValue.
+func setSliceValue(field reflect.Value, raw string) error {
+	items := make([]string, 0)
+	for _, item := range strings.Split(raw, ",") {
+		if trimmed := strings.TrimSpace(item); trimmed != "" {
+			items = append(items, trimmed)
+		}
+	}
+
+	slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := setFieldValue(slice.Index(i), item); err != nil {
+			return err
+		}
+	}
+
+	field.Set(slice)
+	return nil
+}
@@ -1,7 +1,13 @@
 package conf
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/driver/postgres"
@@ -17,25 +23,178 @@ type PGConfig struct {
 	DBName   string
 	Password string
 	SSLMode  string
-	MaxConns int
-	Logger   *logger.Logger
+	// URL is a complete connection string, key=value or postgres://, used
+	// instead of the fields above, e.g. for a read replica
+	URL    string
+	Logger *logger.Logger
+
+	// Pool
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration // 0 keeps connections forever
+	ConnMaxIdleTime time.Duration // 0 keeps idle connections forever
+
+	// Session
+	ApplicationName        string        // shown in pg_stat_activity
+	StatementTimeout       time.Duration // 0 disables the server-side limit
+	StatementCacheCapacity int           // prepared statements cached per connection; 0 disables, e.g. behind PgBouncer
+
+	// GORM
+	PrepareStmt            bool
+	SkipDefaultTransaction bool
+	SlowQueryThreshold     time.Duration // 0 disables slow query warnings
+	LogQueryParams         bool          // log bound values instead of placeholders
+}
+
+type dsnParam struct{ key, value string }
+
+// DSN builds the connection string, quoting every value. With URL set, the
+// session settings are added to it unless it already sets them.
+func (c PGConfig) DSN() string {
+	session := []dsnParam{
+		{"statement_cache_capacity", strconv.Itoa(c.StatementCacheCapacity)},
+	}
+	if c.ApplicationName != "" {
+		session = append(session, dsnParam{"application_name", c.ApplicationName})
+	}
+	if c.StatementTimeout > 0 {
+		session = append(session, dsnParam{"statement_timeout", strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10)})
+	}
+	if c.URL != "" {
+		return withSessionParams(c.URL, session)
+	}
+
+	params := []dsnParam{
+		{"host", c.Host},
+		{"user", c.User},
+		{"password", c.Password},
+		{"dbname", c.DBName},
+		{"port", c.Port},
+		{"sslmode", c.SSLMode},
+	}
+	params = append(params, session...)
+
+	parts := make([]string, 0, len(params))
+	for _, param := range params {
+		parts = append(parts, param.key+"="+quoteDSNValue(param.value))
+	}
+	return strings.Join(parts, " ")
+}
+
+func quoteDSNValue(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	return "'" + escaped + "'"
+}
+
+// withSessionParams adds params missing from dsn, as query parameters of a
+// postgres:// URL or as key=value pairs
+func withSessionParams(dsn string, params []dsnParam) string {
+	if strings.Contains(dsn, "://") {
+		parsed, err := url.Parse(dsn)
+		if err != nil {
+			// Left for the driver to report
+			return dsn
+		}
+		query := parsed.Query()
+		for _, param := range params {
+			if !query.Has(param.key) {
+				query.Set(param.key, param.value)
+			}
+		}
+		parsed.RawQuery = query.Encode()
+		return parsed.String()
+	}
+
+	for _, param := range params {
+		if !regexp.MustCompile(`(^|\s)` + param.key + `\s*=`).MatchString(dsn) {
+			dsn += " " + param.key + "=" + quoteDSNValue(param.value)
+		}
+	}
+	return dsn
+}
+
+// DSNHost returns the host of a connection string, for logs that must not show credentials.
+func DSNHost(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		if parsed, err := url.Parse(dsn); err == nil {
+			return parsed.Host
+		}
+		return "unknown"
+	}
+	if match := regexp.MustCompile(`(?:^|\s)host\s*=\s*'?([^'\s]+)`).FindStringSubmatch(dsn); match != nil {
+		return match[1]
+	}
+	return "unknown"
+}
+
+// pingTimeout bounds a single connection attempt
+const pingTimeout = 5 * time.Second
+
+// RetryPolicy controls how connecting to the database is retried.
+type RetryPolicy struct {
+	Attempts       int           // 0 retries until the context is done
+	InitialBackoff time.Duration // wait after the first failure, doubled on each retry
+	MaxBackoff     time.Duration
+}
+
+// Backoff returns the wait after the given failed attempt (1-based): exponential
+// growth capped at MaxBackoff, with jitter over the upper half so instances
+// restarted together do not reconnect in lockstep.
+func (rp RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := rp.InitialBackoff
+	for i := 1; i < attempt && backoff < rp.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if rp.MaxBackoff > 0 && backoff > rp.MaxBackoff {
+		backoff = rp.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
 }
 
+// InitConnectionPool opens the pool and fails unless the database answers a ping.
 func InitConnectionPool(config PGConfig) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-		config.Host,
-		config.User,
-		config.Password,
-		config.DBName,
-		config.Port,
-		config.SSLMode,
-	)
+	db, err := OpenConnectionPool(config)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying sql.DB: %w", err)
+	}
+
+	// Test the connection
+	if err := sqlDB.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return db, nil
+}
+
+// OpenConnectionPool configures the pool without connecting; connections are
+// made on first use, so the handle is valid while the database is still down.
+func OpenConnectionPool(config PGConfig) (*gorm.DB, error) {
+	var queryLogger gormLogger.Interface = gormLogger.Discard
+	if config.Logger != nil {
+		queryLogger = NewGormLogger(config.Logger, GormLoggerConfig{
+			SlowThreshold: config.SlowQueryThreshold,
+			LogParams:     config.LogQueryParams,
+		})
+	}
 
 	gormConfig := &gorm.Config{
-		Logger: gormLogger.Default.LogMode(gormLogger.Info),
+		Logger:                 queryLogger,
+		DisableAutomaticPing:   true,
+		PrepareStmt:            config.PrepareStmt,
+		SkipDefaultTransaction: config.SkipDefaultTransaction,
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), gormConfig)
+	db, err := gorm.Open(postgres.Open(config.DSN()), gormConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -46,14 +205,71 @@ func InitConnectionPool(config PGConfig) (*gorm.DB, error) {
 	}
 
 	// Configure connection pool
-	sqlDB.SetMaxOpenConns(config.MaxConns)
-	sqlDB.SetMaxIdleConns(config.MaxConns / 2)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
 
-	// Test the connection
-	if err := sqlDB.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	if config.Logger != nil {
+		host := config.Host
+		if config.URL != "" {
+			host = DSNHost(config.URL)
+		}
+		config.Logger.Info("database pool configured",
+			"host", host,
+			"max_open_conns", sqlDB.Stats().MaxOpenConnections,
+			"max_idle_conns", config.MaxIdleConns,
+			"conn_max_lifetime", config.ConnMaxLifetime.String(),
+			"conn_max_idle_time", config.ConnMaxIdleTime.String(),
+			"statement_timeout", config.StatementTimeout.String(),
+			"statement_cache_capacity", config.StatementCacheCapacity,
+			"application_name", config.ApplicationName,
+			"prepare_stmt", config.PrepareStmt,
+			"skip_default_transaction", config.SkipDefaultTransaction,
+			"slow_query_threshold", config.SlowQueryThreshold.String(),
+		)
 	}
 
 	return db, nil
 }
+
+// ConnectDatabase pings db until it answers, waiting policy.Backoff between
+// attempts. It gives up after policy.Attempts or when ctx is done, returning
+// the last connection error.
+func ConnectDatabase(ctx context.Context, db *gorm.DB, policy RetryPolicy, logger *logger.Logger) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get underlying sql.DB: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err = sqlDB.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			logger.Info("database connected", "attempt", attempt)
+			return nil
+		}
+
+		if policy.Attempts > 0 && attempt >= policy.Attempts {
+			logger.Error("database connection failed, giving up", "attempt", attempt, "max_attempts", policy.Attempts, "error", err)
+			return fmt.Errorf("failed to connect to database after %d attempts: %w", attempt, err)
+		}
+
+		wait := policy.Backoff(attempt)
+		logger.Warn("database connection failed, retrying",
+			"attempt", attempt,
+			"max_attempts", policy.Attempts,
+			"retry_in", wait.String(),
+			"error", err,
+		)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("failed to connect to database after %d attempts: %w", attempt, err)
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package conf
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"{{.Module}}/internal/shared/logger"
+)
+
+// ReloadHook validates a freshly loaded config and returns a function that applies
+// the reloadable parts of it. Returning an error rejects the whole reload.
+type ReloadHook func(cfg *ConfigVars) (apply func(), err error)
+
+// reloadableSections are the ConfigVars fields a reload swaps in; changes to any
+// other section only take effect after a restart
+var reloadableSections = map[string]bool{
+	"Logging":   true,
+	"RateLimit": true,
+	"CORS":      true,
+	"Security":  true,
+}
+
+// Reloader re-reads configuration on SIGHUP (and optionally when config files
+// change), validates it and swaps the reloadable settings in one step.
+type Reloader struct {
+	log        *logger.Logger
+	opts       LoadOptions
+	current    atomic.Pointer[ConfigVars]
+	version    atomic.Value
+	generation uint64
+
+	mu    sync.Mutex
+	hooks []ReloadHook
+}
+
+func NewReloader(logger *logger.Logger, initial *ConfigVars, opts LoadOptions) *Reloader {
+	reloader := &Reloader{
+		log:  logger.With("component", "config_reloader"),
+		opts: opts,
+	}
+	reloader.current.Store(initial)
+	reloader.version.Store(configVersion(1, initial))
+	reloader.generation = 1
+	return reloader
+}
+
+// Current returns the active configuration.
+func (r *Reloader) Current() *ConfigVars {
+	return r.current.Load()
+}
+
+// Version identifies the active configuration as "<generation>-<hash>".
+func (r *Reloader) Version() string {
+	return r.version.Load().(string)
+}
+
+// OnReload registers a hook that takes part in every reload.
+func (r *Reloader) OnReload(hook ReloadHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, hook)
+}
+
+// Reload loads and validates the configuration, runs every hook and only applies
+// the changes once all of them accepted it. Only the reloadable sections are
+// swapped in; changes to the others are logged and wait for a restart.
+func (r *Reloader) Reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loaded, err := LoadConfigVars(ctx, r.opts)
+	if err != nil {
+		return err
+	}
+	cfg, ignored := mergeReloadable(r.Current(), loaded)
+
+	applies := make([]func(), 0, len(r.hooks))
+	var hookErrors []error
+	for _, hook := range r.hooks {
+		apply, err := hook(cfg)
+		if err != nil {
+			hookErrors = append(hookErrors, err)
+			continue
+		}
+		if apply != nil {
+			applies = append(applies, apply)
+		}
+	}
+	if len(hookErrors) > 0 {
+		return errors.Join(hookErrors...)
+	}
+
+	for _, apply := range applies {
+		apply()
+	}
+	if len(ignored) > 0 {
+		r.log.Warn("config changes need a restart and were ignored", "sections", ignored)
+	}
+
+	r.generation++
+	r.current.Store(cfg)
+	r.version.Store(configVersion(r.generation, cfg))
+	return nil
+}
+
+// Watch reloads on SIGHUP until ctx is done, and also when a config file changes
+// if the Reload.WatchFiles setting is enabled.
+func (r *Reloader) Watch(ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var ticks <-chan time.Time
+	if settings := r.Current().Reload; settings.WatchFiles && settings.WatchInterval > 0 {
+		ticker := time.NewTicker(settings.WatchInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	r.log.Info("watching for config changes", "config_version", r.Version())
+	fingerprint := r.fingerprint()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			r.reloadAndLog(ctx, "sighup")
+			fingerprint = r.fingerprint()
+		case <-ticks:
+			if current := r.fingerprint(); current != fingerprint {
+				fingerprint = current
+				r.reloadAndLog(ctx, "file_change")
+			}
+		}
+	}
+}
+
+func (r *Reloader) reloadAndLog(ctx context.Context, trigger string) {
+	previous := r.Version()
+	if err := r.Reload(ctx); err != nil {
+		r.log.Error("config reload rejected", "trigger", trigger, "reason", err, "config_version", previous)
+		return
+	}
+	r.log.Info("config reloaded", "trigger", trigger, "previous_version", previous, "config_version", r.Version())
+}
+
+// fingerprint summarises the modification times of every config file that is read.
+func (r *Reloader) fingerprint() string {
+	dir := r.opts.dir()
+	environment := r.Current().Server.Environment
+	files := []string{".env", "config.yaml", ".env." + environment, "config." + environment + ".yaml", ".env.local", SecretsFileName}
+
+	hash := sha256.New()
+	for _, name := range files {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			fmt.Fprintf(hash, "%s:%d:%d;", name, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// mergeReloadable returns current with the reloadable sections of loaded, and the
+// names of the other sections that differ between the two
+func mergeReloadable(current, loaded *ConfigVars) (*ConfigVars, []string) {
+	merged := *current
+	mergedValue := reflect.ValueOf(&merged).Elem()
+	currentValue := reflect.ValueOf(current).Elem()
+	loadedValue := reflect.ValueOf(loaded).Elem()
+
+	var ignored []string
+	for i := 0; i < mergedValue.NumField(); i++ {
+		name := mergedValue.Type().Field(i).Name
+		if name == "Origins" {
+			continue
+		}
+		if reloadableSections[name] {
+			mergedValue.Field(i).Set(loadedValue.Field(i))
+			continue
+		}
+		if !reflect.DeepEqual(currentValue.Field(i).Interface(), loadedValue.Field(i).Interface()) {
+			ignored = append(ignored, name)
+		}
+	}
+	return &merged, ignored
+}
+
+func configVersion(generation uint64, cfg *ConfigVars) string {
+	sum := sha256.Sum256([]byte(cfg.String()))
+	return fmt.Sprintf("%d-%s", generation, hex.EncodeToString(sum[:])[:12])
+}
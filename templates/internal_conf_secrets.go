@@ -0,0 +1,278 @@
+package conf
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// RedactedValue replaces secret values in logs and config dumps.
+const RedactedValue = "[REDACTED]"
+
+// SecretsFileName is the encrypted dotenv file read from the config directory.
+const SecretsFileName = ".secrets.enc"
+
+var ErrInvalidMasterKey = errors.New("master key must be 32 bytes, hex or base64 encoded")
+
+// SecretProvider resolves secret config values from an external store such as a vault.
+// Providers are only consulted for fields tagged `secret:"true"` that no other layer set.
+type SecretProvider interface {
+	Name() string
+	GetSecret(ctx context.Context, key string) (string, bool, error)
+}
+
+// secretResolver wraps a LookupFunc so that every key can also be read from a
+// KEY_FILE path, and secret keys fall through to the configured SecretProviders.
+type secretResolver struct {
+	ctx        context.Context
+	lookup     LookupFunc
+	secretKeys map[string]bool
+	providers  []SecretProvider
+	origins    ValueOrigins
+	errors     []FieldError
+}
+
+func newSecretResolver(ctx context.Context, lookup LookupFunc, secretKeys []string, providers []SecretProvider) *secretResolver {
+	keys := make(map[string]bool, len(secretKeys))
+	for _, key := range secretKeys {
+		keys[key] = true
+	}
+	return &secretResolver{
+		ctx:        ctx,
+		lookup:     lookup,
+		secretKeys: keys,
+		providers:  providers,
+		origins:    make(ValueOrigins),
+	}
+}
+
+func (sr *secretResolver) Lookup(key string) (string, bool) {
+	if value, ok := sr.lookup(key); ok {
+		return value, true
+	}
+
+	if path, ok := sr.lookup(key + "_FILE"); ok {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			sr.errors = append(sr.errors, FieldError{Key: key + "_FILE", Field: path, Err: err})
+			return "", false
+		}
+		sr.origins[key] = "file:" + path
+		return strings.TrimSpace(string(content)), true
+	}
+
+	if !sr.secretKeys[key] {
+		return "", false
+	}
+
+	for _, provider := range sr.providers {
+		value, ok, err := provider.GetSecret(sr.ctx, key)
+		if err != nil {
+			sr.errors = append(sr.errors, FieldError{Key: key, Field: provider.Name(), Err: err})
+			continue
+		}
+		if ok && value != "" {
+			sr.origins[key] = provider.Name()
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// secretKeys lists the prefixed keys of every field tagged `secret:"true"`.
+func secretKeys(prefix string, structType reflect.Type) []string {
+	keys := make([]string, 0)
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, ok := field.Tag.Lookup("env")
+		if !ok {
+			if field.Type.Kind() == reflect.Struct {
+				keys = append(keys, secretKeys(prefix, field.Type)...)
+			}
+			continue
+		}
+		if field.Tag.Get("secret") == "true" {
+			keys = append(keys, prefix+parseEnvTag(tag).key)
+		}
+	}
+	return keys
+}
+
+// Redact replaces every non-empty string field tagged `secret:"true"` in target,
+// a pointer to a struct, with RedactedValue, and each element of such []string fields.
+func Redact(target interface{}) {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return
+	}
+	redactFields(value.Elem())
+}
+
+func redactFields(value reflect.Value) {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			redactFields(fieldValue)
+			continue
+		}
+
+		if field.Tag.Get("secret") != "true" {
+			continue
+		}
+		switch {
+		case field.Type.Kind() == reflect.String && fieldValue.String() != "":
+			fieldValue.SetString(RedactedValue)
+		case field.Type == reflect.TypeOf([]string(nil)) && fieldValue.Len() > 0:
+			// A new slice, since copies of the struct share the original
+			masked := make([]string, fieldValue.Len())
+			for i := range masked {
+				masked[i] = RedactedValue
+			}
+			fieldValue.Set(reflect.ValueOf(masked))
+		}
+	}
+}
+
+// redactedConfig has no methods so that slog does not resolve it as a LogValuer again.
+type redactedConfig ConfigVars
+
+// Redacted returns a copy of the config with every secret value masked.
+func (cv ConfigVars) Redacted() ConfigVars {
+	redacted := cv
+	Redact(&redacted)
+	return redacted
+}
+
+// LogValue keeps secrets out of logs when the config is passed to the logger.
+func (cv ConfigVars) LogValue() slog.Value {
+	return slog.AnyValue(redactedConfig(cv.Redacted()))
+}
+
+// String keeps secrets out of fmt output.
+func (cv ConfigVars) String() string {
+	return fmt.Sprintf("%+v", redactedConfig(cv.Redacted()))
+}
+
+// EncryptedFileProvider serves secrets from a dotenv file encrypted with AES-256-GCM.
+type EncryptedFileProvider struct {
+	path   string
+	values map[string]string
+}
+
+// NewEncryptedFileProvider decrypts the file at path with masterKey (32 bytes, hex or base64).
+func NewEncryptedFileProvider(path string, masterKey string) (*EncryptedFileProvider, error) {
+	key, err := ParseMasterKey(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := DecryptSecrets(encrypted, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+
+	values, err := godotenv.Unmarshal(string(plaintext))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return &EncryptedFileProvider{path: path, values: values}, nil
+}
+
+func (efp *EncryptedFileProvider) Name() string {
+	return "secrets:" + filepath.Base(efp.path)
+}
+
+func (efp *EncryptedFileProvider) GetSecret(ctx context.Context, key string) (string, bool, error) {
+	value, ok := efp.values[key]
+	return value, ok, nil
+}
+
+// ParseMasterKey decodes a hex or base64 encoded 32 byte key.
+func ParseMasterKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, ErrInvalidMasterKey
+}
+
+// EncryptSecrets seals plaintext with AES-256-GCM and returns base64(nonce || ciphertext).
+func EncryptSecrets(plaintext []byte, key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(sealed)))
+	base64.StdEncoding.Encode(encoded, sealed)
+	return encoded, nil
+}
+
+// DecryptSecrets reverses EncryptSecrets.
+func DecryptSecrets(encoded []byte, key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("encrypted secrets are truncated")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, ErrInvalidMasterKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,302 @@
+package conf
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// OriginDefault marks values that were not found in any source and came from a `default` tag.
+const OriginDefault = "default"
+
+// Source is a single layer of configuration values.
+type Source interface {
+	Name() string
+	Lookup(key string) (string, bool)
+}
+
+// MapSource is a Source backed by an in-memory map of fully qualified keys.
+type MapSource struct {
+	name   string
+	values map[string]string
+}
+
+func NewMapSource(name string, values map[string]string) *MapSource {
+	return &MapSource{name: name, values: values}
+}
+
+func (ms *MapSource) Name() string {
+	return ms.name
+}
+
+func (ms *MapSource) Lookup(key string) (string, bool) {
+	value, ok := ms.values[key]
+	return value, ok
+}
+
+// EnvSource reads values from the process environment.
+type EnvSource struct{}
+
+func (EnvSource) Name() string {
+	return "env"
+}
+
+func (EnvSource) Lookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+// LoadEnvFile reads a dotenv file into a Source. A missing file returns os.ErrNotExist.
+func LoadEnvFile(path string) (Source, error) {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMapSource("file:"+filepath.Base(path), values), nil
+}
+
+// LoadYAMLFile reads a YAML file into a Source. Nested keys are flattened into
+// upper snake case and prefixed, so `database: {host: db}` resolves PREFIX_DATABASE_HOST,
+// and lists are joined with commas. A missing file returns os.ErrNotExist.
+func LoadYAMLFile(path string, prefix string) (Source, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	flattenYAML(prefix, document, values)
+	return NewMapSource("file:"+filepath.Base(path), values), nil
+}
+
+func flattenYAML(prefix string, node map[string]interface{}, values map[string]string) {
+	for key, value := range node {
+		fullKey := prefix + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			flattenYAML(fullKey+"_", typed, values)
+		case []interface{}:
+			items := make([]string, 0, len(typed))
+			for _, item := range typed {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[fullKey] = strings.Join(items, ",")
+		case nil:
+			values[fullKey] = ""
+		default:
+			values[fullKey] = fmt.Sprint(typed)
+		}
+	}
+}
+
+// FlagSource exposes every `env` tagged config field as a command-line flag,
+// e.g. PREFIX_SERVER_PORT becomes --server-port. Only flags that were set are visible.
+type FlagSource struct {
+	fs     *flag.FlagSet
+	keys   map[string]string
+	values map[string]*string
+}
+
+// RegisterFlags registers a flag for every `env` tag on target (a pointer to a config struct).
+func RegisterFlags(fs *flag.FlagSet, prefix string, target interface{}) *FlagSource {
+	source := &FlagSource{
+		fs:     fs,
+		keys:   make(map[string]string),
+		values: make(map[string]*string),
+	}
+
+	for _, key := range envKeys(reflect.TypeOf(target).Elem()) {
+		name := strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+		source.keys[name] = prefix + key
+		source.values[name] = fs.String(name, "", fmt.Sprintf("override %s%s", prefix, key))
+	}
+
+	return source
+}
+
+func (fsrc *FlagSource) Name() string {
+	return "flag"
+}
+
+func (fsrc *FlagSource) Lookup(key string) (string, bool) {
+	found := false
+	var value string
+	fsrc.fs.Visit(func(f *flag.Flag) {
+		if fullKey, ok := fsrc.keys[f.Name]; ok && fullKey == key {
+			value = *fsrc.values[f.Name]
+			found = true
+		}
+	})
+	return value, found
+}
+
+// envKeys lists the unprefixed `env` tag keys of a config struct type.
+func envKeys(structType reflect.Type) []string {
+	keys := make([]string, 0)
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, ok := field.Tag.Lookup("env")
+		if !ok {
+			if field.Type.Kind() == reflect.Struct {
+				keys = append(keys, envKeys(field.Type)...)
+			}
+			continue
+		}
+		keys = append(keys, parseEnvTag(tag).key)
+	}
+	return keys
+}
+
+// Layers resolves keys against an ordered list of sources where later sources
+// win, and remembers which source supplied each value it returned.
+type Layers struct {
+	sources []Source
+	origins map[string]string
+}
+
+func NewLayers(sources ...Source) *Layers {
+	return &Layers{sources: sources, origins: make(map[string]string)}
+}
+
+// Lookup satisfies LookupFunc.
+func (l *Layers) Lookup(key string) (string, bool) {
+	for i := len(l.sources) - 1; i >= 0; i-- {
+		if value, ok := l.sources[i].Lookup(key); ok && value != "" {
+			l.origins[key] = l.sources[i].Name()
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Origins returns the source name for every key found so far.
+func (l *Layers) Origins() ValueOrigins {
+	origins := make(ValueOrigins, len(l.origins))
+	for key, origin := range l.origins {
+		origins[key] = origin
+	}
+	return origins
+}
+
+// ValueOrigins maps a fully qualified config key to the source its value came from.
+type ValueOrigins map[string]string
+
+// Sorted returns "KEY=source" pairs ordered by key, for debugging output.
+func (vo ValueOrigins) Sorted() []string {
+	pairs := make([]string, 0, len(vo))
+	for key, origin := range vo {
+		pairs = append(pairs, key+"="+origin)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// LoadOptions controls where LoadConfigVars looks for configuration.
+type LoadOptions struct {
+	// Dir holds the config files, defaults to the working directory
+	Dir string
+	// Flags are command-line overrides registered with RegisterFlags
+	Flags *FlagSource
+	// SecretProviders resolve secret fields that no other layer set
+	SecretProviders []SecretProvider
+}
+
+func (opts LoadOptions) dir() string {
+	if opts.Dir == "" {
+		return "."
+	}
+	return opts.Dir
+}
+
+// configLayers builds the source stack in increasing priority:
+// .env, config.yaml, .env.<env>, config.<env>.yaml, .env.local, process env, flags.
+func configLayers(opts LoadOptions) (*Layers, error) {
+	dir := opts.dir()
+
+	base, err := loadFiles(
+		fileLoader{path: filepath.Join(dir, ".env")},
+		fileLoader{path: filepath.Join(dir, "config.yaml"), yaml: true},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	local, err := loadFiles(fileLoader{path: filepath.Join(dir, ".env.local")})
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := []Source{EnvSource{}}
+	if opts.Flags != nil {
+		overrides = append(overrides, opts.Flags)
+	}
+
+	// The environment name may come from any layer except the environment-specific files themselves
+	probeSources := make([]Source, 0, len(base)+len(local)+len(overrides))
+	probeSources = append(probeSources, base...)
+	probeSources = append(probeSources, local...)
+	probeSources = append(probeSources, overrides...)
+	probe := NewLayers(probeSources...)
+	environment, ok := probe.Lookup(EnvPrefix + "SERVER_ENV")
+	if !ok {
+		environment = "development"
+	}
+
+	specific, err := loadFiles(
+		fileLoader{path: filepath.Join(dir, ".env."+environment)},
+		fileLoader{path: filepath.Join(dir, "config."+environment+".yaml"), yaml: true},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]Source, 0, len(base)+len(specific)+len(local)+len(overrides))
+	sources = append(sources, base...)
+	sources = append(sources, specific...)
+	sources = append(sources, local...)
+	sources = append(sources, overrides...)
+	return NewLayers(sources...), nil
+}
+
+type fileLoader struct {
+	path string
+	yaml bool
+}
+
+// loadFiles reads each file that exists, skipping missing ones.
+func loadFiles(loaders ...fileLoader) ([]Source, error) {
+	sources := make([]Source, 0, len(loaders))
+	for _, loader := range loaders {
+		var source Source
+		var err error
+		if loader.yaml {
+			source, err = LoadYAMLFile(loader.path, EnvPrefix)
+		} else {
+			source, err = LoadEnvFile(loader.path)
+		}
+
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", loader.path, err)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
@@ -1,39 +1,101 @@
 package conf
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"strconv"
+	"path/filepath"
+	"reflect"
+	"time"
 
-	"github.com/spf13/viper"
 	"{{.Module}}/internal/shared/validation"
 )
 
+// EnvPrefix is prepended to every `env` tag when configuration is loaded.
+const EnvPrefix = "{{.Name | upper}}_"
+
 type ClerkVars struct {
-	Key    string `validate:"required"`
-	Secret string `validate:"required"`
+	Key    string `env:"CLERK_KEY,required" validate:"required"`
+	Secret string `env:"CLERK_SECRET,required" secret:"true" validate:"required"`
+	// WebhookSecret is the whsec_ signing secret of the Clerk webhook endpoint; webhooks are refused without it
+	WebhookSecret string `env:"CLERK_WEBHOOK_SECRET" secret:"true"`
+	// SyncOrganizations pushes organizations created, updated or deleted through the API to Clerk
+	SyncOrganizations bool `env:"CLERK_SYNC_ORGANIZATIONS" default:"false"`
 }
 
 type ServerVars struct {
-	Name        string `validate:"required"`
-	Version     string `validate:"required"`
-	Environment string `validate:"required"`
-	Host        string `validate:"required"`
-	Port        string `validate:"required"`
-	Protocol    string `validate:"required"`
+	Name        string `env:"SERVER_NAME,required" validate:"required"`
+	Version     string `env:"SERVER_VERSION,required" validate:"required"`
+	Environment string `env:"SERVER_ENV" default:"development" validate:"required"`
+	Host        string `env:"SERVER_HOST" default:"localhost" validate:"required"`
+	Port        string `env:"SERVER_PORT" default:"{{.Port}}" validate:"required"`
+	Protocol    string `env:"SERVER_PROTOCOL" default:"http" validate:"required,oneof=http https"`
+}
+
+// TLSVars applies when SERVER_PROTOCOL is https
+type TLSVars struct {
+	CertFile       string        `env:"TLS_CERT_FILE"`
+	KeyFile        string        `env:"TLS_KEY_FILE"`
+	MinVersion     string        `env:"TLS_MIN_VERSION" default:"1.2" validate:"oneof=1.2 1.3"`
+	CipherPolicy   string        `env:"TLS_CIPHER_POLICY" default:"modern" validate:"oneof=modern compatible"`
+	RedirectPort   string        `env:"TLS_REDIRECT_PORT"` // plain HTTP port redirecting to HTTPS; empty disables it
+	ReloadInterval time.Duration `env:"TLS_RELOAD_INTERVAL" default:"1m" validate:"gt=0"`
 }
 
 type DatabaseVars struct {
-	DatabaseHost     string `validate:"required"`
-	DatabasePort     string `validate:"required"`
-	DatabaseName     string `validate:"required"`
-	DatabaseUser     string `validate:"required"`
-	DatabasePassword string `validate:"required"`
-	DatabaseSSLMode  string `validate:"required"`
+	DatabaseHost     string `env:"DATABASE_HOST,required" validate:"required"`
+	DatabasePort     string `env:"DATABASE_PORT" default:"5432" validate:"required"`
+	DatabaseName     string `env:"DATABASE_NAME,required" validate:"required"`
+	DatabaseUser     string `env:"DATABASE_USER,required" validate:"required"`
+	DatabasePassword string `env:"DATABASE_PASSWORD,required" secret:"true" validate:"required"`
+	DatabaseSSLMode  string `env:"DATABASE_SSL_MODE" default:"require" validate:"required"`
+
+	// Connection pool
+	MaxOpenConns    int           `env:"DATABASE_MAX_OPEN_CONNS" default:"10" validate:"gt=0"`
+	MaxIdleConns    int           `env:"DATABASE_MAX_IDLE_CONNS" default:"5" validate:"gte=0,ltefield=MaxOpenConns"`
+	ConnMaxLifetime time.Duration `env:"DATABASE_CONN_MAX_LIFETIME" default:"1h" validate:"gte=0"` // 0 keeps connections forever
+	ConnMaxIdleTime time.Duration `env:"DATABASE_CONN_MAX_IDLE_TIME" default:"10m" validate:"gte=0"`
+
+	// Session settings
+	ApplicationName        string        `env:"DATABASE_APPLICATION_NAME"`                                        // defaults to SERVER_NAME
+	StatementTimeout       time.Duration `env:"DATABASE_STATEMENT_TIMEOUT" default:"30s" validate:"gte=0"`        // 0 disables
+	StatementCacheCapacity int           `env:"DATABASE_STATEMENT_CACHE_CAPACITY" default:"512" validate:"gte=0"` // 0 behind transaction-pooling PgBouncer
+
+	// GORM
+	PrepareStmt            bool `env:"DATABASE_PREPARE_STMT" default:"false"`
+	SkipDefaultTransaction bool `env:"DATABASE_SKIP_DEFAULT_TRANSACTION" default:"false"`
+
+	// Query logging; every query is logged at debug level
+	SlowQueryThreshold time.Duration `env:"DATABASE_SLOW_QUERY_THRESHOLD" default:"200ms" validate:"gte=0"` // 0 disables slow query warnings
+	LogQueryParams     bool          `env:"DATABASE_LOG_QUERY_PARAMS" default:"false"`                      // bound values may hold personal data
+
+	// Startup retries; CONNECT_TIMEOUT bounds all attempts together
+	ConnectAttempts   int           `env:"DATABASE_CONNECT_ATTEMPTS" default:"5" validate:"gte=0"` // 0 retries until CONNECT_TIMEOUT
+	ConnectBackoff    time.Duration `env:"DATABASE_CONNECT_BACKOFF" default:"1s" validate:"gt=0"`
+	ConnectMaxBackoff time.Duration `env:"DATABASE_CONNECT_MAX_BACKOFF" default:"30s" validate:"gtefield=ConnectBackoff"`
+	ConnectTimeout    time.Duration `env:"DATABASE_CONNECT_TIMEOUT" default:"1m" validate:"gt=0"`
+	// DegradedStart serves HTTP while the database is down, reconnecting in the background
+	DegradedStart bool `env:"DATABASE_DEGRADED_START" default:"false"`
+
+	// Transactions spanning several datasources are retried from the start on
+	// serialization failures and deadlocks
+	TxMaxAttempts  int           `env:"DATABASE_TX_MAX_ATTEMPTS" default:"3" validate:"gte=1"`
+	TxRetryBackoff time.Duration `env:"DATABASE_TX_RETRY_BACKOFF" default:"50ms" validate:"gt=0"`
+
+	// Read replicas: comma-separated key=value or postgres:// connection strings.
+	// Reads outside transactions go to healthy replicas, everything else to the primary.
+	ReplicaDSNs           []string      `env:"DATABASE_REPLICA_DSNS" secret:"true"`
+	ReplicaHealthInterval time.Duration `env:"DATABASE_REPLICA_HEALTH_INTERVAL" default:"10s" validate:"gt=0"`
+
+	// Soft-deleted users and organizations are purged once deleted longer than
+	// the retention, checked every PURGE_INTERVAL
+	SoftDeleteRetention time.Duration `env:"DATABASE_SOFT_DELETE_RETENTION" default:"720h" validate:"gte=0"` // 0 keeps them until purged by hand
+	PurgeInterval       time.Duration `env:"DATABASE_PURGE_INTERVAL" default:"1h" validate:"gt=0"`
 }
 
 type CSRFVars struct {
-	AuthKey string `env:"CSRF_AUTH_KEY"`
+	AuthKey string `env:"CSRF_AUTH_KEY" secret:"true"`
 	Secure  bool   `env:"CSRF_SECURE" default:"false"`
 }
 
@@ -54,166 +116,162 @@ type RequestLimitsVars struct {
 	WriteTimeout      int   `env:"REQUEST_WRITE_TIMEOUT" default:"30"`               // 30 seconds
 }
 
+type CORSVars struct {
+	AllowedOrigins   []string `env:"CORS_ALLOWED_ORIGINS" default:"http://localhost:3000,http://localhost:5173,http://localhost:3001,http://localhost:8081,https://www.postman.com"`
+	AllowedMethods   []string `env:"CORS_ALLOWED_METHODS" default:"GET,POST,PUT,PATCH,DELETE"`
+	AllowedHeaders   []string `env:"CORS_ALLOWED_HEADERS" default:"Accept,Authorization,Content-Type,X-CSRF-Token,If-Match,If-None-Match"`
+	ExposedHeaders   []string `env:"CORS_EXPOSED_HEADERS" default:"ETag,X-Request-ID"`
+	MaxAge           int      `env:"CORS_MAX_AGE" default:"600" validate:"gte=0"` // seconds browsers may cache a preflight
+	AllowCredentials bool     `env:"CORS_ALLOW_CREDENTIALS" default:"true"`
+
+	// Webhook routes are called server to server, so browsers are refused unless origins are listed here
+	WebhookAllowedOrigins []string `env:"CORS_WEBHOOK_ALLOWED_ORIGINS"`
+}
+
+type LoggingVars struct {
+	Level string `env:"LOG_LEVEL" default:"debug" validate:"oneof=debug info warn error"`
+}
+
+type RateLimitVars struct {
+	RequestsPerSecond float64 `env:"RATE_LIMIT_RPS" default:"100" validate:"gt=0"`
+	Burst             int     `env:"RATE_LIMIT_BURST" default:"200" validate:"gt=0"`
+}
+
+type ReloadVars struct {
+	WatchFiles    bool          `env:"CONFIG_WATCH" default:"false"`
+	WatchInterval time.Duration `env:"CONFIG_WATCH_INTERVAL" default:"10s"`
+}
+
+type ShutdownVars struct {
+	DrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" default:"5s"` // readiness reports not-ready for this long before stopping
+	Timeout    time.Duration `env:"SHUTDOWN_TIMEOUT" default:"30s" validate:"gt=0"`
+}
+
+// AdminVars configures the optional admin listener serving pprof, expvar and runtime stats
+type AdminVars struct {
+	Enabled bool   `env:"ADMIN_ENABLED" default:"false"`
+	Addr    string `env:"ADMIN_ADDR" default:"127.0.0.1:6060"` // keep on localhost or a private interface
+	Token   string `env:"ADMIN_TOKEN" secret:"true"`
+}
+
+type AuditVars struct {
+	// AllowUnsafe starts production despite critical audit findings
+	AllowUnsafe bool `env:"AUDIT_ALLOW_UNSAFE" default:"false"`
+}
+
 type ConfigVars struct {
 	Clerk         ClerkVars
 	Server        ServerVars
+	TLS           TLSVars
 	Database      DatabaseVars
 	CSRF          CSRFVars
 	Security      SecurityVars
 	RequestLimits RequestLimitsVars
-}
+	CORS          CORSVars
+	Logging       LoggingVars
+	RateLimit     RateLimitVars
+	Reload        ReloadVars
+	Shutdown      ShutdownVars
+	Admin         AdminVars
+	Audit         AuditVars
 
-// getEnvVar gets an environment variable using Viper with fallback to os.Getenv
-func getEnvVar(key string) string {
-	// First try to get from Viper (which loads from .env.local)
-	if viper.IsSet(key) {
-		return viper.GetString(key)
-	}
-	// Fallback to os.Getenv
-	return os.Getenv(key)
+	// Origins records which source supplied each value, for debugging
+	Origins ValueOrigins `json:"-"`
 }
 
-// LoadConfigVarsFromEnv loads and validates all application configuration variables from environment variables.
+// LoadConfigVarsFromEnv loads and validates all application configuration variables from
+// the config files in the working directory and the process environment.
 func LoadConfigVarsFromEnv() (*ConfigVars, error) {
-	// Initialize Viper
-	viper.SetConfigName(".env.local")
-	viper.SetConfigType("env")
-	viper.AddConfigPath(".")
-	viper.AutomaticEnv()
-
-	// Try to read .env.local file, ignore error if file doesn't exist
-	if err := viper.ReadInConfig(); err != nil {
-		// File doesn't exist or other error, continue with os.Getenv fallback
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			// If it's not a "file not found" error, log it but continue
-			fmt.Printf("Warning: Error reading .env.local file: %v\n", err)
-		}
-	}
-	dbVars := DatabaseVars{
-		DatabaseHost:     getEnvVar("{{.Name | upper}}_DATABASE_HOST"),
-		DatabasePort:     getEnvVar("{{.Name | upper}}_DATABASE_PORT"),
-		DatabaseUser:     getEnvVar("{{.Name | upper}}_DATABASE_USER"),
-		DatabaseName:     getEnvVar("{{.Name | upper}}_DATABASE_NAME"),
-		DatabasePassword: getEnvVar("{{.Name | upper}}_DATABASE_PASSWORD"),
-		DatabaseSSLMode:  getEnvVar("{{.Name | upper}}_DATABASE_SSL_MODE"),
-	}
+	return LoadConfigVars(context.Background(), LoadOptions{})
+}
 
-	serverVars := ServerVars{
-		Environment: getEnvVar("{{.Name | upper}}_SERVER_ENV"),
-		Version:     getEnvVar("{{.Name | upper}}_SERVER_VERSION"),
-		Name:        getEnvVar("{{.Name | upper}}_SERVER_NAME"),
-		Host:        getEnvVar("{{.Name | upper}}_SERVER_HOST"),
-		Port:        getEnvVar("{{.Name | upper}}_SERVER_PORT"),
-		Protocol:    getEnvVar("{{.Name | upper}}_SERVER_PROTOCOL"),
+// LoadConfigVars loads every configuration layer described by opts, resolves
+// KEY_FILE paths and secret providers, then validates the result.
+func LoadConfigVars(ctx context.Context, opts LoadOptions) (*ConfigVars, error) {
+	layers, err := configLayers(opts)
+	if err != nil {
+		return nil, err
 	}
 
-	csrfVars := CSRFVars{
-		AuthKey: getEnvVar("{{.Name | upper}}_CSRF_AUTH_KEY"),
-		Secure:  getEnvVar("{{.Name | upper}}_CSRF_SECURE") == "false",
+	providers, err := secretProviders(ctx, opts, layers.Lookup)
+	if err != nil {
+		return nil, err
 	}
 
-	clerkVars := ClerkVars{
-		Key:    getEnvVar("{{.Name | upper}}_CLERK_KEY"),
-		Secret: getEnvVar("{{.Name | upper}}_CLERK_SECRET"),
-	}
+	configType := reflect.TypeOf(ConfigVars{})
+	resolver := newSecretResolver(ctx, layers.Lookup, secretKeys(EnvPrefix, configType), providers)
 
-	securityVars := SecurityVars{
-		CSPPolicy: getEnvVar("{{.Name | upper}}_SECURITY_CSP_POLICY"),
-		HSTSMaxAge: func() int {
-			if val := getEnvVar("{{.Name | upper}}_SECURITY_HSTS_MAX_AGE"); val != "" {
-				if parsed, err := strconv.Atoi(val); err == nil {
-					return parsed
-				}
-			}
-			return 31536000 // Default 1 year
-		}(),
-		FrameOptions:       getEnvVar("{{.Name | upper}}_SECURITY_FRAME_OPTIONS"),
-		ContentTypeOptions: getEnvVar("{{.Name | upper}}_SECURITY_CONTENT_TYPE_OPTIONS") != "false",
-		ReferrerPolicy:     getEnvVar("{{.Name | upper}}_SECURITY_REFERRER_POLICY"),
-		PermissionsPolicy:  getEnvVar("{{.Name | upper}}_SECURITY_PERMISSIONS_POLICY"),
+	vars := &ConfigVars{}
+	if err := joinLoadErrors(LoadStruct(EnvPrefix, vars, resolver.Lookup), resolver.errors); err != nil {
+		return nil, err
 	}
 
-	requestLimitsVars := RequestLimitsVars{
-		MaxRequestSize: func() int64 {
-			if val := getEnvVar("{{.Name | upper}}_REQUEST_MAX_SIZE"); val != "" {
-				if parsed, err := strconv.ParseInt(val, 10, 64); err == nil {
-					return parsed
-				}
-			}
-			return 10485760 // 10MB default
-		}(),
-		MaxHeaderSize: func() int64 {
-			if val := getEnvVar("{{.Name | upper}}_REQUEST_MAX_HEADER_SIZE"); val != "" {
-				if parsed, err := strconv.ParseInt(val, 10, 64); err == nil {
-					return parsed
-				}
-			}
-			return 1048576 // 1MB default
-		}(),
-		MaxFileUploadSize: func() int64 {
-			if val := getEnvVar("{{.Name | upper}}_REQUEST_MAX_FILE_UPLOAD_SIZE"); val != "" {
-				if parsed, err := strconv.ParseInt(val, 10, 64); err == nil {
-					return parsed
-				}
-			}
-			return 104857600 // 100MB default
-		}(),
-		ReadTimeout: func() int {
-			if val := getEnvVar("{{.Name | upper}}_REQUEST_READ_TIMEOUT"); val != "" {
-				if parsed, err := strconv.Atoi(val); err == nil {
-					return parsed
-				}
-			}
-			return 30 // 30 seconds default
-		}(),
-		WriteTimeout: func() int {
-			if val := getEnvVar("{{.Name | upper}}_REQUEST_WRITE_TIMEOUT"); val != "" {
-				if parsed, err := strconv.Atoi(val); err == nil {
-					return parsed
-				}
-			}
-			return 30 // 30 seconds default
-		}(),
+	vars.Origins = layers.Origins()
+	for key, origin := range resolver.origins {
+		vars.Origins[key] = origin
 	}
-
-	if err := validation.ValidateStruct(clerkVars); err != nil {
-		return nil, fmt.Errorf("invalid clerk vars: %w", err)
+	for _, key := range envKeys(configType) {
+		if _, ok := vars.Origins[EnvPrefix+key]; !ok {
+			vars.Origins[EnvPrefix+key] = OriginDefault
+		}
 	}
 
-	if err := validation.ValidateStruct(serverVars); err != nil {
-		return nil, fmt.Errorf("invalid server vars: %w", err)
+	if err := validation.ValidateStruct(vars); err != nil {
+		return nil, fmt.Errorf("invalid config vars: %w", err)
 	}
-
-	if err := validation.ValidateStruct(dbVars); err != nil {
-		return nil, fmt.Errorf("invalid db vars: %w", err)
+	if err := vars.CORS.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config vars: %w", err)
 	}
-
-	if err := validation.ValidateStruct(csrfVars); err != nil {
-		return nil, fmt.Errorf("invalid csrf vars: %w", err)
+	if err := vars.TLS.Validate(vars.Server.Protocol); err != nil {
+		return nil, fmt.Errorf("invalid config vars: %w", err)
 	}
+	if err := vars.Admin.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config vars: %w", err)
+	}
+
+	return vars, nil
+}
+
+// secretProviders opens the encrypted secrets file when present, ahead of any custom providers.
+func secretProviders(ctx context.Context, opts LoadOptions, lookup LookupFunc) ([]SecretProvider, error) {
+	providers := make([]SecretProvider, 0, len(opts.SecretProviders)+1)
 
-	if err := validation.ValidateStruct(securityVars); err != nil {
-		return nil, fmt.Errorf("invalid security vars: %w", err)
+	path := filepath.Join(opts.dir(), SecretsFileName)
+	if _, err := os.Stat(path); err == nil {
+		masterKey, ok := newSecretResolver(ctx, lookup, nil, nil).Lookup(EnvPrefix + "MASTER_KEY")
+		if !ok {
+			return nil, fmt.Errorf("%s exists but %sMASTER_KEY is not set", path, EnvPrefix)
+		}
+
+		provider, err := NewEncryptedFileProvider(path, masterKey)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
 	}
 
-	if err := validation.ValidateStruct(requestLimitsVars); err != nil {
-		return nil, fmt.Errorf("invalid request limits vars: %w", err)
+	return append(providers, opts.SecretProviders...), nil
+}
+
+// joinLoadErrors merges the loader result with errors raised while resolving secrets.
+func joinLoadErrors(err error, resolveErrors []FieldError) error {
+	if len(resolveErrors) == 0 {
+		return err
 	}
 
-	return &ConfigVars{
-		Clerk:         clerkVars,
-		Server:        serverVars,
-		Database:      dbVars,
-		CSRF:          csrfVars,
-		Security:      securityVars,
-		RequestLimits: requestLimitsVars,
-	}, nil
+	loadErr := &LoadError{}
+	if !errors.As(err, &loadErr) && err != nil {
+		return err
+	}
+	loadErr.Errors = append(loadErr.Errors, resolveErrors...)
+	return loadErr
 }
 
 // Sanitize methods for all structs
 func (cv *ClerkVars) Sanitize() {
 	cv.Key = validation.SanitizeString(cv.Key)
 	cv.Secret = validation.SanitizeString(cv.Secret)
+	cv.WebhookSecret = validation.SanitizeString(cv.WebhookSecret)
 }
 
 func (sv *ServerVars) Sanitize() {
@@ -225,6 +283,35 @@ func (sv *ServerVars) Sanitize() {
 	sv.Protocol = validation.SanitizeString(sv.Protocol)
 }
 
+// Validate requires a certificate and key when serving HTTPS.
+func (tv TLSVars) Validate(protocol string) error {
+	if protocol != "https" {
+		return nil
+	}
+
+	loadErr := &LoadError{}
+	if tv.CertFile == "" {
+		loadErr.Errors = append(loadErr.Errors, FieldError{Key: EnvPrefix + "TLS_CERT_FILE", Field: "ConfigVars.TLS.CertFile", Err: ErrMissingValue})
+	}
+	if tv.KeyFile == "" {
+		loadErr.Errors = append(loadErr.Errors, FieldError{Key: EnvPrefix + "TLS_KEY_FILE", Field: "ConfigVars.TLS.KeyFile", Err: ErrMissingValue})
+	}
+
+	if len(loadErr.Errors) > 0 {
+		return loadErr
+	}
+	return nil
+}
+
+// Validate requires ADMIN_TOKEN when the admin listener is enabled.
+func (av AdminVars) Validate() error {
+	if !av.Enabled || av.Token != "" {
+		return nil
+	}
+	missing := FieldError{Key: EnvPrefix + "ADMIN_TOKEN", Field: "ConfigVars.Admin.Token", Err: ErrMissingValue}
+	return &LoadError{Errors: []FieldError{missing}}
+}
+
 func (dv *DatabaseVars) Sanitize() {
 	dv.DatabaseHost = validation.SanitizeString(dv.DatabaseHost)
 	dv.DatabasePort = validation.SanitizeString(dv.DatabasePort)
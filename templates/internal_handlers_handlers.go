@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 
+	auditModels "{{.Module}}/internal/audit/models"
 	"{{.Module}}/internal/conf"
 	"{{.Module}}/internal/shared/assertions"
 	"{{.Module}}/internal/shared/constants"
@@ -20,6 +21,9 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// webhookRoutePrefix groups the identity provider webhooks, which get their own CORS policy
+const webhookRoutePrefix = "/identity"
+
 type Handler struct {
 	Logger       *logger.Logger
 	Dependencies *conf.Dependencies
@@ -77,6 +81,10 @@ func (h *Handler) HandleClerkWebhook(w http.ResponseWriter, r *http.Request) err
 		return h.Dependencies.Controllers.Organizations.UpdateOrganizationFromClerk(w, r)
 	case string(constants.WebhookEventOrganizationDeleted):
 		return h.Dependencies.Controllers.Organizations.DeleteOrganization(w, r)
+	case string(constants.WebhookEventMembershipCreated), string(constants.WebhookEventMembershipUpdated):
+		return h.Dependencies.Controllers.Memberships.SaveMembershipFromClerk(w, r)
+	case string(constants.WebhookEventMembershipDeleted):
+		return h.Dependencies.Controllers.Memberships.DeleteMembershipFromClerk(w, r)
 	default:
 		l.Error("unsupported webhook event", "eventType", eventType)
 		return errors.New("unsupported webhook event")
@@ -86,7 +94,7 @@ func (h *Handler) HandleClerkWebhook(w http.ResponseWriter, r *http.Request) err
 func (handler *Handler) Register() *mux.Router {
 	router := mux.NewRouter()
 	prefix := fmt.Sprintf("/%s", constants.SERVICE_API_PREFIX)
-	mw := middleware.NewMiddleware(handler.Dependencies.ExternalDependencies.Clerk, handler.Dependencies.Config.Clerk.Secret)
+	mw := handler.Dependencies.Middleware
 
 	// Generate CSRF auth key if not provided
 	csrfAuthKey := []byte(handler.Dependencies.Config.CSRF.AuthKey)
@@ -99,15 +107,9 @@ func (handler *Handler) Register() *mux.Router {
 	}
 
 	csrfMiddleware := mw.CSRFMiddleware(csrfAuthKey, handler.Dependencies.Config.CSRF.Secure)
-	securityConfig := middleware.SecurityConfig{
-		CSPPolicy:          handler.Dependencies.Config.Security.CSPPolicy,
-		HSTSMaxAge:         handler.Dependencies.Config.Security.HSTSMaxAge,
-		FrameOptions:       handler.Dependencies.Config.Security.FrameOptions,
-		ContentTypeOptions: handler.Dependencies.Config.Security.ContentTypeOptions,
-		ReferrerPolicy:     handler.Dependencies.Config.Security.ReferrerPolicy,
-		PermissionsPolicy:  handler.Dependencies.Config.Security.PermissionsPolicy,
-	}
-	securityMiddleware := mw.SecurityHeadersMiddleware(securityConfig)
+	securityMiddleware := mw.SecurityHeadersMiddleware(securityConfig(handler.Dependencies.Config.Security))
+	mw.SetRateLimit(handler.Dependencies.Config.RateLimit.RequestsPerSecond, handler.Dependencies.Config.RateLimit.Burst)
+	mw.SetCORSConfig(corsConfig(handler.Dependencies.Config))
 
 	requestLimitsConfig := middleware.RequestLimitsConfig{
 		MaxRequestSize:    handler.Dependencies.Config.RequestLimits.MaxRequestSize,
@@ -120,7 +122,8 @@ func (handler *Handler) Register() *mux.Router {
 	requestSizeLimitMiddleware := mw.RequestSizeLimitMiddleware(requestLimitsConfig)
 	requestTimeoutMiddleware := mw.RequestTimeoutMiddleware(requestLimitsConfig)
 
-	// Apply security headers and request limits to all routes
+	// Apply the request ID, security headers and request limits to all routes
+	router.Use(mw.RequestContextMiddleware)
 	router.Use(securityMiddleware)
 	router.Use(requestSizeLimitMiddleware)
 	router.Use(requestTimeoutMiddleware)
@@ -134,24 +137,145 @@ func (handler *Handler) Register() *mux.Router {
 	private.Use(mw.RateLimiterMiddleware)
 	private.Use(mw.ClerkAuthMiddleware)
 	private.Use(csrfMiddleware)
+	private.Use(mw.DatabaseMiddleware(handler.Dependencies.DatabaseAvailable))
+	private.Use(mw.PrimaryReadsMiddleware)
 
 	webhook := router.PathPrefix(prefix).Subrouter()
 	webhook.Use(mw.LoggerMiddleware)
 	webhook.Use(mw.RateLimiterMiddleware)
 	webhook.Use(mw.ClerkWebhookMiddleware)
+	webhook.Use(actorMiddleware(func(r *http.Request) auditModels.Actor {
+		return auditModels.Actor{Type: auditModels.ActorClerk, ID: r.Header.Get("svix-id")}
+	}))
+	webhook.Use(mw.DatabaseMiddleware(handler.Dependencies.DatabaseAvailable))
+	webhook.Use(mw.PrimaryReadsMiddleware)
 
 	// Health
 	api.Handle("/health", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Health.GetHealth)).Methods(http.MethodGet)
+	api.Handle("/health/ready", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Health.GetReadiness)).Methods(http.MethodGet)
+	api.Handle("/version", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Health.GetVersion)).Methods(http.MethodGet)
 
 	// CSRF Token
 	api.Handle("/csrf-token", httpHelpers.HandlerFunc(handler.GetCSRFToken)).Methods(http.MethodGet)
 
 	// Identity Webhook
-	webhook.Handle("/identity/clerk", httpHelpers.HandlerFunc(handler.HandleClerkWebhook)).Methods(http.MethodPost)
+	webhook.Handle(webhookRoutePrefix+"/clerk", httpHelpers.HandlerFunc(handler.HandleClerkWebhook)).Methods(http.MethodPost)
 
 	// Organizations
+	private.Handle("/organizations", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Organizations.CreateOrganization)).Methods(http.MethodPost)
+	// /organizations/slug-availability must be registered before /organizations/{id}
+	private.Handle("/organizations/slug-availability", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Organizations.CheckSlug)).Methods(http.MethodGet)
 	private.Handle("/organizations/{id}", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Organizations.GetOrganizationByID)).Methods(http.MethodGet)
+	private.Handle("/organizations/{id}", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Organizations.UpdateOrganization)).Methods(http.MethodPatch)
+	private.Handle("/organizations/{id}", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Organizations.DeleteOrganizationByID)).Methods(http.MethodDelete)
 	private.Handle("/organizations/clerk/{clerk_id}", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Organizations.GetOrganizationByClerkID)).Methods(http.MethodGet)
+	private.Handle("/organizations/slug/{slug}", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Organizations.GetOrganizationBySlug)).Methods(http.MethodGet)
+
+	// Users
+	// /users/me must be registered before /users/{id}
+	private.Handle("/users/me", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Users.GetCurrentUser)).Methods(http.MethodGet)
+	private.Handle("/users/me", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Users.UpdateCurrentUser)).Methods(http.MethodPatch)
+	private.Handle("/users/{id}", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Users.GetUserByID)).Methods(http.MethodGet)
+
+	// Memberships
+	private.Handle("/organizations/{id}/members", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Memberships.ListOrganizationMembers)).Methods(http.MethodGet)
+	private.Handle("/users/{id}/organizations", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Memberships.ListUserOrganizations)).Methods(http.MethodGet)
+
+	return router
+}
+
+// RegisterAdmin builds the routes served under /admin on the admin listener,
+// which authenticates them with the admin token.
+func (handler *Handler) RegisterAdmin() *mux.Router {
+	router := mux.NewRouter()
+	mw := handler.Dependencies.Middleware
+	users := handler.Dependencies.Controllers.Users
+	organizations := handler.Dependencies.Controllers.Organizations
+
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(mw.RequestContextMiddleware)
+	admin.Use(actorMiddleware(func(r *http.Request) auditModels.Actor {
+		return auditModels.Actor{Type: auditModels.ActorAdmin}
+	}))
+	admin.Use(mw.LoggerMiddleware)
+	admin.Use(mw.DatabaseMiddleware(handler.Dependencies.DatabaseAvailable))
+	admin.Use(mw.PrimaryReadsMiddleware)
+
+	// User and organization directories
+	admin.Handle("/users", httpHelpers.HandlerFunc(users.ListUsers)).Methods(http.MethodGet)
+	admin.Handle("/organizations", httpHelpers.HandlerFunc(organizations.ListOrganizations)).Methods(http.MethodGet)
+
+	// Soft-deleted records
+	admin.Handle("/users/deleted", httpHelpers.HandlerFunc(users.ListDeletedUsers)).Methods(http.MethodGet)
+	admin.Handle("/users/{clerk_id}/restore", httpHelpers.HandlerFunc(users.RestoreUser)).Methods(http.MethodPost)
+	admin.Handle("/users/{clerk_id}", httpHelpers.HandlerFunc(users.PurgeUser)).Methods(http.MethodDelete)
+	admin.Handle("/organizations/deleted", httpHelpers.HandlerFunc(organizations.ListDeletedOrganizations)).Methods(http.MethodGet)
+	admin.Handle("/organizations/{clerk_id}/restore", httpHelpers.HandlerFunc(organizations.RestoreOrganization)).Methods(http.MethodPost)
+	admin.Handle("/organizations/{clerk_id}", httpHelpers.HandlerFunc(organizations.PurgeOrganization)).Methods(http.MethodDelete)
+
+	// Audit trail
+	admin.Handle("/audit-events", httpHelpers.HandlerFunc(handler.Dependencies.Controllers.Audit.ListEvents)).Methods(http.MethodGet)
 
 	return router
 }
+
+// actorMiddleware attributes the changes made by a request to the actor returned for it
+func actorMiddleware(actor func(r *http.Request) auditModels.Actor) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(auditModels.ContextWithActor(r.Context(), actor(r))))
+		})
+	}
+}
+
+// Reload prepares the reloadable middleware settings from cfg and returns a
+// function that swaps them in. It satisfies conf.ReloadHook.
+func (handler *Handler) Reload(cfg *conf.ConfigVars) (func(), error) {
+	if cfg.RateLimit.RequestsPerSecond <= 0 || cfg.RateLimit.Burst <= 0 {
+		return nil, fmt.Errorf("rate limit must be positive, got %v/s burst %d", cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+	}
+
+	security := securityConfig(cfg.Security)
+	cors := corsConfig(cfg)
+	mw := handler.Dependencies.Middleware
+
+	return func() {
+		mw.SetSecurityConfig(security)
+		mw.SetRateLimit(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+		mw.SetCORSConfig(cors)
+	}, nil
+}
+
+func securityConfig(vars conf.SecurityVars) middleware.SecurityConfig {
+	return middleware.SecurityConfig{
+		CSPPolicy:          vars.CSPPolicy,
+		HSTSMaxAge:         vars.HSTSMaxAge,
+		FrameOptions:       vars.FrameOptions,
+		ContentTypeOptions: vars.ContentTypeOptions,
+		ReferrerPolicy:     vars.ReferrerPolicy,
+		PermissionsPolicy:  vars.PermissionsPolicy,
+	}
+}
+
+// corsConfig builds the API CORS policy from configuration, with webhook routes
+// overridden to only the origins listed for them
+func corsConfig(vars *conf.ConfigVars) middleware.CORSConfig {
+	webhookPrefix := fmt.Sprintf("/%s%s", constants.SERVICE_API_PREFIX, webhookRoutePrefix)
+
+	return middleware.CORSConfig{
+		AllowedOrigins:   vars.CORS.AllowedOrigins,
+		AllowedMethods:   vars.CORS.AllowedMethods,
+		AllowedHeaders:   vars.CORS.AllowedHeaders,
+		ExposedHeaders:   vars.CORS.ExposedHeaders,
+		MaxAge:           vars.CORS.MaxAge,
+		AllowCredentials: vars.CORS.AllowCredentials,
+		Routes: map[string]middleware.CORSConfig{
+			webhookPrefix: {
+				AllowedOrigins: vars.CORS.WebhookAllowedOrigins,
+				AllowedMethods: []string{string(constants.AllowedMethodPOST)},
+				AllowedHeaders: []string{"Content-Type", "Svix-Id", "Svix-Timestamp", "Svix-Signature"},
+				MaxAge:         vars.CORS.MaxAge,
+			},
+		},
+	}
+}
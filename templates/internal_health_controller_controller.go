@@ -15,6 +15,8 @@ const (
 
 type HealthController interface {
 	GetHealth(w http.ResponseWriter, r *http.Request) error
+	GetReadiness(w http.ResponseWriter, r *http.Request) error
+	GetVersion(w http.ResponseWriter, r *http.Request) error
 }
 
 type ControllerImpl struct {
@@ -34,8 +36,24 @@ func (c *ControllerImpl) GetHealth(w http.ResponseWriter, r *http.Request) error
 	health, err := c.service.GetHealth(ctx)
 	if err != nil {
 		l.Error("failed to get health status", "error", err)
+		if health != nil {
+			// Unhealthy dependencies, e.g. the database while running degraded
+			return httpHelpers.RespondWithJSON(w, http.StatusServiceUnavailable, health)
+		}
 		return httpHelpers.RespondWithError(w, err)
 	}
 
 	return httpHelpers.RespondWithJSON(w, http.StatusOK, health)
 }
+
+func (c *ControllerImpl) GetReadiness(w http.ResponseWriter, r *http.Request) error {
+	readiness := c.service.GetReadiness(r.Context())
+	if readiness.Status != "ready" {
+		return httpHelpers.RespondWithJSON(w, http.StatusServiceUnavailable, readiness)
+	}
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, readiness)
+}
+
+func (c *ControllerImpl) GetVersion(w http.ResponseWriter, r *http.Request) error {
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, c.service.GetVersion(r.Context()))
+}
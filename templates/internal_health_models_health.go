@@ -2,12 +2,21 @@ package models
 
 import (
 	"time"
+
+	"{{.Module}}/internal/shared/buildinfo"
 )
 
 type HealthStatus struct {
-	Status    string            `json:"status"`
-	Timestamp time.Time         `json:"timestamp"`
-	Services  map[string]string `json:"services"`
-	Version   string            `json:"version"`
-	Uptime    string            `json:"uptime"`
+	Status        string            `json:"status"`
+	Timestamp     time.Time         `json:"timestamp"`
+	Services      map[string]string `json:"services"`
+	Version       string            `json:"version"`
+	Uptime        string            `json:"uptime"`
+	ConfigVersion string            `json:"config_version,omitempty"`
+}
+
+// VersionInfo is the build metadata of the running binary
+type VersionInfo struct {
+	buildinfo.Info
+	Uptime string `json:"uptime"`
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"{{.Module}}/internal/health/models"
+	"{{.Module}}/internal/shared/buildinfo"
 	"{{.Module}}/internal/shared/logger"
 
 	"gorm.io/gorm"
@@ -19,52 +20,77 @@ const (
 
 type HealthService interface {
 	GetHealth(ctx context.Context) (*models.HealthStatus, error)
+	GetReadiness(ctx context.Context) *models.HealthStatus
+	GetVersion(ctx context.Context) *models.VersionInfo
 }
 
 type ServiceImpl struct {
-	log *logger.Logger
-	db  *gorm.DB
+	log           *logger.Logger
+	db            *gorm.DB
+	configVersion func() string
+	ready         func() bool
 }
 
-func NewService(logger *logger.Logger, db *gorm.DB) HealthService {
+func NewService(logger *logger.Logger, db *gorm.DB, configVersion func() string, ready func() bool) HealthService {
 	serviceLogger := logger.With("package", pkgName, "layer", layer)
-	return &ServiceImpl{log: serviceLogger, db: db}
+	return &ServiceImpl{log: serviceLogger, db: db, configVersion: configVersion, ready: ready}
+}
+
+// GetReadiness reports whether the service should receive traffic. It turns
+// not ready as soon as shutdown begins so load balancers stop routing to it.
+func (s *ServiceImpl) GetReadiness(ctx context.Context) *models.HealthStatus {
+	status := "ready"
+	if s.ready != nil && !s.ready() {
+		status = "not_ready"
+	}
+
+	return &models.HealthStatus{
+		Status:        status,
+		Timestamp:     time.Now(),
+		ConfigVersion: s.configVersion(),
+	}
 }
 
 func (s *ServiceImpl) GetHealth(ctx context.Context) (*models.HealthStatus, error) {
 	l := s.log.WithContext(ctx).With("operation", "GetHealth")
 
+	status := &models.HealthStatus{
+		Status:    "healthy",
+		Timestamp: time.Now(),
+		Services: map[string]string{
+			"database": "healthy",
+		},
+		Version:       buildinfo.Get().Version,
+		Uptime:        uptime(),
+		ConfigVersion: s.configVersion(),
+	}
+
 	// Check database connectivity
 	sqlDB, err := s.db.DB()
 	if err != nil {
 		l.Error("failed to get sql db", "error", err)
-		return &models.HealthStatus{
-			Status:    "unhealthy",
-			Timestamp: time.Now(),
-			Services: map[string]string{
-				"database": "unhealthy",
-			},
-		}, err
+		status.Status = "unhealthy"
+		status.Services["database"] = "unhealthy"
+		return status, err
 	}
 
-	if err := sqlDB.Ping(); err != nil {
+	if err := sqlDB.PingContext(ctx); err != nil {
 		l.Error("database ping failed", "error", err)
-		return &models.HealthStatus{
-			Status:    "unhealthy",
-			Timestamp: time.Now(),
-			Services: map[string]string{
-				"database": "unhealthy",
-			},
-		}, err
+		status.Status = "unhealthy"
+		status.Services["database"] = "unhealthy"
+		return status, err
 	}
 
-	return &models.HealthStatus{
-		Status:    "healthy",
-		Timestamp: time.Now(),
-		Services: map[string]string{
-			"database": "healthy",
-		},
-		Version: "1.0.0",
-		Uptime:  "unknown", // You can implement uptime tracking
-	}, nil
+	return status, nil
+}
+
+func (s *ServiceImpl) GetVersion(ctx context.Context) *models.VersionInfo {
+	return &models.VersionInfo{
+		Info:   buildinfo.Get(),
+		Uptime: uptime(),
+	}
+}
+
+func uptime() string {
+	return buildinfo.Uptime().Round(time.Second).String()
 }
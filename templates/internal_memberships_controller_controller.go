@@ -0,0 +1,136 @@
+package memberships
+
+import (
+	"net/http"
+
+	"{{.Module}}/internal/memberships/models"
+	membershipsService "{{.Module}}/internal/memberships/service"
+	"{{.Module}}/internal/shared/constants"
+	httpHelpers "{{.Module}}/internal/shared/http"
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/middleware"
+	"{{.Module}}/internal/shared/validation"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	pkgName = "memberships"
+	layer   = "controller"
+)
+
+type MembershipsController interface {
+	SaveMembershipFromClerk(w http.ResponseWriter, r *http.Request) error
+	DeleteMembershipFromClerk(w http.ResponseWriter, r *http.Request) error
+	ListOrganizationMembers(w http.ResponseWriter, r *http.Request) error
+	ListUserOrganizations(w http.ResponseWriter, r *http.Request) error
+}
+
+type ControllerImpl struct {
+	log     *logger.Logger
+	service membershipsService.MembershipsService
+}
+
+func NewController(logger *logger.Logger, service membershipsService.MembershipsService) MembershipsController {
+	ctrlLogger := logger.With("package", pkgName, "layer", layer)
+	return &ControllerImpl{log: ctrlLogger, service: service}
+}
+
+// SaveMembershipFromClerk handles organizationMembership.created and .updated
+func (c *ControllerImpl) SaveMembershipFromClerk(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "SaveMembershipFromClerk")
+
+	membershipRequest := &models.ClerkMembershipRequest{}
+
+	if err := middleware.SafeJSONDecoder(r, membershipRequest, constants.JSONMaxSize); err != nil {
+		l.Debug("failed to decode membership request", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	if err := validation.ValidateStruct(membershipRequest); err != nil {
+		l.Debug("failed to validate membership from clerk request", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	membership, err := c.service.SaveMembership(ctx, membershipRequest)
+	if err != nil {
+		l.Error("failed to save membership from clerk request", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, membership)
+}
+
+// DeleteMembershipFromClerk handles organizationMembership.deleted
+func (c *ControllerImpl) DeleteMembershipFromClerk(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "DeleteMembershipFromClerk")
+
+	membershipRequest := &models.ClerkMembershipRequest{}
+
+	if err := middleware.SafeJSONDecoder(r, membershipRequest, constants.JSONMaxSize); err != nil {
+		l.Debug("failed to decode delete membership request", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	deleted, err := c.service.DeleteMembership(ctx, membershipRequest.Data.ID)
+	if err != nil {
+		l.Error("failed to delete membership", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, deleted)
+}
+
+// ListOrganizationMembers lists the members of an organization the caller belongs to
+func (c *ControllerImpl) ListOrganizationMembers(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "ListOrganizationMembers")
+
+	clerkUserID, ok := middleware.ClerkUserID(ctx)
+	if !ok {
+		l.Debug("no clerk user id in request context")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusUnauthorized, "Unauthorized"))
+	}
+
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		l.Debug("missing organization id in path")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusBadRequest, "organization id is required"))
+	}
+
+	members, err := c.service.ListOrganizationMembers(ctx, clerkUserID, id)
+	if err != nil {
+		l.Error("failed to list organization members", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, members)
+}
+
+// ListUserOrganizations lists the organizations of the caller
+func (c *ControllerImpl) ListUserOrganizations(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "ListUserOrganizations")
+
+	clerkUserID, ok := middleware.ClerkUserID(ctx)
+	if !ok {
+		l.Debug("no clerk user id in request context")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusUnauthorized, "Unauthorized"))
+	}
+
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		l.Debug("missing user id in path")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusBadRequest, "user id is required"))
+	}
+
+	memberships, err := c.service.ListUserOrganizations(ctx, clerkUserID, id)
+	if err != nil {
+		l.Error("failed to list user organizations", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, memberships)
+}
@@ -0,0 +1,200 @@
+//go:generate mockgen -destination=../../mocks/mock_memberships_datasource.go -package=mocks {{.Module}}/internal/memberships/datasource MembershipsDatasource
+
+package datasource
+
+import (
+	"context"
+
+	"{{.Module}}/internal/memberships/models"
+	"{{.Module}}/internal/shared/assertions"
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/transaction"
+	"{{.Module}}/internal/shared/uuid"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	pkgName = "memberships"
+	layer   = "datasource"
+)
+
+type MembershipsDatasource interface {
+	UpsertMembership(ctx context.Context, membership *models.Membership) (*models.Membership, error)
+	DeleteMembershipByClerkID(ctx context.Context, clerkMembershipID string) (bool, error)
+	GetMembership(ctx context.Context, userID string, orgID string) (*models.Membership, error)
+	ListMembershipsByOrganizationID(ctx context.Context, orgID string) ([]models.Membership, error)
+	ListMembershipsByUserID(ctx context.Context, userID string) ([]models.Membership, error)
+	SharesOrganization(ctx context.Context, userID string, otherUserID string) (bool, error)
+}
+
+type DatasourceImpl struct {
+	log *logger.Logger
+	db  *gorm.DB
+}
+
+func NewDatasource(logger *logger.Logger, db *gorm.DB) MembershipsDatasource {
+	dsLogger := logger.With("package", pkgName, "layer", layer)
+	return &DatasourceImpl{log: dsLogger, db: db}
+}
+
+// UpsertMembership creates the membership of a user in an organization, or
+// updates its role and Clerk ID when the user already belongs to it
+func (d *DatasourceImpl) UpsertMembership(ctx context.Context, membership *models.Membership) (*models.Membership, error) {
+	l := d.log.WithContext(ctx).With("operation", "UpsertMembership")
+
+	// Generate UUID if not provided
+	if membership.ID == "" {
+		membership.ID = uuid.GenerateNamespaceUUID("mem")
+	}
+
+	err := transaction.DB(ctx, d.db).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{
+				{Name: "user_id"},
+				{Name: "organization_id"},
+			},
+			DoUpdates: clause.AssignmentColumns([]string{"clerk_membership_id", "role", "updated_at"}),
+		}).
+		// Reads back the existing row's ID after an update
+		Clauses(clause.Returning{}).
+		Create(membership).Error
+	if err != nil {
+		l.Error("failed to upsert membership", "error", err)
+		return nil, err
+	}
+
+	l.Debug("membership upserted successfully", "membership_id", membership.ID)
+	return membership, nil
+}
+
+func (d *DatasourceImpl) DeleteMembershipByClerkID(ctx context.Context, clerkMembershipID string) (bool, error) {
+	l := d.log.WithContext(ctx).With("operation", "DeleteMembershipByClerkID")
+
+	if err := assertions.AssertNonEmptyString(clerkMembershipID); err != nil {
+		l.Debug("invalid clerk membership id", "error", err)
+		return false, err
+	}
+
+	result := transaction.DB(ctx, d.db).Where("clerk_membership_id = ?", clerkMembershipID).Delete(&models.Membership{})
+	if result.Error != nil {
+		l.Error("failed to delete membership", "error", result.Error)
+		return false, result.Error
+	}
+
+	l.Debug("membership deleted successfully", "clerk_membership_id", clerkMembershipID, "rows_affected", result.RowsAffected)
+	return result.RowsAffected > 0, nil
+}
+
+// GetMembership returns the membership of a user in an organization, or
+// gorm.ErrRecordNotFound when the user doesn't belong to it
+func (d *DatasourceImpl) GetMembership(ctx context.Context, userID string, orgID string) (*models.Membership, error) {
+	l := d.log.WithContext(ctx).With("operation", "GetMembership")
+
+	if err := assertions.AssertNonEmptyString(userID); err != nil {
+		l.Debug("invalid user id", "error", err)
+		return nil, err
+	}
+	if err := assertions.AssertNonEmptyString(orgID); err != nil {
+		l.Debug("invalid organization id", "error", err)
+		return nil, err
+	}
+
+	var membership models.Membership
+	err := transaction.DB(ctx, d.db).
+		Where("user_id = ? AND organization_id = ?", userID, orgID).
+		First(&membership).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			l.Debug("membership not found", "user_id", userID, "org_id", orgID)
+			return nil, err
+		}
+		l.Error("failed to get membership", "error", err)
+		return nil, err
+	}
+
+	l.Debug("membership retrieved successfully", "membership_id", membership.ID)
+	return &membership, nil
+}
+
+// ListMembershipsByOrganizationID returns the members of an organization with
+// their user, leaving out soft-deleted users
+func (d *DatasourceImpl) ListMembershipsByOrganizationID(ctx context.Context, orgID string) ([]models.Membership, error) {
+	l := d.log.WithContext(ctx).With("operation", "ListMembershipsByOrganizationID")
+
+	if err := assertions.AssertNonEmptyString(orgID); err != nil {
+		l.Debug("invalid organization id", "error", err)
+		return nil, err
+	}
+
+	var memberships []models.Membership
+	err := transaction.DB(ctx, d.db).
+		InnerJoins("User").
+		Where("organization_memberships.organization_id = ?", orgID).
+		Order("organization_memberships.joined_at").
+		Find(&memberships).Error
+	if err != nil {
+		l.Error("failed to list organization memberships", "error", err)
+		return nil, err
+	}
+
+	l.Debug("organization memberships retrieved successfully", "org_id", orgID, "count", len(memberships))
+	return memberships, nil
+}
+
+// ListMembershipsByUserID returns the organizations a user belongs to, leaving
+// out soft-deleted organizations
+func (d *DatasourceImpl) ListMembershipsByUserID(ctx context.Context, userID string) ([]models.Membership, error) {
+	l := d.log.WithContext(ctx).With("operation", "ListMembershipsByUserID")
+
+	if err := assertions.AssertNonEmptyString(userID); err != nil {
+		l.Debug("invalid user id", "error", err)
+		return nil, err
+	}
+
+	var memberships []models.Membership
+	err := transaction.DB(ctx, d.db).
+		InnerJoins("Organization").
+		Where("organization_memberships.user_id = ?", userID).
+		Order("organization_memberships.joined_at").
+		Find(&memberships).Error
+	if err != nil {
+		l.Error("failed to list user memberships", "error", err)
+		return nil, err
+	}
+
+	l.Debug("user memberships retrieved successfully", "user_id", userID, "count", len(memberships))
+	return memberships, nil
+}
+
+// SharesOrganization reports whether two users belong to a common organization
+// that hasn't been soft-deleted
+func (d *DatasourceImpl) SharesOrganization(ctx context.Context, userID string, otherUserID string) (bool, error) {
+	l := d.log.WithContext(ctx).With("operation", "SharesOrganization")
+
+	if err := assertions.AssertNonEmptyString(userID); err != nil {
+		l.Debug("invalid user id", "error", err)
+		return false, err
+	}
+	if err := assertions.AssertNonEmptyString(otherUserID); err != nil {
+		l.Debug("invalid other user id", "error", err)
+		return false, err
+	}
+
+	var count int64
+	err := transaction.DB(ctx, d.db).
+		Model(&models.Membership{}).
+		Joins("JOIN organization_memberships other ON other.organization_id = organization_memberships.organization_id").
+		Joins("JOIN organizations ON organizations.id = organization_memberships.organization_id AND organizations.deleted_at IS NULL").
+		Where("organization_memberships.user_id = ? AND other.user_id = ?", userID, otherUserID).
+		Count(&count).Error
+	if err != nil {
+		l.Error("failed to check shared organizations", "error", err)
+		return false, err
+	}
+
+	l.Debug("shared organizations counted", "user_id", userID, "other_user_id", otherUserID, "count", count)
+	return count > 0, nil
+}
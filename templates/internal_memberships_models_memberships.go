@@ -0,0 +1,110 @@
+package models
+
+import (
+	"time"
+
+	organizationsModels "{{.Module}}/internal/organizations/models"
+	"{{.Module}}/internal/shared/validation"
+	usersModels "{{.Module}}/internal/users/models"
+)
+
+// Membership links a user to an organization with a role. A user can belong to
+// several organizations.
+type Membership struct {
+	ID                string    `json:"id" gorm:"unique" validate:"required"`
+	ClerkMembershipID string    `json:"clerk_membership_id" gorm:"unique" validate:"required"` // Clerk's membership ID
+	UserID            string    `json:"user_id" validate:"required"`                           // users.id
+	OrganizationID    string    `json:"organization_id" validate:"required"`                   // organizations.id
+	Role              string    `json:"role" validate:"required"`                              // e.g. org:admin, org:member
+	JoinedAt          time.Time `json:"joined_at"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+
+	// Loaded by the list queries
+	User         *usersModels.User                 `json:"user,omitempty"`
+	Organization *organizationsModels.Organization `json:"organization,omitempty"`
+}
+
+// Organization roles. Instances created before Clerk's custom roles send "admin" and "basic_member".
+const (
+	RoleAdmin       = "org:admin"
+	RoleMember      = "org:member"
+	legacyRoleAdmin = "admin"
+)
+
+// IsAdmin reports whether the member may manage the organization
+func (m Membership) IsAdmin() bool {
+	return m.Role == RoleAdmin || m.Role == legacyRoleAdmin
+}
+
+func (Membership) TableName() string {
+	return "organization_memberships"
+}
+
+type ClerkMembershipRequest struct {
+	Data            MembershipData  `json:"data" validate:"required"`
+	EventAttributes EventAttributes `json:"event_attributes" validate:"required"`
+	Object          string          `json:"object" validate:"required"`
+	Timestamp       int64           `json:"timestamp" validate:"required"`
+	Type            string          `json:"type" validate:"required"`
+}
+
+type MembershipData struct {
+	ID             string               `json:"id" validate:"required"`
+	Object         string               `json:"object"`
+	Role           string               `json:"role" validate:"required"`
+	Organization   MembershipOrg        `json:"organization" validate:"required"`
+	PublicUserData MembershipPublicUser `json:"public_user_data" validate:"required"`
+	CreatedAt      int64                `json:"created_at"`
+	UpdatedAt      int64                `json:"updated_at"`
+}
+
+type MembershipOrg struct {
+	ID   string `json:"id" validate:"required"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type MembershipPublicUser struct {
+	UserID     string `json:"user_id" validate:"required"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Identifier string `json:"identifier"`
+	ImageURL   string `json:"image_url"`
+}
+
+type EventAttributes struct {
+	HTTPRequest HTTPRequest `json:"http_request"`
+}
+
+type HTTPRequest struct {
+	ClientIP  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+}
+
+// ToMembership maps the webhook payload; UserID and OrganizationID are resolved
+// from the Clerk IDs by the service
+func (cmr *ClerkMembershipRequest) ToMembership() Membership {
+	return Membership{
+		ClerkMembershipID: cmr.Data.ID,
+		Role:              cmr.Data.Role,
+		JoinedAt:          time.UnixMilli(cmr.Data.CreatedAt),
+		CreatedAt:         time.UnixMilli(cmr.Data.CreatedAt),
+		UpdatedAt:         time.UnixMilli(cmr.Data.UpdatedAt),
+	}
+}
+
+// Sanitize cleans all string fields in the Membership struct
+func (m *Membership) Sanitize() {
+	m.ID = validation.SanitizeString(m.ID)
+	m.ClerkMembershipID = validation.SanitizeString(m.ClerkMembershipID)
+	m.UserID = validation.SanitizeString(m.UserID)
+	m.OrganizationID = validation.SanitizeString(m.OrganizationID)
+	m.Role = validation.SanitizeString(m.Role)
+}
+
+// Sanitize cleans all string fields in the ClerkMembershipRequest struct
+func (cmr *ClerkMembershipRequest) Sanitize() {
+	cmr.Object = validation.SanitizeString(cmr.Object)
+	cmr.Type = validation.SanitizeString(cmr.Type)
+}
@@ -0,0 +1,150 @@
+//go:generate mockgen -destination=../../mocks/mock_memberships_service.go -package=mocks {{.Module}}/internal/memberships/service MembershipsService
+
+package memberships
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	membershipsDatasource "{{.Module}}/internal/memberships/datasource"
+	"{{.Module}}/internal/memberships/models"
+	organizationsDatasource "{{.Module}}/internal/organizations/datasource"
+	"{{.Module}}/internal/shared/assertions"
+	httpHelpers "{{.Module}}/internal/shared/http"
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/uuid"
+	"{{.Module}}/internal/shared/validation"
+	usersDatasource "{{.Module}}/internal/users/datasource"
+	usersService "{{.Module}}/internal/users/service"
+
+	"gorm.io/gorm"
+)
+
+const (
+	pkgName = "memberships"
+	layer   = "service"
+)
+
+type MembershipsService interface {
+	SaveMembership(ctx context.Context, request *models.ClerkMembershipRequest) (*models.Membership, error)
+	DeleteMembership(ctx context.Context, clerkMembershipID string) (bool, error)
+	ListOrganizationMembers(ctx context.Context, clerkUserID string, orgID string) ([]models.Membership, error)
+	ListUserOrganizations(ctx context.Context, clerkUserID string, userID string) ([]models.Membership, error)
+}
+
+var (
+	errNotMember = httpHelpers.NewError(http.StatusForbidden, "organization membership required")
+	errNotSelf   = httpHelpers.NewError(http.StatusForbidden, "only your own organizations can be listed")
+)
+
+type ServiceImpl struct {
+	log           *logger.Logger
+	data          membershipsDatasource.MembershipsDatasource
+	users         usersDatasource.UsersDatasource
+	organizations organizationsDatasource.OrganizationsDatasource
+}
+
+func NewService(logger *logger.Logger, datasource membershipsDatasource.MembershipsDatasource, users usersDatasource.UsersDatasource, organizations organizationsDatasource.OrganizationsDatasource) MembershipsService {
+	serviceLogger := logger.With("package", pkgName, "layer", layer)
+	return &ServiceImpl{log: serviceLogger, data: datasource, users: users, organizations: organizations}
+}
+
+// SaveMembership creates or updates a membership from a Clerk
+// organizationMembership.created or .updated event. It fails while the user or
+// organization has not been received yet, so the webhook is retried.
+func (s *ServiceImpl) SaveMembership(ctx context.Context, request *models.ClerkMembershipRequest) (*models.Membership, error) {
+	l := s.log.WithContext(ctx).With("operation", "SaveMembership")
+	membership := request.ToMembership()
+	// Replaced by the stored ID when the user already belongs to the organization
+	membership.ID = uuid.GenerateNamespaceUUID("mem")
+
+	user, err := s.users.GetUserByClerkUserID(ctx, request.Data.PublicUserData.UserID)
+	if err != nil {
+		l.Debug("membership user not found", "clerk_user_id", request.Data.PublicUserData.UserID, "error", err)
+		return &models.Membership{}, fmt.Errorf("user %s: %w", request.Data.PublicUserData.UserID, err)
+	}
+	org, err := s.organizations.GetOrganizationByClerkOrgID(ctx, request.Data.Organization.ID)
+	if err != nil {
+		l.Debug("membership organization not found", "clerk_org_id", request.Data.Organization.ID, "error", err)
+		return &models.Membership{}, fmt.Errorf("organization %s: %w", request.Data.Organization.ID, err)
+	}
+	membership.UserID = user.ID
+	membership.OrganizationID = org.ID
+
+	if err := validation.ValidateStruct(membership); err != nil {
+		l.Error("failed to parse clerk membership request to membership", "error", err)
+		return &models.Membership{}, err
+	}
+
+	saved, err := s.data.UpsertMembership(ctx, &membership)
+	if err != nil {
+		return &models.Membership{}, err
+	}
+
+	return saved, nil
+}
+
+func (s *ServiceImpl) DeleteMembership(ctx context.Context, clerkMembershipID string) (bool, error) {
+	l := s.log.WithContext(ctx).With("operation", "DeleteMembership")
+
+	if err := assertions.AssertNonEmptyString(clerkMembershipID); err != nil {
+		l.Debug("failed to validate clerk membership id", "error", err)
+		return false, err
+	}
+
+	deleted, err := s.data.DeleteMembershipByClerkID(ctx, clerkMembershipID)
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
+
+// ListOrganizationMembers returns the members of organization orgID to a
+// caller who belongs to it
+func (s *ServiceImpl) ListOrganizationMembers(ctx context.Context, clerkUserID string, orgID string) ([]models.Membership, error) {
+	l := s.log.WithContext(ctx).With("operation", "ListOrganizationMembers")
+
+	if err := assertions.AssertNonEmptyString(orgID); err != nil {
+		l.Debug("failed to validate organization id", "error", err)
+		return nil, err
+	}
+
+	caller, err := usersService.Caller(ctx, s.users, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.data.GetMembership(ctx, caller.ID, orgID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			l.Debug("caller is not a member of the organization", "org_id", orgID)
+			return nil, errNotMember
+		}
+		return nil, err
+	}
+
+	return s.data.ListMembershipsByOrganizationID(ctx, orgID)
+}
+
+// ListUserOrganizations returns the organizations of user userID, who must be
+// the caller
+func (s *ServiceImpl) ListUserOrganizations(ctx context.Context, clerkUserID string, userID string) ([]models.Membership, error) {
+	l := s.log.WithContext(ctx).With("operation", "ListUserOrganizations")
+
+	if err := assertions.AssertNonEmptyString(userID); err != nil {
+		l.Debug("failed to validate user id", "error", err)
+		return nil, err
+	}
+
+	caller, err := usersService.Caller(ctx, s.users, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+	if caller.ID != userID {
+		l.Debug("caller listed the organizations of another user", "user_id", userID)
+		return nil, errNotSelf
+	}
+
+	return s.data.ListMembershipsByUserID(ctx, userID)
+}
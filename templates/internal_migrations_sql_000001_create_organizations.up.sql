@@ -0,0 +1,12 @@
+CREATE TABLE organizations (
+    id           TEXT PRIMARY KEY,
+    clerk_org_id TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    slug         TEXT,
+    image_url    TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at   TIMESTAMPTZ,
+    CONSTRAINT uni_organizations_clerk_org_id UNIQUE (clerk_org_id),
+    CONSTRAINT uni_organizations_slug UNIQUE (slug)
+);
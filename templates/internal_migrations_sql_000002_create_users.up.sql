@@ -0,0 +1,23 @@
+CREATE TABLE users (
+    id                  TEXT PRIMARY KEY,
+    clerk_user_id       TEXT NOT NULL,
+    email               TEXT,
+    first_name          TEXT NOT NULL,
+    last_name           TEXT NOT NULL,
+    organization_id     TEXT,
+    is_business_acount  BOOLEAN NOT NULL DEFAULT FALSE,
+    business_name       TEXT,
+    profile_image_url   TEXT,
+    mfa_enabled         BOOLEAN NOT NULL DEFAULT FALSE,
+    two_factor_enabled  BOOLEAN NOT NULL DEFAULT FALSE,
+    is_banned           BOOLEAN NOT NULL DEFAULT FALSE,
+    last_active_at      TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at          TIMESTAMPTZ,
+    CONSTRAINT uni_users_clerk_user_id UNIQUE (clerk_user_id),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+-- Column names follow the GORM naming of the User model, including is_business_acount
+CREATE INDEX idx_users_organization_id ON users (organization_id);
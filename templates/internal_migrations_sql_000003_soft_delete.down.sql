@@ -0,0 +1,8 @@
+-- Fails while a soft-deleted row shares its email or slug with a live one
+DROP INDEX IF EXISTS uni_users_email;
+ALTER TABLE users ADD CONSTRAINT uni_users_email UNIQUE (email);
+DROP INDEX IF EXISTS uni_organizations_slug;
+ALTER TABLE organizations ADD CONSTRAINT uni_organizations_slug UNIQUE (slug);
+
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS idx_organizations_deleted_at;
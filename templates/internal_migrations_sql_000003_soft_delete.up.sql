@@ -0,0 +1,12 @@
+-- Rows written before soft deletes stored the zero time instead of NULL
+UPDATE organizations SET deleted_at = NULL WHERE deleted_at < '0002-01-01';
+UPDATE users SET deleted_at = NULL WHERE deleted_at < '0002-01-01';
+
+CREATE INDEX idx_organizations_deleted_at ON organizations (deleted_at);
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+
+-- Soft-deleted rows must not keep their slug or email from being reused
+ALTER TABLE organizations DROP CONSTRAINT uni_organizations_slug;
+CREATE UNIQUE INDEX uni_organizations_slug ON organizations (slug) WHERE deleted_at IS NULL;
+ALTER TABLE users DROP CONSTRAINT uni_users_email;
+CREATE UNIQUE INDEX uni_users_email ON users (email) WHERE deleted_at IS NULL;
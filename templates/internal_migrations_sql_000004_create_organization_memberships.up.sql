@@ -0,0 +1,15 @@
+CREATE TABLE organization_memberships (
+    id                   TEXT PRIMARY KEY,
+    clerk_membership_id  TEXT NOT NULL,
+    user_id              TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    organization_id      TEXT NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    role                 TEXT NOT NULL,
+    joined_at            TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uni_organization_memberships_clerk_membership_id UNIQUE (clerk_membership_id),
+    CONSTRAINT uni_organization_memberships_user_organization UNIQUE (user_id, organization_id)
+);
+
+-- The user side of the pair is covered by the unique constraint
+CREATE INDEX idx_organization_memberships_organization_id ON organization_memberships (organization_id);
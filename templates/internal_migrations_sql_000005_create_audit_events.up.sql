@@ -0,0 +1,18 @@
+-- Append-only: rows are never updated, and outlive the users and organizations they name
+CREATE TABLE audit_events (
+    id             TEXT PRIMARY KEY,
+    actor_type     TEXT NOT NULL,
+    actor_id       TEXT NOT NULL DEFAULT '',
+    action         TEXT NOT NULL,
+    resource_type  TEXT NOT NULL,
+    resource_id    TEXT NOT NULL,
+    changes        JSONB NOT NULL DEFAULT '{}',
+    request_id     TEXT NOT NULL DEFAULT '',
+    client_ip      TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One index per query of the admin endpoint, each ending in the page order
+CREATE INDEX idx_audit_events_resource ON audit_events (resource_type, resource_id, created_at, id);
+CREATE INDEX idx_audit_events_actor ON audit_events (actor_id, created_at, id);
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at, id);
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS organizations_bump_version ON organizations;
+DROP TRIGGER IF EXISTS users_bump_version ON users;
+DROP FUNCTION IF EXISTS bump_row_version();
+
+ALTER TABLE organizations DROP COLUMN IF EXISTS version;
+ALTER TABLE users DROP COLUMN IF EXISTS version;
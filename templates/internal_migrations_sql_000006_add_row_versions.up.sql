@@ -0,0 +1,22 @@
+-- The version backs the ETag of a row. The trigger bumps it on every update that
+-- changes the row, webhook syncs and restores included, so no write can keep a
+-- stale ETag valid. A webhook echoing a change made through the API, which only
+-- touches updated_at, leaves it alone.
+ALTER TABLE users ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE organizations ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+CREATE FUNCTION bump_row_version() RETURNS trigger AS $$
+BEGIN
+    IF to_jsonb(NEW) - 'updated_at' - 'version' IS DISTINCT FROM to_jsonb(OLD) - 'updated_at' - 'version' THEN
+        NEW.version := OLD.version + 1;
+    ELSE
+        NEW.version := OLD.version;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_bump_version BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION bump_row_version();
+CREATE TRIGGER organizations_bump_version BEFORE UPDATE ON organizations
+    FOR EACH ROW EXECUTE FUNCTION bump_row_version();
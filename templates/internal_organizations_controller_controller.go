@@ -6,13 +6,16 @@ import (
 
 	"{{.Module}}/internal/organizations/models"
 	organizationsService "{{.Module}}/internal/organizations/service"
+	"{{.Module}}/internal/shared/concurrency"
 	"{{.Module}}/internal/shared/constants"
 	httpHelpers "{{.Module}}/internal/shared/http"
 	"{{.Module}}/internal/shared/logger"
 	"{{.Module}}/internal/shared/middleware"
+	"{{.Module}}/internal/shared/pagination"
 	"{{.Module}}/internal/shared/validation"
 
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 const (
@@ -23,9 +26,18 @@ const (
 type OrganizationsController interface {
 	GetOrganizationByID(w http.ResponseWriter, r *http.Request) error
 	GetOrganizationByClerkID(w http.ResponseWriter, r *http.Request) error
+	GetOrganizationBySlug(w http.ResponseWriter, r *http.Request) error
+	CheckSlug(w http.ResponseWriter, r *http.Request) error
+	CreateOrganization(w http.ResponseWriter, r *http.Request) error
+	UpdateOrganization(w http.ResponseWriter, r *http.Request) error
+	DeleteOrganizationByID(w http.ResponseWriter, r *http.Request) error
 	CreateOrganizationFromClerk(w http.ResponseWriter, r *http.Request) error
 	UpdateOrganizationFromClerk(w http.ResponseWriter, r *http.Request) error
 	DeleteOrganization(w http.ResponseWriter, r *http.Request) error
+	ListOrganizations(w http.ResponseWriter, r *http.Request) error
+	ListDeletedOrganizations(w http.ResponseWriter, r *http.Request) error
+	RestoreOrganization(w http.ResponseWriter, r *http.Request) error
+	PurgeOrganization(w http.ResponseWriter, r *http.Request) error
 }
 
 type ControllerImpl struct {
@@ -57,6 +69,10 @@ func (c *ControllerImpl) GetOrganizationByID(w http.ResponseWriter, r *http.Requ
 		return httpHelpers.RespondWithError(w, err)
 	}
 
+	if concurrency.NotModified(w, r, org.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
 	return httpHelpers.RespondWithJSON(w, http.StatusOK, org)
 }
 
@@ -79,9 +95,166 @@ func (c *ControllerImpl) GetOrganizationByClerkID(w http.ResponseWriter, r *http
 		return httpHelpers.RespondWithError(w, err)
 	}
 
+	if concurrency.NotModified(w, r, org.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, org)
+}
+
+func (c *ControllerImpl) GetOrganizationBySlug(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "GetOrganizationBySlug")
+
+	slug := mux.Vars(r)["slug"]
+	if slug == "" {
+		l.Debug("missing organization slug in path")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusBadRequest, "organization slug is required"))
+	}
+
+	org, err := c.service.GetOrganizationBySlug(ctx, slug)
+	if err != nil {
+		return c.respondWithManagementError(w, l, err, "failed to get organization by slug")
+	}
+
+	if concurrency.NotModified(w, r, org.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, org)
+}
+
+// CheckSlug answers whether the slug query parameter is free, with suggestions when it isn't
+func (c *ControllerImpl) CheckSlug(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "CheckSlug")
+
+	availability, err := c.service.CheckSlug(ctx, r.URL.Query().Get("slug"))
+	if err != nil {
+		return c.respondWithManagementError(w, l, err, "failed to check organization slug")
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, availability)
+}
+
+// CreateOrganization creates an organization with the caller as its admin
+func (c *ControllerImpl) CreateOrganization(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "CreateOrganization")
+
+	clerkUserID, ok := middleware.ClerkUserID(ctx)
+	if !ok {
+		l.Debug("no clerk user id in request context")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusUnauthorized, "Unauthorized"))
+	}
+
+	request := models.CreateOrganizationRequest{}
+	if err := middleware.SafeJSONDecoder(r, &request, constants.JSONMaxSize); err != nil {
+		l.Debug("failed to decode create organization request", "error", err)
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusBadRequest, err.Error()))
+	}
+
+	if err := validation.ValidateStruct(request); err != nil {
+		l.Debug("failed to validate create organization request", "error", err)
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusBadRequest, err.Error()))
+	}
+
+	org, err := c.service.CreateOrganizationAsUser(ctx, clerkUserID, request)
+	if err != nil {
+		return c.respondWithManagementError(w, l, err, "failed to create organization")
+	}
+
+	concurrency.SetETag(w, org.Version)
+	return httpHelpers.RespondWithJSON(w, http.StatusCreated, org)
+}
+
+// UpdateOrganization renames an organization or changes its slug; the caller
+// must be an admin of it. With If-Match, only the tagged version is updated.
+func (c *ControllerImpl) UpdateOrganization(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "UpdateOrganization")
+
+	clerkUserID, ok := middleware.ClerkUserID(ctx)
+	if !ok {
+		l.Debug("no clerk user id in request context")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusUnauthorized, "Unauthorized"))
+	}
+
+	version, err := concurrency.IfMatch(r)
+	if err != nil {
+		l.Debug("rejected update organization precondition", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	request := models.UpdateOrganizationRequest{}
+	if err := middleware.SafeJSONDecoder(r, &request, constants.JSONMaxSize); err != nil {
+		l.Debug("failed to decode update organization request", "error", err)
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusBadRequest, err.Error()))
+	}
+
+	if err := validation.ValidateStruct(request); err != nil {
+		l.Debug("failed to validate update organization request", "error", err)
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusBadRequest, err.Error()))
+	}
+
+	org, err := c.service.UpdateOrganizationAsUser(ctx, clerkUserID, mux.Vars(r)["id"], request, version)
+	if err != nil {
+		return c.respondWithManagementError(w, l, err, "failed to update organization")
+	}
+
+	concurrency.SetETag(w, org.Version)
 	return httpHelpers.RespondWithJSON(w, http.StatusOK, org)
 }
 
+// DeleteOrganizationByID soft-deletes an organization; the caller must be an admin of it
+func (c *ControllerImpl) DeleteOrganizationByID(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "DeleteOrganizationByID")
+
+	clerkUserID, ok := middleware.ClerkUserID(ctx)
+	if !ok {
+		l.Debug("no clerk user id in request context")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusUnauthorized, "Unauthorized"))
+	}
+
+	if err := c.service.DeleteOrganizationAsUser(ctx, clerkUserID, mux.Vars(r)["id"]); err != nil {
+		return c.respondWithManagementError(w, l, err, "failed to delete organization")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// respondWithManagementError answers 404 for a missing organization and 409
+// with suggestions for a taken slug, and logs unexpected errors
+func (c *ControllerImpl) respondWithManagementError(w http.ResponseWriter, l *logger.Logger, err error, msg string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		l.Debug("organization not found")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusNotFound, "organization not found"))
+	}
+
+	var slugErr *models.SlugTakenError
+	if errors.As(err, &slugErr) {
+		l.Debug("organization slug taken", "slug", slugErr.Slug)
+		suggestions := slugErr.Suggestions
+		if suggestions == nil {
+			suggestions = []string{}
+		}
+		return httpHelpers.RespondWithJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":       slugErr.Error(),
+			"suggestions": suggestions,
+		})
+	}
+
+	var httpErr *httpHelpers.Error
+	if errors.As(err, &httpErr) && httpErr.Status < http.StatusInternalServerError {
+		l.Debug(msg, "error", err)
+	} else {
+		l.Error(msg, "error", err)
+	}
+	return httpHelpers.RespondWithError(w, err)
+}
+
 func (c *ControllerImpl) CreateOrganizationFromClerk(w http.ResponseWriter, r *http.Request) error {
 	ctx := r.Context()
 	l := c.log.WithContext(ctx).With("method", "CreateOrganizationFromClerk")
@@ -151,3 +324,78 @@ func (c *ControllerImpl) DeleteOrganization(w http.ResponseWriter, r *http.Reque
 
 	return httpHelpers.RespondWithJSON(w, http.StatusOK, deleted)
 }
+
+func (c *ControllerImpl) ListOrganizations(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "ListOrganizations")
+
+	page, err := pagination.Parse(r.URL.Query(), models.OrganizationListOptions)
+	if err != nil {
+		l.Debug("invalid list organizations query", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+	filter, err := models.NewOrganizationFilter(page.Filters)
+	if err != nil {
+		l.Debug("invalid list organizations filter", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	organizations, next, err := c.service.ListOrganizations(ctx, filter, page)
+	if err != nil {
+		l.Error("failed to list organizations", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, pagination.NewPage(r, organizations, page.Limit, next))
+}
+
+func (c *ControllerImpl) ListDeletedOrganizations(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "ListDeletedOrganizations")
+
+	deleted, err := c.service.ListDeletedOrganizations(ctx)
+	if err != nil {
+		l.Error("failed to list deleted organizations", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, deleted)
+}
+
+func (c *ControllerImpl) RestoreOrganization(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "RestoreOrganization")
+
+	clerkID := mux.Vars(r)["clerk_id"]
+	if clerkID == "" {
+		l.Debug("missing clerk organization id in path")
+		return httpHelpers.RespondWithError(w, errors.New("clerk organization id is required"))
+	}
+
+	restored, err := c.service.RestoreOrganization(ctx, clerkID)
+	if err != nil {
+		l.Error("failed to restore organization", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, restored)
+}
+
+func (c *ControllerImpl) PurgeOrganization(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "PurgeOrganization")
+
+	clerkID := mux.Vars(r)["clerk_id"]
+	if clerkID == "" {
+		l.Debug("missing clerk organization id in path")
+		return httpHelpers.RespondWithError(w, errors.New("clerk organization id is required"))
+	}
+
+	purged, err := c.service.PurgeOrganization(ctx, clerkID)
+	if err != nil {
+		l.Error("failed to purge organization", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, purged)
+}
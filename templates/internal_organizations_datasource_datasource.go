@@ -4,13 +4,19 @@ package datasource
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"{{.Module}}/internal/organizations/models"
 	"{{.Module}}/internal/shared/assertions"
+	"{{.Module}}/internal/shared/concurrency"
 	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/pagination"
+	"{{.Module}}/internal/shared/transaction"
 	"{{.Module}}/internal/shared/uuid"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
@@ -18,12 +24,25 @@ const (
 	layer   = "datasource"
 )
 
+// likeEscaper makes a filter value match literally inside a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 type OrganizationsDatasource interface {
 	CreateOrganization(ctx context.Context, org *models.Organization) (*models.Organization, error)
 	UpdateOrganization(ctx context.Context, org *models.Organization) (bool, error)
 	GetOrganizationByClerkOrgID(ctx context.Context, clerkOrgID string) (*models.Organization, error)
 	GetOrganizationByID(ctx context.Context, id string) (*models.Organization, error)
+	GetOrganizationBySlug(ctx context.Context, slug string) (*models.Organization, error)
+	GetDeletedOrganizationByClerkID(ctx context.Context, clerkID string) (*models.Organization, error)
+	ListTakenSlugs(ctx context.Context, slug string) ([]string, error)
+	FilterTakenSlugs(ctx context.Context, slugs []string) ([]string, error)
+	UpdateOrganizationByID(ctx context.Context, id string, changes map[string]interface{}, version int64) (*models.Organization, error)
+	ListOrganizations(ctx context.Context, filter models.OrganizationFilter, page pagination.Request) ([]models.Organization, string, error)
 	DeleteOrganizationByClerkID(ctx context.Context, clerkID string) (bool, error)
+	ListDeletedOrganizations(ctx context.Context) ([]models.Organization, error)
+	RestoreOrganizationByClerkID(ctx context.Context, clerkID string) (bool, error)
+	PurgeOrganizationByClerkID(ctx context.Context, clerkID string) (bool, error)
+	PurgeDeletedOrganizationsBefore(ctx context.Context, cutoff time.Time) (int64, error)
 }
 
 type DatasourceImpl struct {
@@ -44,7 +63,7 @@ func (d *DatasourceImpl) CreateOrganization(ctx context.Context, org *models.Org
 		org.ID = uuid.GenerateNamespaceUUID("org")
 	}
 
-	if err := d.db.WithContext(ctx).Create(org).Error; err != nil {
+	if err := transaction.DB(ctx, d.db).Create(org).Error; err != nil {
 		l.Error("failed to create organization", "error", err)
 		return nil, err
 	}
@@ -56,7 +75,7 @@ func (d *DatasourceImpl) CreateOrganization(ctx context.Context, org *models.Org
 func (d *DatasourceImpl) UpdateOrganization(ctx context.Context, org *models.Organization) (bool, error) {
 	l := d.log.WithContext(ctx).With("operation", "UpdateOrganization")
 
-	result := d.db.WithContext(ctx).Model(org).Where("clerk_org_id = ?", org.ClerkOrgID).Updates(org)
+	result := transaction.DB(ctx, d.db).Model(org).Where("clerk_org_id = ?", org.ClerkOrgID).Updates(org)
 	if result.Error != nil {
 		l.Error("failed to update organization", "error", result.Error)
 		return false, result.Error
@@ -75,7 +94,7 @@ func (d *DatasourceImpl) GetOrganizationByClerkOrgID(ctx context.Context, clerkO
 	}
 
 	var org models.Organization
-	if err := d.db.WithContext(ctx).Where("clerk_org_id = ?", clerkOrgID).First(&org).Error; err != nil {
+	if err := transaction.DB(ctx, d.db).Where("clerk_org_id = ?", clerkOrgID).First(&org).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			l.Debug("organization not found", "clerk_org_id", clerkOrgID)
 			return nil, err
@@ -97,7 +116,7 @@ func (d *DatasourceImpl) GetOrganizationByID(ctx context.Context, id string) (*m
 	}
 
 	var org models.Organization
-	if err := d.db.WithContext(ctx).Where("id = ?", id).First(&org).Error; err != nil {
+	if err := transaction.DB(ctx, d.db).Where("id = ?", id).First(&org).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			l.Debug("organization not found", "org_id", id)
 			return nil, err
@@ -110,6 +129,174 @@ func (d *DatasourceImpl) GetOrganizationByID(ctx context.Context, id string) (*m
 	return &org, nil
 }
 
+// GetDeletedOrganizationByClerkID returns a soft-deleted organization; a live one is not found
+func (d *DatasourceImpl) GetDeletedOrganizationByClerkID(ctx context.Context, clerkID string) (*models.Organization, error) {
+	l := d.log.WithContext(ctx).With("operation", "GetDeletedOrganizationByClerkID")
+
+	if err := assertions.AssertNonEmptyString(clerkID); err != nil {
+		l.Debug("invalid clerk organization id", "error", err)
+		return nil, err
+	}
+
+	var org models.Organization
+	if err := transaction.DB(ctx, d.db).Unscoped().Where("clerk_org_id = ? AND deleted_at IS NOT NULL", clerkID).First(&org).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			l.Debug("deleted organization not found", "clerk_org_id", clerkID)
+			return nil, err
+		}
+		l.Error("failed to get deleted organization by clerk org id", "error", err)
+		return nil, err
+	}
+
+	l.Debug("deleted organization retrieved successfully", "org_id", org.ID)
+	return &org, nil
+}
+
+func (d *DatasourceImpl) GetOrganizationBySlug(ctx context.Context, slug string) (*models.Organization, error) {
+	l := d.log.WithContext(ctx).With("operation", "GetOrganizationBySlug")
+
+	if err := assertions.AssertNonEmptyString(slug); err != nil {
+		l.Debug("invalid organization slug", "error", err)
+		return nil, err
+	}
+
+	var org models.Organization
+	if err := transaction.DB(ctx, d.db).Where("slug = ?", slug).First(&org).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			l.Debug("organization not found", "slug", slug)
+			return nil, err
+		}
+		l.Error("failed to get organization by slug", "error", err)
+		return nil, err
+	}
+
+	l.Debug("organization retrieved successfully", "org_id", org.ID)
+	return &org, nil
+}
+
+// ListTakenSlugs returns slug and the "slug-" prefixed slugs in use. Soft-deleted
+// organizations release their slug, as the unique index only covers live rows.
+func (d *DatasourceImpl) ListTakenSlugs(ctx context.Context, slug string) ([]string, error) {
+	l := d.log.WithContext(ctx).With("operation", "ListTakenSlugs")
+
+	if err := assertions.AssertNonEmptyString(slug); err != nil {
+		l.Debug("invalid organization slug", "error", err)
+		return nil, err
+	}
+
+	var taken []string
+	err := transaction.DB(ctx, d.db).
+		Model(&models.Organization{}).
+		Where("slug = ? OR slug LIKE ?", slug, likeEscaper.Replace(slug)+"-%").
+		Pluck("slug", &taken).Error
+	if err != nil {
+		l.Error("failed to list taken slugs", "error", err)
+		return nil, err
+	}
+
+	l.Debug("taken slugs retrieved successfully", "slug", slug, "count", len(taken))
+	return taken, nil
+}
+
+// FilterTakenSlugs returns those of slugs that are in use by live organizations
+func (d *DatasourceImpl) FilterTakenSlugs(ctx context.Context, slugs []string) ([]string, error) {
+	l := d.log.WithContext(ctx).With("operation", "FilterTakenSlugs")
+
+	if len(slugs) == 0 {
+		return nil, nil
+	}
+
+	var taken []string
+	err := transaction.DB(ctx, d.db).
+		Model(&models.Organization{}).
+		Where("slug IN ?", slugs).
+		Pluck("slug", &taken).Error
+	if err != nil {
+		l.Error("failed to filter taken slugs", "error", err)
+		return nil, err
+	}
+
+	l.Debug("taken slugs filtered successfully", "count", len(taken))
+	return taken, nil
+}
+
+// UpdateOrganizationByID writes changes, keyed by column, and returns the
+// updated organization, or gorm.ErrRecordNotFound when no live organization has
+// id. A non-zero version makes the update conditional: it fails with
+// concurrency.ErrVersionConflict when the organization is at another version.
+func (d *DatasourceImpl) UpdateOrganizationByID(ctx context.Context, id string, changes map[string]interface{}, version int64) (*models.Organization, error) {
+	l := d.log.WithContext(ctx).With("operation", "UpdateOrganizationByID")
+
+	if err := assertions.AssertNonEmptyString(id); err != nil {
+		l.Debug("invalid organization id", "error", err)
+		return nil, err
+	}
+
+	var org models.Organization
+	query := transaction.DB(ctx, d.db).
+		Model(&org).
+		Clauses(clause.Returning{}).
+		Where("id = ?", id)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.Updates(changes)
+	if result.Error != nil {
+		l.Error("failed to update organization", "error", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if version > 0 {
+			var count int64
+			if err := transaction.DB(ctx, d.db).Model(&models.Organization{}).Where("id = ?", id).Count(&count).Error; err != nil {
+				l.Error("failed to check organization version", "error", err)
+				return nil, err
+			}
+			if count > 0 {
+				l.Debug("organization version conflict", "org_id", id, "version", version)
+				return nil, concurrency.ErrVersionConflict
+			}
+		}
+		l.Debug("organization not found", "org_id", id)
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	l.Debug("organization updated successfully", "org_id", org.ID)
+	return &org, nil
+}
+
+// ListOrganizations returns a page of organizations and the cursor of the next page, empty on the last one
+func (d *DatasourceImpl) ListOrganizations(ctx context.Context, filter models.OrganizationFilter, page pagination.Request) ([]models.Organization, string, error) {
+	l := d.log.WithContext(ctx).With("operation", "ListOrganizations")
+
+	query := transaction.DB(ctx, d.db).Model(&models.Organization{})
+	if filter.Name != "" {
+		query = query.Where("organizations.name ILIKE ?", "%"+likeEscaper.Replace(filter.Name)+"%")
+	}
+	if filter.Slug != "" {
+		query = query.Where("organizations.slug = ?", filter.Slug)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("organizations.created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("organizations.created_at < ?", *filter.CreatedBefore)
+	}
+
+	var orgs []models.Organization
+	if err := page.Apply(query).Find(&orgs).Error; err != nil {
+		l.Error("failed to list organizations", "error", err)
+		return nil, "", err
+	}
+
+	orgs, next := pagination.Trim(orgs, page, func(o models.Organization) (interface{}, string) {
+		return o.SortValue(page.SortField()), o.ID
+	})
+
+	l.Debug("organizations listed successfully", "count", len(orgs), "has_next", next != "")
+	return orgs, next, nil
+}
+
 func (d *DatasourceImpl) DeleteOrganizationByClerkID(ctx context.Context, clerkID string) (bool, error) {
 	l := d.log.WithContext(ctx).With("operation", "DeleteOrganizationByClerkID")
 
@@ -118,7 +305,7 @@ func (d *DatasourceImpl) DeleteOrganizationByClerkID(ctx context.Context, clerkI
 		return false, err
 	}
 
-	result := d.db.WithContext(ctx).Where("clerk_org_id = ?", clerkID).Delete(&models.Organization{})
+	result := transaction.DB(ctx, d.db).Where("clerk_org_id = ?", clerkID).Delete(&models.Organization{})
 	if result.Error != nil {
 		l.Error("failed to delete organization", "error", result.Error)
 		return false, result.Error
@@ -127,3 +314,74 @@ func (d *DatasourceImpl) DeleteOrganizationByClerkID(ctx context.Context, clerkI
 	l.Debug("organization deleted successfully", "clerk_org_id", clerkID, "rows_affected", result.RowsAffected)
 	return result.RowsAffected > 0, nil
 }
+
+func (d *DatasourceImpl) ListDeletedOrganizations(ctx context.Context) ([]models.Organization, error) {
+	l := d.log.WithContext(ctx).With("operation", "ListDeletedOrganizations")
+
+	var orgs []models.Organization
+	if err := transaction.DB(ctx, d.db).Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&orgs).Error; err != nil {
+		l.Error("failed to list deleted organizations", "error", err)
+		return nil, err
+	}
+
+	l.Debug("deleted organizations retrieved successfully", "count", len(orgs))
+	return orgs, nil
+}
+
+func (d *DatasourceImpl) RestoreOrganizationByClerkID(ctx context.Context, clerkID string) (bool, error) {
+	l := d.log.WithContext(ctx).With("operation", "RestoreOrganizationByClerkID")
+
+	if err := assertions.AssertNonEmptyString(clerkID); err != nil {
+		l.Debug("invalid clerk org id", "error", err)
+		return false, err
+	}
+
+	result := transaction.DB(ctx, d.db).Unscoped().Model(&models.Organization{}).
+		Where("clerk_org_id = ? AND deleted_at IS NOT NULL", clerkID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		l.Error("failed to restore organization", "error", result.Error)
+		return false, result.Error
+	}
+
+	l.Debug("organization restored successfully", "clerk_org_id", clerkID, "rows_affected", result.RowsAffected)
+	return result.RowsAffected > 0, nil
+}
+
+// PurgeOrganizationByClerkID permanently deletes an organization; only
+// soft-deleted organizations can be purged
+func (d *DatasourceImpl) PurgeOrganizationByClerkID(ctx context.Context, clerkID string) (bool, error) {
+	l := d.log.WithContext(ctx).With("operation", "PurgeOrganizationByClerkID")
+
+	if err := assertions.AssertNonEmptyString(clerkID); err != nil {
+		l.Debug("invalid clerk org id", "error", err)
+		return false, err
+	}
+
+	result := transaction.DB(ctx, d.db).Unscoped().
+		Where("clerk_org_id = ? AND deleted_at IS NOT NULL", clerkID).
+		Delete(&models.Organization{})
+	if result.Error != nil {
+		l.Error("failed to purge organization", "error", result.Error)
+		return false, result.Error
+	}
+
+	l.Debug("organization purged successfully", "clerk_org_id", clerkID, "rows_affected", result.RowsAffected)
+	return result.RowsAffected > 0, nil
+}
+
+// PurgeDeletedOrganizationsBefore permanently deletes the organizations soft-deleted before cutoff
+func (d *DatasourceImpl) PurgeDeletedOrganizationsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	l := d.log.WithContext(ctx).With("operation", "PurgeDeletedOrganizationsBefore")
+
+	result := transaction.DB(ctx, d.db).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Delete(&models.Organization{})
+	if result.Error != nil {
+		l.Error("failed to purge deleted organizations", "error", result.Error)
+		return 0, result.Error
+	}
+
+	l.Debug("deleted organizations purged successfully", "cutoff", cutoff, "rows_affected", result.RowsAffected)
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,64 @@
+//go:generate mockgen -destination=../../mocks/mock_organizations_identity.go -package=mocks {{.Module}}/internal/organizations/identity Provider
+
+package identity
+
+import (
+	"context"
+
+	"github.com/clerkinc/clerk-sdk-go/clerk"
+)
+
+// Provider pushes organization changes made through the API to the identity
+// provider, which then sends the usual webhooks back.
+type Provider interface {
+	// CreateOrganization returns the provider's ID of the new organization
+	CreateOrganization(ctx context.Context, params CreateParams) (string, error)
+	UpdateOrganization(ctx context.Context, providerID string, params UpdateParams) error
+	DeleteOrganization(ctx context.Context, providerID string) error
+}
+
+type CreateParams struct {
+	Name      string
+	Slug      string
+	CreatedBy string // provider user ID, made the organization's admin
+}
+
+// UpdateParams holds the fields to change; nil fields are left unchanged
+type UpdateParams struct {
+	Name *string
+	Slug *string
+}
+
+type clerkProvider struct {
+	client clerk.Client
+}
+
+// NewClerkProvider pushes organization changes to Clerk through its backend API
+func NewClerkProvider(client clerk.Client) Provider {
+	return &clerkProvider{client: client}
+}
+
+func (p *clerkProvider) CreateOrganization(ctx context.Context, params CreateParams) (string, error) {
+	org, err := p.client.Organizations().Create(clerk.CreateOrganizationParams{
+		Name:      params.Name,
+		Slug:      &params.Slug,
+		CreatedBy: params.CreatedBy,
+	})
+	if err != nil {
+		return "", err
+	}
+	return org.ID, nil
+}
+
+func (p *clerkProvider) UpdateOrganization(ctx context.Context, providerID string, params UpdateParams) error {
+	_, err := p.client.Organizations().Update(providerID, clerk.UpdateOrganizationParams{
+		Name: params.Name,
+		Slug: params.Slug,
+	})
+	return err
+}
+
+func (p *clerkProvider) DeleteOrganization(ctx context.Context, providerID string) error {
+	_, err := p.client.Organizations().Delete(providerID)
+	return err
+}
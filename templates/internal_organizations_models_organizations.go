@@ -1,20 +1,93 @@
 package models
 
 import (
+	"net/url"
+	"strings"
 	"time"
 
+	"{{.Module}}/internal/shared/pagination"
 	"{{.Module}}/internal/shared/validation"
+
+	"gorm.io/gorm"
 )
 
 type Organization struct {
-	ID         string    `json:"id" gorm:"unique" validate:"required"`
-	ClerkOrgID string    `json:"clerk_org_id" gorm:"unique" validate:"required"`
-	Name       string    `json:"name" validate:"required"`
-	Slug       string    `json:"slug" gorm:"unique"`
-	ImageURL   *string   `json:"image_url"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	DeletedAt  time.Time `json:"deleted_at"`
+	ID         string         `json:"id" gorm:"unique" validate:"required"`
+	ClerkOrgID string         `json:"clerk_org_id" gorm:"unique" validate:"required"`
+	Name       string         `json:"name" validate:"required"`
+	Slug       string         `json:"slug" gorm:"unique"`
+	ImageURL   *string        `json:"image_url"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index"`  // null until soft-deleted
+	Version    int64          `json:"version" gorm:"default:1"` // bumped by the database on every update
+}
+
+// OrganizationListOptions are the sort fields and filters of the organizations list
+var OrganizationListOptions = pagination.Options{
+	Table: "organizations",
+	SortFields: map[string]pagination.Field{
+		"created_at": {Column: "created_at", Time: true},
+		"updated_at": {Column: "updated_at", Time: true},
+		"name":       {Column: "name"},
+	},
+	DefaultSort: "-created_at",
+	Filters:     []string{"name", "slug", "created_after", "created_before"},
+}
+
+// OrganizationFilter narrows the organizations list; zero fields match every organization
+type OrganizationFilter struct {
+	Name          string // case-insensitive substring
+	Slug          string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// NewOrganizationFilter reads the filters allowed by OrganizationListOptions
+func NewOrganizationFilter(filters url.Values) (OrganizationFilter, error) {
+	filter := OrganizationFilter{
+		Name: strings.TrimSpace(filters.Get("name")),
+		Slug: strings.TrimSpace(filters.Get("slug")),
+	}
+
+	var err error
+	if filter.CreatedAfter, err = pagination.Time(filters, "created_after"); err != nil {
+		return OrganizationFilter{}, err
+	}
+	if filter.CreatedBefore, err = pagination.Time(filters, "created_before"); err != nil {
+		return OrganizationFilter{}, err
+	}
+	return filter, nil
+}
+
+// SortValue returns the value of an OrganizationListOptions sort field, for the next page cursor
+func (o Organization) SortValue(field string) interface{} {
+	switch field {
+	case "updated_at":
+		return o.UpdatedAt
+	case "name":
+		return o.Name
+	default:
+		return o.CreatedAt
+	}
+}
+
+// CreateOrganizationRequest is the body of POST /organizations
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,max=256"`
+	Slug string `json:"slug"` // derived from the name when empty
+}
+
+// UpdateOrganizationRequest is the body of PATCH /organizations/{id}; absent fields are left unchanged
+type UpdateOrganizationRequest struct {
+	Name *string `json:"name" validate:"omitempty,max=256"`
+	Slug *string `json:"slug"`
+}
+
+// IsLocal reports whether the organization was created through the API without
+// being pushed to Clerk, in which case its Clerk ID is its own ID
+func (o Organization) IsLocal() bool {
+	return o.ClerkOrgID == o.ID
 }
 
 type ClerkOrganizationRequest struct {
@@ -43,6 +116,7 @@ type OrganizationData struct {
 	Name      string `json:"name"`
 	Slug      string `json:"slug"`
 	ImageURL  string `json:"image_url"`
+	CreatedBy string `json:"created_by"` // Clerk user ID of the creator
 	CreatedAt int64  `json:"created_at"`
 	UpdatedAt int64  `json:"updated_at"`
 }
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+
+	httpHelpers "{{.Module}}/internal/shared/http"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Slug length bounds
+const (
+	SlugMinLength = 2
+	SlugMaxLength = 64
+)
+
+// SlugSuggestions is how many free alternatives a taken slug is answered with
+const SlugSuggestions = 3
+
+// slugPattern allows lowercase letters, digits and single hyphens between them
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// SlugTakenError is returned when an organization already uses a slug. The
+// controllers answer it with 409 and the suggestions.
+type SlugTakenError struct {
+	Slug        string
+	Suggestions []string
+}
+
+func (e *SlugTakenError) Error() string {
+	return fmt.Sprintf("slug %q is already taken", e.Slug)
+}
+
+// SlugAvailability answers GET /organizations/slug-availability
+type SlugAvailability struct {
+	Slug        string   `json:"slug"`
+	Available   bool     `json:"available"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// ValidateSlug rejects a malformed slug with a 400 error
+func ValidateSlug(slug string) error {
+	if len(slug) < SlugMinLength || len(slug) > SlugMaxLength {
+		return httpHelpers.NewError(http.StatusBadRequest, fmt.Sprintf("slug must be between %d and %d characters", SlugMinLength, SlugMaxLength))
+	}
+	if !slugPattern.MatchString(slug) {
+		return httpHelpers.NewError(http.StatusBadRequest, "slug may only contain lowercase letters, digits and single hyphens between them")
+	}
+	return nil
+}
+
+// Slugify derives a slug from an organization name, e.g. "Acme Café, Inc." becomes "acme-cafe-inc"
+func Slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range norm.NFKD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// drop accents left by the decomposition
+		case r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+		default:
+			hyphen = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > SlugMaxLength {
+		slug = strings.TrimRight(slug[:SlugMaxLength], "-")
+	}
+	return slug
+}
+
+// SuggestSlugs returns up to n variants of slug, "slug-2", "slug-3" and so on,
+// that are not in taken
+func SuggestSlugs(slug string, taken []string, n int) []string {
+	used := make(map[string]bool, len(taken))
+	for _, t := range taken {
+		used[t] = true
+	}
+
+	suggestions := make([]string, 0, n)
+	for i := 2; len(suggestions) < n; i++ {
+		suffix := fmt.Sprintf("-%d", i)
+		base := slug
+		if len(base)+len(suffix) > SlugMaxLength {
+			base = strings.TrimRight(base[:SlugMaxLength-len(suffix)], "-")
+		}
+		if candidate := base + suffix; !used[candidate] {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return suggestions
+}
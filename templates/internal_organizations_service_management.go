@@ -0,0 +1,351 @@
+package organizations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	auditModels "{{.Module}}/internal/audit/models"
+	"{{.Module}}/internal/organizations/identity"
+	"{{.Module}}/internal/organizations/models"
+	"{{.Module}}/internal/shared/assertions"
+	"{{.Module}}/internal/shared/concurrency"
+	httpHelpers "{{.Module}}/internal/shared/http"
+	"{{.Module}}/internal/shared/uuid"
+	usersService "{{.Module}}/internal/users/service"
+
+	"gorm.io/gorm"
+)
+
+// uniqueViolation is the SQLSTATE of a unique index conflict
+const uniqueViolation = "23505"
+
+var (
+	errNotAdmin         = httpHelpers.NewError(http.StatusForbidden, "organization admin role required")
+	errNameRequired     = httpHelpers.NewError(http.StatusBadRequest, "name is required")
+	errIdentityProvider = httpHelpers.NewError(http.StatusBadGateway, "failed to sync the organization with the identity provider")
+)
+
+func (s *ServiceImpl) GetOrganizationBySlug(ctx context.Context, slug string) (*models.Organization, error) {
+	l := s.log.WithContext(ctx).With("operation", "GetOrganizationBySlug")
+
+	if err := assertions.AssertNonEmptyString(slug); err != nil {
+		l.Debug("failed to validate organization slug", "error", err)
+		return &models.Organization{}, err
+	}
+
+	org, err := s.data.GetOrganizationBySlug(ctx, slug)
+	if err != nil {
+		return &models.Organization{}, err
+	}
+
+	return org, nil
+}
+
+// CheckSlug reports whether slug is free, with free alternatives when it isn't
+func (s *ServiceImpl) CheckSlug(ctx context.Context, slug string) (models.SlugAvailability, error) {
+	if err := models.ValidateSlug(slug); err != nil {
+		return models.SlugAvailability{}, err
+	}
+
+	taken, err := s.data.ListTakenSlugs(ctx, slug)
+	if err != nil {
+		return models.SlugAvailability{}, err
+	}
+
+	availability := models.SlugAvailability{Slug: slug, Available: !slices.Contains(taken, slug)}
+	if !availability.Available {
+		if availability.Suggestions, err = s.suggestSlugs(ctx, slug, taken, models.SlugSuggestions); err != nil {
+			return models.SlugAvailability{}, err
+		}
+	}
+	return availability, nil
+}
+
+// CreateOrganizationAsUser creates an organization with the caller as its
+// admin. A slug derived from the name gets a free numeric suffix when taken,
+// while a slug given in the request fails with models.SlugTakenError.
+func (s *ServiceImpl) CreateOrganizationAsUser(ctx context.Context, clerkUserID string, request models.CreateOrganizationRequest) (*models.Organization, error) {
+	l := s.log.WithContext(ctx).With("operation", "CreateOrganizationAsUser")
+
+	caller, err := usersService.Caller(ctx, s.users, clerkUserID)
+	if err != nil {
+		return &models.Organization{}, err
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return &models.Organization{}, errNameRequired
+	}
+
+	derived := request.Slug == ""
+	slug := request.Slug
+	if derived {
+		slug = models.Slugify(name)
+	}
+	if err := models.ValidateSlug(slug); err != nil {
+		if derived {
+			return &models.Organization{}, httpHelpers.NewError(http.StatusBadRequest, "no slug can be derived from the name, send one")
+		}
+		return &models.Organization{}, err
+	}
+	if slug, err = s.freeSlug(ctx, slug, derived); err != nil {
+		return &models.Organization{}, err
+	}
+
+	// Until the identity provider knows it, the organization's Clerk ID is its own ID
+	now := time.Now()
+	org := models.Organization{ID: uuid.GenerateNamespaceUUID("org"), Name: name, Slug: slug}
+	org.ClerkOrgID = org.ID
+
+	var created *models.Organization
+	var providerID string
+	err = s.tx.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if created, err = s.data.CreateOrganization(ctx, &org); err != nil {
+			return err
+		}
+
+		if err := s.addAdmin(ctx, caller.ID, created.ID, now); err != nil {
+			return err
+		}
+		if _, err := s.users.UpdateUserOrganization(ctx, clerkUserID, created.ID); err != nil {
+			return err
+		}
+
+		if s.identity == nil {
+			return s.record(ctx, auditModels.ActionOrganizationCreated, nil, created)
+		}
+		// Pushed last, inside the transaction, so a rejected push rolls the
+		// organization back; the transaction stays open for the length of the
+		// call. Clerk may send organization.created before the commit: its insert
+		// then waits on the slug, fails with a unique violation once this commits,
+		// and CreateOrganization saves it again as an update of this row. A
+		// retried attempt reuses the organization already created in the provider.
+		if providerID == "" {
+			providerID, err = s.identity.CreateOrganization(ctx, identity.CreateParams{Name: name, Slug: slug, CreatedBy: clerkUserID})
+			if err != nil {
+				l.Error("failed to create organization in the identity provider", "error", err)
+				return errIdentityProvider
+			}
+		}
+		if created, err = s.data.UpdateOrganizationByID(ctx, created.ID, map[string]interface{}{"clerk_org_id": providerID}, 0); err != nil {
+			return err
+		}
+		return s.record(ctx, auditModels.ActionOrganizationCreated, nil, created)
+	})
+	if err != nil {
+		if providerID != "" {
+			// Don't leave an organization in the provider that isn't stored here
+			if deleteErr := s.identity.DeleteOrganization(ctx, providerID); deleteErr != nil {
+				l.Error("failed to delete organization from the identity provider after a failed create", "provider_id", providerID, "error", deleteErr)
+			}
+		}
+		return &models.Organization{}, s.slugConflict(err, slug)
+	}
+
+	l.Info("organization created", "org_id", created.ID, "slug", created.Slug, "pushed", providerID != "")
+	return created, nil
+}
+
+// UpdateOrganizationAsUser renames an organization or changes its slug. The
+// caller must be one of its admins. A non-zero version must be the
+// organization's current one, else it fails with concurrency.ErrVersionConflict.
+func (s *ServiceImpl) UpdateOrganizationAsUser(ctx context.Context, clerkUserID string, id string, request models.UpdateOrganizationRequest, version int64) (*models.Organization, error) {
+	l := s.log.WithContext(ctx).With("operation", "UpdateOrganizationAsUser")
+
+	org, err := s.adminOrganization(ctx, clerkUserID, id)
+	if err != nil {
+		return &models.Organization{}, err
+	}
+	// Checked up front too, so a stale request is refused even when it changes nothing
+	if version > 0 && version != org.Version {
+		return &models.Organization{}, concurrency.ErrVersionConflict
+	}
+
+	changes := map[string]interface{}{}
+	var params identity.UpdateParams
+	if request.Name != nil {
+		name := strings.TrimSpace(*request.Name)
+		if name == "" {
+			return &models.Organization{}, errNameRequired
+		}
+		if name != org.Name {
+			changes["name"] = name
+			params.Name = &name
+		}
+	}
+	if request.Slug != nil && *request.Slug != org.Slug {
+		slug := *request.Slug
+		if err := models.ValidateSlug(slug); err != nil {
+			return &models.Organization{}, err
+		}
+		if _, err := s.freeSlug(ctx, slug, false); err != nil {
+			return &models.Organization{}, err
+		}
+		changes["slug"] = slug
+		params.Slug = &slug
+	}
+	if len(changes) == 0 {
+		return org, nil
+	}
+
+	var updated *models.Organization
+	err = s.tx.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if updated, err = s.data.UpdateOrganizationByID(ctx, org.ID, changes, version); err != nil {
+			return err
+		}
+		if err := s.record(ctx, auditModels.ActionOrganizationUpdated, org, updated); err != nil {
+			return err
+		}
+		if s.identity == nil || org.IsLocal() {
+			return nil
+		}
+		// A rejected push rolls the local change back
+		if err := s.identity.UpdateOrganization(ctx, org.ClerkOrgID, params); err != nil {
+			l.Error("failed to update organization in the identity provider", "error", err)
+			return errIdentityProvider
+		}
+		return nil
+	})
+	if err != nil {
+		if params.Slug != nil {
+			err = s.slugConflict(err, *params.Slug)
+		}
+		return &models.Organization{}, err
+	}
+
+	l.Info("organization updated", "org_id", updated.ID)
+	return updated, nil
+}
+
+// DeleteOrganizationAsUser soft-deletes an organization. The caller must be one of its admins.
+func (s *ServiceImpl) DeleteOrganizationAsUser(ctx context.Context, clerkUserID string, id string) error {
+	l := s.log.WithContext(ctx).With("operation", "DeleteOrganizationAsUser")
+
+	org, err := s.adminOrganization(ctx, clerkUserID, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.tx.Do(ctx, func(ctx context.Context) error {
+		if _, err := s.data.DeleteOrganizationByClerkID(ctx, org.ClerkOrgID); err != nil {
+			return err
+		}
+		if err := s.record(ctx, auditModels.ActionOrganizationDeleted, org, nil); err != nil {
+			return err
+		}
+		if s.identity == nil || org.IsLocal() {
+			return nil
+		}
+		if err := s.identity.DeleteOrganization(ctx, org.ClerkOrgID); err != nil {
+			l.Error("failed to delete organization from the identity provider", "error", err)
+			return errIdentityProvider
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	l.Info("organization deleted", "org_id", org.ID)
+	return nil
+}
+
+// adminOrganization returns organization id when the caller is one of its admins.
+// A missing organization fails with gorm.ErrRecordNotFound.
+func (s *ServiceImpl) adminOrganization(ctx context.Context, clerkUserID string, id string) (*models.Organization, error) {
+	org, err := s.data.GetOrganizationByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	caller, err := usersService.Caller(ctx, s.users, clerkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	membership, err := s.memberships.GetMembership(ctx, caller.ID, org.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errNotAdmin
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !membership.IsAdmin() {
+		return nil, errNotAdmin
+	}
+	return org, nil
+}
+
+// freeSlug returns slug when no organization uses it. Otherwise a derived slug
+// is replaced by the first free suggestion, and a requested one is refused.
+func (s *ServiceImpl) freeSlug(ctx context.Context, slug string, derived bool) (string, error) {
+	taken, err := s.data.ListTakenSlugs(ctx, slug)
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(taken, slug) {
+		return slug, nil
+	}
+	if derived {
+		suggestions, err := s.suggestSlugs(ctx, slug, taken, 1)
+		if err != nil {
+			return "", err
+		}
+		return suggestions[0], nil
+	}
+	suggestions, err := s.suggestSlugs(ctx, slug, taken, models.SlugSuggestions)
+	if err != nil {
+		return "", err
+	}
+	return "", &models.SlugTakenError{Slug: slug, Suggestions: suggestions}
+}
+
+// suggestSlugs returns n free variants of slug, given the slugs ListTakenSlugs
+// found taken. Variants of a slug near the length limit truncate it, so they
+// can be taken without sharing its prefix; those are looked up until free.
+func (s *ServiceImpl) suggestSlugs(ctx context.Context, slug string, taken []string, n int) ([]string, error) {
+	for {
+		suggestions := models.SuggestSlugs(slug, taken, n)
+
+		var truncated []string
+		for _, suggestion := range suggestions {
+			if !strings.HasPrefix(suggestion, slug+"-") {
+				truncated = append(truncated, suggestion)
+			}
+		}
+		if len(truncated) == 0 {
+			return suggestions, nil
+		}
+
+		used, err := s.data.FilterTakenSlugs(ctx, truncated)
+		if err != nil {
+			return nil, err
+		}
+		if len(used) == 0 {
+			return suggestions, nil
+		}
+		taken = append(taken, used...)
+	}
+}
+
+// slugConflict turns a unique index conflict, from an organization taking the
+// slug since it was checked, into models.SlugTakenError. The slug is the only
+// unique column the caller sets.
+func (s *ServiceImpl) slugConflict(err error, slug string) error {
+	if isUniqueViolation(err) {
+		return &models.SlugTakenError{Slug: slug}
+	}
+	return err
+}
+
+// isUniqueViolation reports whether err is a unique index conflict
+func isUniqueViolation(err error) bool {
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == uniqueViolation
+}
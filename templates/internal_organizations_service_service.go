@@ -4,13 +4,26 @@ package organizations
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
+	auditModels "{{.Module}}/internal/audit/models"
+	auditService "{{.Module}}/internal/audit/service"
+	membershipsDatasource "{{.Module}}/internal/memberships/datasource"
+	membershipsModels "{{.Module}}/internal/memberships/models"
 	organizationsDatasource "{{.Module}}/internal/organizations/datasource"
+	"{{.Module}}/internal/organizations/identity"
 	"{{.Module}}/internal/organizations/models"
 	"{{.Module}}/internal/shared/assertions"
 	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/pagination"
+	"{{.Module}}/internal/shared/transaction"
 	"{{.Module}}/internal/shared/uuid"
 	"{{.Module}}/internal/shared/validation"
+	usersDatasource "{{.Module}}/internal/users/datasource"
+
+	"gorm.io/gorm"
 )
 
 const (
@@ -23,17 +36,32 @@ type OrganizationsService interface {
 	UpdateOrganization(ctx context.Context, org *models.ClerkOrganizationRequest) (bool, error)
 	GetOrganizationByClerkOrgID(ctx context.Context, clerkOrgID string) (*models.Organization, error)
 	GetOrganizationByID(ctx context.Context, id string) (*models.Organization, error)
+	GetOrganizationBySlug(ctx context.Context, slug string) (*models.Organization, error)
+	CheckSlug(ctx context.Context, slug string) (models.SlugAvailability, error)
+	CreateOrganizationAsUser(ctx context.Context, clerkUserID string, request models.CreateOrganizationRequest) (*models.Organization, error)
+	UpdateOrganizationAsUser(ctx context.Context, clerkUserID string, id string, request models.UpdateOrganizationRequest, version int64) (*models.Organization, error)
+	DeleteOrganizationAsUser(ctx context.Context, clerkUserID string, id string) error
+	ListOrganizations(ctx context.Context, filter models.OrganizationFilter, page pagination.Request) ([]models.Organization, string, error)
 	DeleteOrganization(ctx context.Context, clerkID string) (bool, error)
+	ListDeletedOrganizations(ctx context.Context) ([]models.Organization, error)
+	RestoreOrganization(ctx context.Context, clerkID string) (bool, error)
+	PurgeOrganization(ctx context.Context, clerkID string) (bool, error)
+	PurgeDeletedOrganizations(ctx context.Context, retention time.Duration) (int64, error)
 }
 
 type ServiceImpl struct {
-	log  *logger.Logger
-	data organizationsDatasource.OrganizationsDatasource
+	log         *logger.Logger
+	data        organizationsDatasource.OrganizationsDatasource
+	users       usersDatasource.UsersDatasource
+	memberships membershipsDatasource.MembershipsDatasource
+	tx          *transaction.Manager
+	identity    identity.Provider // nil keeps API changes local
+	audit       auditService.AuditService
 }
 
-func NewService(logger *logger.Logger, datasource organizationsDatasource.OrganizationsDatasource) OrganizationsService {
+func NewService(logger *logger.Logger, datasource organizationsDatasource.OrganizationsDatasource, users usersDatasource.UsersDatasource, memberships membershipsDatasource.MembershipsDatasource, tx *transaction.Manager, provider identity.Provider, audit auditService.AuditService) OrganizationsService {
 	serviceLogger := logger.With("package", pkgName, "layer", layer)
-	return &ServiceImpl{log: serviceLogger, data: datasource}
+	return &ServiceImpl{log: serviceLogger, data: datasource, users: users, memberships: memberships, tx: tx, identity: provider, audit: audit}
 }
 
 func (s *ServiceImpl) CreateOrganization(ctx context.Context, request *models.ClerkOrganizationRequest) (*models.Organization, error) {
@@ -47,7 +75,62 @@ func (s *ServiceImpl) CreateOrganization(ctx context.Context, request *models.Cl
 		return &models.Organization{}, err
 	}
 
-	created, err := s.data.CreateOrganization(ctx, &org)
+	// The organization, its creator's membership and the audit event are saved
+	// together or not at all
+	var created *models.Organization
+	save := func(ctx context.Context) error {
+		// Organizations created through the API are already stored; update them instead
+		existing, err := s.data.GetOrganizationByClerkOrgID(ctx, org.ClerkOrgID)
+		if err == nil {
+			l.Debug("organization already exists, updating it", "org_id", existing.ID)
+			org.ID = existing.ID
+			if _, err := s.data.UpdateOrganization(ctx, &org); err != nil {
+				return err
+			}
+			if created, err = s.data.GetOrganizationByClerkOrgID(ctx, org.ClerkOrgID); err != nil {
+				return err
+			}
+			return s.record(ctx, auditModels.ActionOrganizationUpdated, existing, created)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		created, err = s.data.CreateOrganization(ctx, &org)
+		if err != nil {
+			return err
+		}
+		if err := s.record(ctx, auditModels.ActionOrganizationCreated, nil, created); err != nil {
+			return err
+		}
+		if request.Data.CreatedBy == "" {
+			return nil
+		}
+
+		creator, err := s.users.GetUserByClerkUserID(ctx, request.Data.CreatedBy)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// The user.created webhook may arrive after this one; the creator's
+			// membership webhook then makes them admin
+			l.Debug("organization creator not found", "clerk_user_id", request.Data.CreatedBy)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.addAdmin(ctx, creator.ID, created.ID, created.CreatedAt); err != nil {
+			return err
+		}
+		_, err = s.users.UpdateUserOrganization(ctx, request.Data.CreatedBy, created.ID)
+		return err
+	}
+	err := s.tx.Do(ctx, save)
+	if isUniqueViolation(err) {
+		// An organization created through the API holds its slug until it has
+		// been pushed and committed, and this insert waited for it. Now
+		// committed, it is found by its Clerk ID and updated.
+		l.Debug("organization stored concurrently, saving it again", "clerk_org_id", org.ClerkOrgID)
+		err = s.tx.Do(ctx, save)
+	}
 	if err != nil {
 		return &models.Organization{}, err
 	}
@@ -64,7 +147,26 @@ func (s *ServiceImpl) UpdateOrganization(ctx context.Context, request *models.Cl
 		return false, err
 	}
 
-	updated, err := s.data.UpdateOrganization(ctx, &org)
+	var updated bool
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		before, err := s.data.GetOrganizationByClerkOrgID(ctx, org.ClerkOrgID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			updated = false
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if updated, err = s.data.UpdateOrganization(ctx, &org); err != nil || !updated {
+			return err
+		}
+		after, err := s.data.GetOrganizationByClerkOrgID(ctx, org.ClerkOrgID)
+		if err != nil {
+			return err
+		}
+		return s.record(ctx, auditModels.ActionOrganizationUpdated, before, after)
+	})
 	if err != nil {
 		return false, err
 	}
@@ -104,6 +206,10 @@ func (s *ServiceImpl) GetOrganizationByID(ctx context.Context, id string) (*mode
 	return org, nil
 }
 
+func (s *ServiceImpl) ListOrganizations(ctx context.Context, filter models.OrganizationFilter, page pagination.Request) ([]models.Organization, string, error) {
+	return s.data.ListOrganizations(ctx, filter, page)
+}
+
 func (s *ServiceImpl) DeleteOrganization(ctx context.Context, clerkID string) (bool, error) {
 	l := s.log.WithContext(ctx).With("operation", "DeleteOrganization")
 
@@ -112,10 +218,139 @@ func (s *ServiceImpl) DeleteOrganization(ctx context.Context, clerkID string) (b
 		return false, err
 	}
 
-	deleted, err := s.data.DeleteOrganizationByClerkID(ctx, clerkID)
+	var deleted bool
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		before, err := s.data.GetOrganizationByClerkOrgID(ctx, clerkID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			deleted = false
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if deleted, err = s.data.DeleteOrganizationByClerkID(ctx, clerkID); err != nil || !deleted {
+			return err
+		}
+		return s.record(ctx, auditModels.ActionOrganizationDeleted, before, nil)
+	})
 	if err != nil {
 		return false, err
 	}
 
 	return deleted, nil
 }
+
+func (s *ServiceImpl) ListDeletedOrganizations(ctx context.Context) ([]models.Organization, error) {
+	return s.data.ListDeletedOrganizations(ctx)
+}
+
+func (s *ServiceImpl) RestoreOrganization(ctx context.Context, clerkID string) (bool, error) {
+	l := s.log.WithContext(ctx).With("operation", "RestoreOrganization")
+
+	if err := assertions.AssertNonEmptyString(clerkID); err != nil {
+		l.Debug("failed to validate clerk org id", "error", err)
+		return false, err
+	}
+
+	var restored bool
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		before, err := s.data.GetDeletedOrganizationByClerkID(ctx, clerkID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			restored = false
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if restored, err = s.data.RestoreOrganizationByClerkID(ctx, clerkID); err != nil || !restored {
+			return err
+		}
+		after, err := s.data.GetOrganizationByClerkOrgID(ctx, clerkID)
+		if err != nil {
+			return err
+		}
+		return s.record(ctx, auditModels.ActionOrganizationRestored, before, after)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return restored, nil
+}
+
+func (s *ServiceImpl) PurgeOrganization(ctx context.Context, clerkID string) (bool, error) {
+	l := s.log.WithContext(ctx).With("operation", "PurgeOrganization")
+
+	if err := assertions.AssertNonEmptyString(clerkID); err != nil {
+		l.Debug("failed to validate clerk org id", "error", err)
+		return false, err
+	}
+
+	var purged bool
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		before, err := s.data.GetDeletedOrganizationByClerkID(ctx, clerkID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			purged = false
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if purged, err = s.data.PurgeOrganizationByClerkID(ctx, clerkID); err != nil || !purged {
+			return err
+		}
+		return s.record(ctx, auditModels.ActionOrganizationPurged, before, nil)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return purged, nil
+}
+
+// PurgeDeletedOrganizations permanently deletes the organizations soft-deleted
+// longer than retention ago. The purge isn't audited per organization; each
+// deletion already was.
+func (s *ServiceImpl) PurgeDeletedOrganizations(ctx context.Context, retention time.Duration) (int64, error) {
+	l := s.log.WithContext(ctx).With("operation", "PurgeDeletedOrganizations")
+
+	if retention <= 0 {
+		l.Debug("invalid retention", "retention", retention.String())
+		return 0, fmt.Errorf("retention must be positive, got %s", retention)
+	}
+
+	return s.data.PurgeDeletedOrganizationsBefore(ctx, time.Now().Add(-retention))
+}
+
+// record audits a change to an organization; before is nil for a created
+// organization and after for a deleted one
+func (s *ServiceImpl) record(ctx context.Context, action string, before, after *models.Organization) error {
+	entry := auditModels.Entry{Action: action, ResourceType: auditModels.ResourceOrganization}
+	if before != nil {
+		entry.Before = before
+		entry.ResourceID = before.ID
+	}
+	if after != nil {
+		entry.After = after
+		entry.ResourceID = after.ID
+	}
+	return s.audit.Record(ctx, entry)
+}
+
+// addAdmin makes user userID an admin of organization orgID. The Clerk
+// membership webhook replaces the placeholder Clerk membership ID.
+func (s *ServiceImpl) addAdmin(ctx context.Context, userID string, orgID string, joinedAt time.Time) error {
+	membershipID := uuid.GenerateNamespaceUUID("mem")
+	_, err := s.memberships.UpsertMembership(ctx, &membershipsModels.Membership{
+		ID:                membershipID,
+		ClerkMembershipID: membershipID,
+		UserID:            userID,
+		OrganizationID:    orgID,
+		Role:              membershipsModels.RoleAdmin,
+		JoinedAt:          joinedAt,
+	})
+	return err
+}
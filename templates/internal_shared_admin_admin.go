@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+	"time"
+
+	"{{.Module}}/internal/shared/buildinfo"
+	httpHelpers "{{.Module}}/internal/shared/http"
+)
+
+// Options configures the admin handler.
+type Options struct {
+	// Token must be sent as "Authorization: Bearer <token>" or X-Admin-Token
+	Token string
+	// DBStats reports the connection pool; nil omits database stats
+	DBStats func() sql.DBStats
+	// API serves the admin endpoints under /admin/; nil leaves them out
+	API http.Handler
+}
+
+// RuntimeStats is the body of /debug/runtime.
+type RuntimeStats struct {
+	Goroutines int            `json:"goroutines"`
+	GoMaxProcs int            `json:"gomaxprocs"`
+	NumCPU     int            `json:"num_cpu"`
+	Uptime     string         `json:"uptime"`
+	Memory     MemoryStats    `json:"memory"`
+	Database   *DatabaseStats `json:"database,omitempty"`
+	Build      buildinfo.Info `json:"build"`
+}
+
+type MemoryStats struct {
+	AllocBytes     uint64 `json:"alloc_bytes"`
+	HeapInuseBytes uint64 `json:"heap_inuse_bytes"`
+	SysBytes       uint64 `json:"sys_bytes"`
+	NumGC          uint32 `json:"num_gc"`
+	PauseTotal     string `json:"pause_total"`
+}
+
+type DatabaseStats struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64  `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+}
+
+// NewHandler serves pprof, expvar and runtime stats behind the admin token:
+//
+//	/debug/pprof/   profiles (net/http/pprof)
+//	/debug/vars     expvar
+//	/debug/runtime  goroutines, memory, connection pool and build info as JSON
+//	/admin/         opts.API, e.g. soft-deleted record management
+func NewHandler(opts Options) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/debug/runtime", httpHelpers.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return httpHelpers.RespondWithJSON(w, http.StatusOK, CollectRuntimeStats(opts.DBStats))
+	}))
+	if opts.API != nil {
+		mux.Handle("/admin/", opts.API)
+	}
+
+	return requireToken(opts.Token, mux)
+}
+
+// CollectRuntimeStats snapshots the process; dbStats may be nil.
+func CollectRuntimeStats(dbStats func() sql.DBStats) RuntimeStats {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	stats := RuntimeStats{
+		Goroutines: runtime.NumGoroutine(),
+		GoMaxProcs: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		Uptime:     buildinfo.Uptime().Round(time.Second).String(),
+		Memory: MemoryStats{
+			AllocBytes:     memory.Alloc,
+			HeapInuseBytes: memory.HeapInuse,
+			SysBytes:       memory.Sys,
+			NumGC:          memory.NumGC,
+			PauseTotal:     time.Duration(memory.PauseTotalNs).String(),
+		},
+		Build: buildinfo.Get(),
+	}
+
+	if dbStats != nil {
+		pool := dbStats()
+		stats.Database = &DatabaseStats{
+			MaxOpenConnections: pool.MaxOpenConnections,
+			OpenConnections:    pool.OpenConnections,
+			InUse:              pool.InUse,
+			Idle:               pool.Idle,
+			WaitCount:          pool.WaitCount,
+			WaitDuration:       pool.WaitDuration.String(),
+			MaxIdleClosed:      pool.MaxIdleClosed,
+			MaxIdleTimeClosed:  pool.MaxIdleTimeClosed,
+			MaxLifetimeClosed:  pool.MaxLifetimeClosed,
+		}
+	}
+	return stats
+}
+
+// requireToken rejects requests without the admin token, compared in constant time.
+// An empty token rejects everything.
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided := r.Header.Get("X-Admin-Token")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			provided = bearer
+		}
+
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "admin token required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
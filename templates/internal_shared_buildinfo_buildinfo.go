@@ -0,0 +1,84 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Set at build time, e.g. `make build`:
+//
+//	go build -ldflags "-X {{.Module}}/internal/shared/buildinfo.Version=v1.2.0 \
+//	  -X {{.Module}}/internal/shared/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X {{.Module}}/internal/shared/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Version and Commit left empty fall back to the module and VCS info embedded by
+// the Go toolchain. The toolchain records no build time, so BuildTime stays
+// Unknown unless injected.
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+// Unknown is reported for values neither injected nor embedded.
+const Unknown = "unknown"
+
+// Info describes the running binary.
+type Info struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit"`
+	CommitTime string `json:"commit_time"` // when Commit was made, which can be long before the build
+	BuildTime  string `json:"build_time"`
+	GoVersion  string `json:"go_version"`
+	Modified   bool   `json:"modified,omitempty"` // built from a dirty working tree
+}
+
+var startTime = time.Now()
+
+// Get returns the injected build metadata, filling gaps from debug.ReadBuildInfo.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if embedded, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && embedded.Main.Version != "" && embedded.Main.Version != "(devel)" {
+			info.Version = embedded.Main.Version
+		}
+		for _, setting := range embedded.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				info.CommitTime = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = Unknown
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = Unknown
+	}
+	if info.CommitTime == "" {
+		info.CommitTime = Unknown
+	}
+	return info
+}
+
+// Uptime returns how long the process has been running.
+func Uptime() time.Duration {
+	return time.Since(startTime)
+}
@@ -0,0 +1,73 @@
+package concurrency
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	httpHelpers "{{.Module}}/internal/shared/http"
+)
+
+// ErrVersionConflict is returned by a conditional update when the row was
+// changed after the expected version was read. Reads are tagged with an ETag of
+// the row version, and writes sent with If-Match only apply to that version.
+var ErrVersionConflict = httpHelpers.NewError(http.StatusPreconditionFailed, "the resource was modified since it was read, fetch it again")
+
+var errMultipleIfMatch = httpHelpers.NewError(http.StatusBadRequest, "If-Match must hold a single ETag or *")
+
+// ETag returns the strong entity tag of a row version
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// SetETag sets the ETag response header to the tag of version
+func SetETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", ETag(version))
+}
+
+// IfMatch returns the version required by the If-Match header of r, or 0 when
+// any version will do: no header, or *. A tag this service didn't issue,
+// including a weak one, can never match and fails with ErrVersionConflict.
+func IfMatch(r *http.Request) (int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, errMultipleIfMatch
+	}
+
+	version, ok := parse(header)
+	if !ok {
+		return 0, ErrVersionConflict
+	}
+	return version, nil
+}
+
+// NotModified sets the ETag of version on w and reports whether the
+// If-None-Match header of r already names it, in which case the caller answers
+// 304 without a body. Tags are compared weakly, as RFC 9110 requires.
+func NotModified(w http.ResponseWriter, r *http.Request, version int64) bool {
+	SetETag(w, version)
+
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	etag := ETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func parse(tag string) (int64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	return version, err == nil && version > 0
+}
@@ -49,4 +49,8 @@ const (
 	WebhookEventOrganizationCreated WebhookEventType = "organization.created"
 	WebhookEventOrganizationUpdated WebhookEventType = "organization.updated"
 	WebhookEventOrganizationDeleted WebhookEventType = "organization.deleted"
+
+	WebhookEventMembershipCreated WebhookEventType = "organizationMembership.created"
+	WebhookEventMembershipUpdated WebhookEventType = "organizationMembership.updated"
+	WebhookEventMembershipDeleted WebhookEventType = "organizationMembership.deleted"
 )
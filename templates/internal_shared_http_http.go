@@ -2,9 +2,25 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 )
 
+// Error carries the HTTP status RespondWithError answers with.
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError returns an error answered with status, e.g. http.StatusBadRequest.
+func NewError(status int, message string) error {
+	return &Error{Status: status, Message: message}
+}
+
 // RespondWithJSON sends a JSON response
 func RespondWithJSON(w http.ResponseWriter, statusCode int, data interface{}) error {
 	w.Header().Set("Content-Type", "application/json")
@@ -19,8 +35,10 @@ func RespondWithError(w http.ResponseWriter, err error) error {
 	// Default to 500 if no specific status code is set
 	statusCode := http.StatusInternalServerError
 
-	// You can extend this to handle different error types
-	// and set appropriate status codes
+	var httpErr *Error
+	if errors.As(err, &httpErr) {
+		statusCode = httpErr.Status
+	}
 
 	w.WriteHeader(statusCode)
 
@@ -0,0 +1,451 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"{{.Module}}/internal/shared/logger"
+)
+
+const (
+	DefaultStartTimeout = 30 * time.Second
+	DefaultStopTimeout  = 30 * time.Second
+)
+
+var (
+	ErrDuplicateComponent = errors.New("component already registered")
+	ErrUnknownDependency  = errors.New("component depends on an unregistered component")
+	ErrDependencyCycle    = errors.New("component dependencies form a cycle")
+	ErrAlreadyStarted     = errors.New("lifecycle already started")
+	ErrShutdownTimeout    = errors.New("shutdown deadline exceeded")
+)
+
+// Hook starts or stops a component. It must return once the component is
+// running (or stopped); long-running work belongs in a goroutine that reports
+// failures through Manager.Fail.
+type Hook func(ctx context.Context) error
+
+// Component is a unit of the application with ordered start and stop hooks.
+// Stop only runs after Start succeeded, so a Start hook that fails must release
+// whatever it acquired before failing.
+type Component struct {
+	Name         string
+	DependsOn    []string
+	Start        Hook
+	Stop         Hook
+	StartTimeout time.Duration // defaults to DefaultStartTimeout
+	StopTimeout  time.Duration // defaults to DefaultStopTimeout
+}
+
+// Options controls how Run shuts the application down.
+type Options struct {
+	// DrainDelay is how long Ready reports false before components are stopped,
+	// giving load balancers time to stop routing new requests. A second signal
+	// cuts it short.
+	DrainDelay time.Duration
+	// ShutdownTimeout bounds the whole shutdown, DrainDelay included; defaults
+	// to DefaultStopTimeout
+	ShutdownTimeout time.Duration
+}
+
+// Manager starts components after their dependencies and stops them in reverse.
+type Manager struct {
+	log  *logger.Logger
+	opts Options
+
+	mu         sync.Mutex
+	components []Component
+	started    []Component
+	running    bool
+	ready      atomic.Bool
+
+	requests   *Tracker
+	workers    *Tracker
+	workCtx    context.Context
+	cancelWork context.CancelFunc
+
+	failed   chan error
+	failOnce sync.Once
+}
+
+func NewManager(logger *logger.Logger, opts Options) *Manager {
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = DefaultStopTimeout
+	}
+
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	return &Manager{
+		log:        logger.With("component", "lifecycle"),
+		opts:       opts,
+		requests:   NewTracker(),
+		workers:    NewTracker(),
+		workCtx:    workCtx,
+		cancelWork: cancelWork,
+		failed:     make(chan error, 1),
+	}
+}
+
+// Ready reports whether every component has started and shutdown has not begun.
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+// Requests tracks in-flight requests so a forced shutdown can report them.
+func (m *Manager) Requests() *Tracker {
+	return m.requests
+}
+
+// Go runs fn as tracked background work. Its context is cancelled when shutdown
+// begins, and shutdown waits for fn to return before stopping components.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	done := m.workers.Track(name)
+	go func() {
+		defer done()
+		fn(m.workCtx)
+	}()
+}
+
+// Register adds a component. Components may be registered in any order; the
+// start order is resolved from DependsOn when Start is called.
+func (m *Manager) Register(component Component) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running {
+		return ErrAlreadyStarted
+	}
+	for _, existing := range m.components {
+		if existing.Name == component.Name {
+			return fmt.Errorf("%w: %s", ErrDuplicateComponent, component.Name)
+		}
+	}
+
+	m.components = append(m.components, component)
+	return nil
+}
+
+// Fail reports that a running component failed, which makes Run stop the application.
+// Only the first failure is kept.
+func (m *Manager) Fail(name string, err error) {
+	m.failOnce.Do(func() {
+		m.failed <- fmt.Errorf("%s failed: %w", name, err)
+	})
+}
+
+// Start starts every component after its dependencies. If a component fails to
+// start, background work is cancelled and awaited and the components already
+// started are stopped in reverse order, all within ShutdownTimeout.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	if m.running {
+		m.mu.Unlock()
+		return ErrAlreadyStarted
+	}
+	order, err := m.startOrder()
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	m.running = true
+	m.mu.Unlock()
+
+	for _, component := range order {
+		if err := m.startComponent(ctx, component); err != nil {
+			m.log.Error("component failed to start, rolling back", "name", component.Name, "error", err)
+			rollbackCtx, cancel := context.WithTimeout(context.Background(), m.opts.ShutdownTimeout)
+			defer cancel()
+			if stopErr := m.shutdown(rollbackCtx); stopErr != nil {
+				err = errors.Join(err, stopErr)
+			}
+			return err
+		}
+
+		m.mu.Lock()
+		m.started = append(m.started, component)
+		m.mu.Unlock()
+	}
+
+	m.ready.Store(true)
+	m.log.Info("all components started", "count", len(order))
+	return nil
+}
+
+// Stop stops every started component in reverse start order, continuing past
+// failures and returning them joined.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	started := m.started
+	m.started = nil
+	m.running = false
+	m.mu.Unlock()
+	m.ready.Store(false)
+
+	var stopErrors []error
+	for i := len(started) - 1; i >= 0; i-- {
+		if err := m.stopComponent(ctx, started[i]); err != nil {
+			m.log.Error("component failed to stop", "name", started[i].Name, "error", err)
+			stopErrors = append(stopErrors, err)
+		}
+	}
+	return errors.Join(stopErrors...)
+}
+
+// Run starts every component, waits for SIGINT, SIGTERM, ctx cancellation or a
+// component failure, then shuts down: readiness drops for DrainDelay or until a
+// second signal, background work is cancelled and awaited, and components are
+// stopped. If ShutdownTimeout passes first, whatever is still running is logged
+// and ErrShutdownTimeout returned so the caller can exit.
+func (m *Manager) Run(ctx context.Context) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := m.Start(ctx); err != nil {
+		return err
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		m.log.Info("shutdown requested")
+	case runErr = <-m.failed:
+		m.log.Error("component failure, shutting down", "error", runErr)
+	}
+
+	m.ready.Store(false)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), m.opts.ShutdownTimeout)
+	defer cancelShutdown()
+
+	if m.opts.DrainDelay > 0 && runErr == nil {
+		m.drain(shutdownCtx)
+	}
+
+	err := m.shutdown(shutdownCtx)
+	if shutdownCtx.Err() != nil {
+		m.log.Error("shutdown deadline exceeded, forcing exit",
+			"timeout", m.opts.ShutdownTimeout.String(),
+			"error", err,
+			"in_flight_requests", m.requests.Active(),
+			"background_work", m.workers.Active(),
+		)
+		return errors.Join(runErr, ErrShutdownTimeout)
+	}
+	return errors.Join(runErr, err)
+}
+
+// drain waits DrainDelay before shutdown proceeds, or less if a second signal
+// arrives or ctx is done.
+func (m *Manager) drain(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	timer := time.NewTimer(m.opts.DrainDelay)
+	defer timer.Stop()
+
+	m.log.Info("draining before shutdown", "delay", m.opts.DrainDelay.String(), "in_flight_requests", m.requests.Count())
+	select {
+	case <-timer.C:
+	case sig := <-signals:
+		m.log.Warn("second signal, skipping the rest of the drain", "signal", sig.String())
+	case <-ctx.Done():
+		m.log.Warn("shutdown deadline reached while draining")
+	}
+}
+
+// shutdown cancels background work, waits for it, then stops every component.
+func (m *Manager) shutdown(ctx context.Context) error {
+	m.cancelWork()
+
+	var errs []error
+	if err := m.workers.Wait(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("waiting for background work: %w", err))
+	}
+	return errors.Join(append(errs, m.Stop(ctx))...)
+}
+
+// startOrder sorts components so each one follows its dependencies, keeping
+// registration order where dependencies allow.
+func (m *Manager) startOrder() ([]Component, error) {
+	byName := make(map[string]Component, len(m.components))
+	for _, component := range m.components {
+		byName[component.Name] = component
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(m.components))
+	order := make([]Component, 0, len(m.components))
+
+	var visit func(component Component, path []string) error
+	visit = func(component Component, path []string) error {
+		switch state[component.Name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %v", ErrDependencyCycle, append(path, component.Name))
+		}
+
+		state[component.Name] = visiting
+		for _, name := range component.DependsOn {
+			dependency, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, component.Name, name)
+			}
+			if err := visit(dependency, append(path, component.Name)); err != nil {
+				return err
+			}
+		}
+		state[component.Name] = visited
+		order = append(order, component)
+		return nil
+	}
+
+	for _, component := range m.components {
+		if err := visit(component, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+func (m *Manager) startComponent(ctx context.Context, component Component) error {
+	if component.Start == nil {
+		return nil
+	}
+
+	timeout := component.StartTimeout
+	if timeout <= 0 {
+		timeout = DefaultStartTimeout
+	}
+
+	begin := time.Now()
+	if err := runHook(ctx, component.Start, timeout); err != nil {
+		return fmt.Errorf("start %s: %w", component.Name, err)
+	}
+	m.log.Info("component started", "name", component.Name, "duration", time.Since(begin).String())
+	return nil
+}
+
+func (m *Manager) stopComponent(ctx context.Context, component Component) error {
+	if component.Stop == nil {
+		return nil
+	}
+
+	timeout := component.StopTimeout
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
+	}
+
+	begin := time.Now()
+	if err := runHook(ctx, component.Stop, timeout); err != nil {
+		return fmt.Errorf("stop %s: %w", component.Name, err)
+	}
+	m.log.Info("component stopped", "name", component.Name, "duration", time.Since(begin).String())
+	return nil
+}
+
+// runHook calls hook with a deadline and gives up waiting once it passes, so a
+// hook that ignores its context cannot block the whole lifecycle.
+func runHook(ctx context.Context, hook Hook, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- hook(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s: %w", timeout, ctx.Err())
+	}
+}
+
+// Tracker counts running work by name so shutdown can wait for it and report
+// what is left.
+type Tracker struct {
+	mu     sync.Mutex
+	active map[string]int
+	total  int
+	idle   chan struct{} // closed when total drops back to zero
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{active: make(map[string]int)}
+}
+
+// Track records one unit of work and returns the function that ends it.
+func (t *Tracker) Track(name string) (done func()) {
+	t.mu.Lock()
+	if t.total == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.total++
+	t.active[name]++
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+
+			t.total--
+			if t.active[name]--; t.active[name] == 0 {
+				delete(t.active, name)
+			}
+			if t.total == 0 {
+				close(t.idle)
+			}
+		})
+	}
+}
+
+// Count returns how much work is running.
+func (t *Tracker) Count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+// Active returns how many units of each name are running.
+func (t *Tracker) Active() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	active := make(map[string]int, len(t.active))
+	for name, count := range t.active {
+		active[name] = count
+	}
+	return active
+}
+
+// Wait blocks until no work is running or ctx is done.
+func (t *Tracker) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	if t.total == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -54,6 +54,37 @@ type Config struct {
 	Service   string
 	Version   string
 	Writer    io.Writer
+
+	// levelVar holds the active level so it can be changed without rebuilding the logger
+	levelVar *slog.LevelVar
+}
+
+// leveler returns the shared LevelVar for this config, creating it from Level on first use
+func (c *Config) leveler() *slog.LevelVar {
+	if c.levelVar == nil {
+		c.levelVar = new(slog.LevelVar)
+		c.levelVar.Set(c.Level)
+	}
+	return c.levelVar
+}
+
+// ParseLevel converts a level name such as "debug", "info", "warn" or "error" to a slog.Level
+func ParseLevel(level string) (slog.Level, error) {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo, err
+	}
+	return parsed, nil
+}
+
+// SetLevel changes the minimum level of this logger and every logger derived from it
+func (l *Logger) SetLevel(level slog.Level) {
+	l.config.leveler().Set(level)
+}
+
+// Level returns the active minimum level
+func (l *Logger) Level() slog.Level {
+	return l.config.leveler().Level()
 }
 
 func DefaultConfig() *Config {
@@ -84,7 +115,7 @@ func Initialize(config *Config) {
 	}
 
 	opts := &slog.HandlerOptions{
-		Level:     config.Level,
+		Level:     config.leveler(),
 		AddSource: config.AddSource,
 		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
 
@@ -133,7 +164,7 @@ func NewLogger(config *Config) *Logger {
 	}
 
 	opts := &slog.HandlerOptions{
-		Level:     config.Level,
+		Level:     config.leveler(),
 		AddSource: config.AddSource,
 	}
 
@@ -156,6 +187,7 @@ const (
 	RequestIDKey contextKey = "request_id"
 	UserIDKey    contextKey = "user_id"
 	SessionIDKey contextKey = "session_id"
+	ClientIPKey  contextKey = "client_ip" // not added to log lines, read by the audit trail
 )
 
 func (l *Logger) WithTraceID(traceID string) *Logger {
@@ -305,7 +337,7 @@ func (l *Logger) ErrorWithStack(msg string, err error, fields ...interface{}) {
 	args := []interface{}{"error", err.Error()}
 	args = append(args, fields...)
 
-	if l.config.Level <= slog.LevelDebug {
+	if l.Level() <= slog.LevelDebug {
 		stack := make([]byte, 4096)
 		length := runtime.Stack(stack, false)
 		args = append(args, "stack_trace", string(stack[:length]))
@@ -434,6 +466,14 @@ func ContextWithUserID(ctx context.Context, userID interface{}) context.Context
 	return context.WithValue(ctx, UserIDKey, userID)
 }
 
+func ContextWithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, SessionIDKey, sessionID)
+}
+
+func ContextWithClientIP(ctx context.Context, clientIP string) context.Context {
+	return context.WithValue(ctx, ClientIPKey, clientIP)
+}
+
 type ErrorDetails struct {
 	Code    string                 `json:"code"`
 	Message string                 `json:"message"`
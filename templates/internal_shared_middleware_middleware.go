@@ -1,24 +1,44 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/replicas"
+	"{{.Module}}/internal/shared/uuid"
 
 	"github.com/clerkinc/clerk-sdk-go/clerk"
 	"github.com/gorilla/csrf"
+	"github.com/rs/cors"
 	"golang.org/x/time/rate"
 )
 
 type Middleware struct {
 	ClerkClient clerk.Client
 	ClerkSecret string
-	RateLimiter *rate.Limiter
+	// ClerkWebhookSecret is the whsec_ secret used to verify Clerk webhook signatures
+	ClerkWebhookSecret string
+	RateLimiter        *rate.Limiter
+
+	// security and cors hold the active settings so they can be swapped on config reload
+	security atomic.Pointer[SecurityConfig]
+	cors     atomic.Pointer[corsPolicy]
 }
 
 type SecurityConfig struct {
@@ -30,6 +50,29 @@ type SecurityConfig struct {
 	PermissionsPolicy  string
 }
 
+type CORSConfig struct {
+	AllowedOrigins   []string // exact origins, "*", or subdomain patterns such as https://*.example.com
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	MaxAge           int // seconds
+	AllowCredentials bool
+
+	// Routes overrides the policy for requests whose path starts with the key
+	Routes map[string]CORSConfig
+}
+
+// corsPolicy is a compiled CORSConfig; a nil handler refuses cross-origin requests
+type corsPolicy struct {
+	handler *cors.Cors
+	routes  []corsRoute
+}
+
+type corsRoute struct {
+	prefix  string
+	handler *cors.Cors
+}
+
 type RequestLimitsConfig struct {
 	MaxRequestSize    int64
 	MaxHeaderSize     int64
@@ -76,6 +119,148 @@ func (m *Middleware) LoggerMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern bounds the request IDs accepted from callers, which end up in logs and audit events
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestContextMiddleware puts the request ID and client IP in the request
+// context. A valid X-Request-ID header is kept, otherwise one is generated, and
+// the ID is echoed in the response. The client IP is the peer address; set up
+// the proxy in front of the service to keep it meaningful.
+func (m *Middleware) RequestContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.GenerateNamespaceUUID("req")
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			clientIP = r.RemoteAddr
+		}
+
+		ctx := logger.ContextWithRequestID(r.Context(), requestID)
+		ctx = logger.ContextWithClientIP(ctx, clientIP)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// SetRateLimit changes the sustained requests per second and burst size of the rate limiter
+func (m *Middleware) SetRateLimit(requestsPerSecond float64, burst int) {
+	m.RateLimiter.SetLimit(rate.Limit(requestsPerSecond))
+	m.RateLimiter.SetBurst(burst)
+}
+
+// SetSecurityConfig replaces the headers written by SecurityHeadersMiddleware
+func (m *Middleware) SetSecurityConfig(config SecurityConfig) {
+	m.security.Store(&config)
+}
+
+// SetCORSConfig replaces the policy enforced by CORSMiddleware
+func (m *Middleware) SetCORSConfig(config CORSConfig) {
+	policy := &corsPolicy{handler: newCORSHandler(config)}
+	for prefix, route := range config.Routes {
+		policy.routes = append(policy.routes, corsRoute{prefix: prefix, handler: newCORSHandler(route)})
+	}
+	// Longest prefix first so nested route groups win over their parents
+	sort.Slice(policy.routes, func(i, j int) bool {
+		return len(policy.routes[i].prefix) > len(policy.routes[j].prefix)
+	})
+	m.cors.Store(policy)
+}
+
+func newCORSHandler(config CORSConfig) *cors.Cors {
+	// rs/cors treats an empty origin list as "*", so keep it empty to refuse every origin
+	if len(config.AllowedOrigins) == 0 {
+		return nil
+	}
+	return cors.New(cors.Options{
+		AllowedOrigins:   config.AllowedOrigins,
+		AllowedMethods:   config.AllowedMethods,
+		AllowedHeaders:   config.AllowedHeaders,
+		ExposedHeaders:   config.ExposedHeaders,
+		MaxAge:           config.MaxAge,
+		AllowCredentials: config.AllowCredentials,
+	})
+}
+
+// CORSMiddleware applies the active CORS policy for the request path, passing
+// requests through without CORS headers until one is set
+func (m *Middleware) CORSMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy := m.cors.Load()
+		if policy == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		handler := policy.handler
+		for _, route := range policy.routes {
+			if strings.HasPrefix(r.URL.Path, route.prefix) {
+				handler = route.handler
+				break
+			}
+		}
+
+		if handler == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r, next.ServeHTTP)
+	})
+}
+
+// RequestTracker records in-flight requests; Track returns the function that ends one
+type RequestTracker interface {
+	Track(name string) (done func())
+}
+
+// InFlightMiddleware tracks every request until its handler returns
+func (m *Middleware) InFlightMiddleware(tracker RequestTracker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			done := tracker.Track(r.Method + " " + r.URL.Path)
+			defer done()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DatabaseMiddleware answers 503 while available reports the database as unreachable,
+// so routes that need it fail fast in degraded mode. A nil available passes everything.
+func (m *Middleware) DatabaseMiddleware(available func() bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if available == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !available() {
+				w.Header().Set("Retry-After", "5")
+				http.Error(w, "Database unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// PrimaryReadsMiddleware sends every query of a POST, PUT, PATCH or DELETE request
+// to the primary, so reads following the request's writes see them even when
+// read replicas lag behind.
+func (m *Middleware) PrimaryReadsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+		default:
+			next.ServeHTTP(w, r.WithContext(replicas.WithPrimary(r.Context())))
+		}
+	})
+}
+
 // RateLimiterMiddleware implements rate limiting
 func (m *Middleware) RateLimiterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -87,7 +272,11 @@ func (m *Middleware) RateLimiterMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// ClerkAuthMiddleware validates Clerk authentication
+// sessionClaimsKey carries the verified Clerk session claims
+type sessionClaimsKey struct{}
+
+// ClerkAuthMiddleware verifies the Clerk session token sent as a bearer token and
+// puts the caller's Clerk user ID and session claims in the request context.
 func (m *Middleware) ClerkAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -103,43 +292,140 @@ func (m *Middleware) ClerkAuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		_ = tokenParts[1] // token variable
+		if m.ClerkClient == nil {
+			http.Error(w, "Authentication unavailable", http.StatusServiceUnavailable)
+			return
+		}
 
-		// Verify the token with Clerk
-		// Note: This is a simplified implementation. In production, you should use proper Clerk verification
-		// For now, we'll just pass through the token
-		ctx := context.WithValue(r.Context(), "user_id", "user_from_token")
-		ctx = context.WithValue(ctx, "session_id", "session_from_token")
+		// Checks the signature against the instance's JWKS, expiry and not-before
+		claims, err := m.ClerkClient.VerifyToken(tokenParts[1])
+		if err != nil || claims.Subject == "" {
+			logger.GetLogger().WithContext(r.Context()).Debug("session token rejected", "error", err)
+			http.Error(w, "Invalid or expired session token", http.StatusUnauthorized)
+			return
+		}
 
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(ContextWithSessionClaims(r.Context(), claims)))
 	})
 }
 
-// ClerkWebhookMiddleware validates Clerk webhook signatures
+// ClerkUserID returns the Clerk user ID of the caller authenticated by ClerkAuthMiddleware.
+func ClerkUserID(ctx context.Context) (string, bool) {
+	claims, ok := SessionClaims(ctx)
+	if !ok {
+		return "", false
+	}
+	return claims.Subject, true
+}
+
+// SessionClaims returns the session claims verified by ClerkAuthMiddleware.
+func SessionClaims(ctx context.Context) (*clerk.SessionClaims, bool) {
+	claims, ok := ctx.Value(sessionClaimsKey{}).(*clerk.SessionClaims)
+	return claims, ok && claims != nil
+}
+
+// ContextWithSessionClaims stores verified claims for ClerkUserID and the request logger.
+func ContextWithSessionClaims(ctx context.Context, claims *clerk.SessionClaims) context.Context {
+	ctx = logger.ContextWithUserID(ctx, claims.Subject)
+	ctx = logger.ContextWithSessionID(ctx, claims.SessionID)
+	return context.WithValue(ctx, sessionClaimsKey{}, claims)
+}
+
+// webhookTolerance bounds how far svix-timestamp may be from now, so captured webhooks can't be replayed later
+const webhookTolerance = 5 * time.Minute
+
+// ClerkWebhookMiddleware verifies the Svix signature of Clerk webhooks, since their
+// membership and role data is later used for authorization
 func (m *Middleware) ClerkWebhookMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := webhookKey(m.ClerkWebhookSecret)
+		if err != nil {
+			logger.GetLogger().WithContext(r.Context()).Error("clerk webhook refused", "error", err)
+			http.Error(w, "Webhook verification unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		id := r.Header.Get("svix-id")
+		timestamp := r.Header.Get("svix-timestamp")
 		signature := r.Header.Get("svix-signature")
 		if signature == "" {
 			http.Error(w, "Missing svix-signature header", http.StatusUnauthorized)
 			return
 		}
+		if id == "" || timestamp == "" {
+			http.Error(w, "Missing svix-id or svix-timestamp header", http.StatusUnauthorized)
+			return
+		}
 
-		// In a real implementation, you would verify the webhook signature here
-		// For now, we'll just pass through
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid svix-timestamp header", http.StatusUnauthorized)
+			return
+		}
+		if age := time.Since(time.Unix(seconds, 0)); age > webhookTolerance || age < -webhookTolerance {
+			http.Error(w, "Webhook timestamp outside tolerance", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		if !validWebhookSignature(key, id, timestamp, body, signature) {
+			http.Error(w, "Invalid webhook signature", http.StatusUnauthorized)
+			return
+		}
 
 		next.ServeHTTP(w, r)
 	})
 }
 
+// webhookKey decodes a whsec_ signing secret into its HMAC key
+func webhookKey(secret string) ([]byte, error) {
+	if secret == "" {
+		return nil, errors.New("CLERK_WEBHOOK_SECRET is not set")
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+	if err != nil {
+		return nil, fmt.Errorf("CLERK_WEBHOOK_SECRET is not a valid signing secret: %w", err)
+	}
+	return key, nil
+}
+
+// validWebhookSignature reports whether any v1 entry of the space separated
+// svix-signature header is the HMAC-SHA256 of "id.timestamp.body"
+func validWebhookSignature(key []byte, id, timestamp string, body []byte, header string) bool {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	expected := []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+	for _, entry := range strings.Fields(header) {
+		version, signature, ok := strings.Cut(entry, ",")
+		if ok && version == "v1" && hmac.Equal([]byte(signature), expected) {
+			return true
+		}
+	}
+	return false
+}
+
 // CSRFMiddleware implements CSRF protection
 func (m *Middleware) CSRFMiddleware(authKey []byte, secure bool) func(http.Handler) http.Handler {
 	return csrf.Protect(authKey, csrf.Secure(secure))
 }
 
-// SecurityHeadersMiddleware adds security headers
+// SecurityHeadersMiddleware adds security headers, starting from config and
+// following any later SetSecurityConfig call
 func (m *Middleware) SecurityHeadersMiddleware(config SecurityConfig) func(http.Handler) http.Handler {
+	m.SetSecurityConfig(config)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			config := m.security.Load()
+
 			if config.CSPPolicy != "" {
 				w.Header().Set("Content-Security-Policy", config.CSPPolicy)
 			}
@@ -0,0 +1,254 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	httpHelpers "{{.Module}}/internal/shared/http"
+
+	"gorm.io/gorm"
+)
+
+// Page size bounds for the limit query parameter
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Query parameters read by Parse; every other parameter must be a listed filter
+const (
+	LimitParam  = "limit"
+	CursorParam = "cursor"
+	SortParam   = "sort"
+)
+
+// Field is a column a list can be sorted by. The column must be NOT NULL, and
+// the table's id breaks ties between equal values.
+type Field struct {
+	Column string
+	Time   bool // timestamp column, compared as a time
+}
+
+// Options lists what a list endpoint accepts.
+type Options struct {
+	// Table qualifies the columns, e.g. "users"
+	Table string
+	// SortFields maps the sort parameter, without its "-" prefix, to a column
+	SortFields map[string]Field
+	// DefaultSort is used without a sort parameter, e.g. "-created_at"
+	DefaultSort string
+	// Filters lists the other query parameters allowed
+	Filters []string
+}
+
+// Request is a parsed page request.
+type Request struct {
+	Limit   int
+	Sort    string // sort field as given, "-" prefixed for descending order
+	Filters url.Values
+
+	table  string
+	field  Field
+	desc   bool
+	cursor *cursor
+}
+
+// cursor points after the last row of the previous page
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// Parse reads limit, cursor, sort and the allowed filters from query. Unknown
+// parameters, invalid values and a cursor issued for another sort order are
+// rejected with a 400 error.
+func Parse(query url.Values, opts Options) (Request, error) {
+	req := Request{Limit: DefaultLimit, Sort: opts.DefaultSort, Filters: url.Values{}, table: opts.Table}
+
+	allowed := map[string]bool{LimitParam: true, CursorParam: true, SortParam: true}
+	for _, filter := range opts.Filters {
+		allowed[filter] = true
+	}
+	for key, values := range query {
+		if !allowed[key] {
+			return Request{}, badRequest("unknown query parameter %q", key)
+		}
+		if len(values) > 1 {
+			return Request{}, badRequest("query parameter %q is given more than once", key)
+		}
+		if key != LimitParam && key != CursorParam && key != SortParam && values[0] != "" {
+			req.Filters.Set(key, values[0])
+		}
+	}
+
+	if limit := query.Get(LimitParam); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxLimit {
+			return Request{}, badRequest("limit must be between 1 and %d", MaxLimit)
+		}
+		req.Limit = n
+	}
+
+	if sort := query.Get(SortParam); sort != "" {
+		req.Sort = sort
+	}
+	name, desc := strings.CutPrefix(req.Sort, "-")
+	field, ok := opts.SortFields[name]
+	if !ok {
+		return Request{}, badRequest("cannot sort by %q", name)
+	}
+	req.field, req.desc = field, desc
+
+	if encoded := query.Get(CursorParam); encoded != "" {
+		decoded, err := decodeCursor(encoded)
+		if err != nil {
+			return Request{}, badRequest("invalid cursor")
+		}
+		if decoded.Sort != req.Sort {
+			return Request{}, badRequest("cursor was issued for sort %q", decoded.Sort)
+		}
+		if field.Time {
+			if _, err := time.Parse(time.RFC3339Nano, decoded.Value); err != nil {
+				return Request{}, badRequest("invalid cursor")
+			}
+		}
+		req.cursor = decoded
+	}
+
+	return req, nil
+}
+
+// Apply orders db by the sort field and id, skips to the cursor and fetches
+// one row more than the limit, which Trim uses to tell whether a next page exists.
+func (r Request) Apply(db *gorm.DB) *gorm.DB {
+	column := r.qualify(r.field.Column)
+	id := r.qualify("id")
+
+	direction, comparison := "ASC", ">"
+	if r.desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if r.cursor != nil {
+		var value interface{} = r.cursor.Value
+		if r.field.Time {
+			value, _ = time.Parse(time.RFC3339Nano, r.cursor.Value)
+		}
+		db = db.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", column, id, comparison), value, r.cursor.ID)
+	}
+
+	return db.
+		Order(fmt.Sprintf("%s %s, %s %s", column, direction, id, direction)).
+		Limit(r.Limit + 1)
+}
+
+// Trim drops the extra row fetched by Apply and returns the cursor of the next
+// page, empty on the last page. key returns a row's sort value and id.
+func Trim[T any](rows []T, r Request, key func(row T) (value interface{}, id string)) ([]T, string) {
+	if len(rows) <= r.Limit {
+		return rows, ""
+	}
+	rows = rows[:r.Limit]
+
+	value, id := key(rows[len(rows)-1])
+	next := cursor{Sort: r.Sort, ID: id}
+	if t, ok := value.(time.Time); ok {
+		next.Value = t.UTC().Format(time.RFC3339Nano)
+	} else {
+		next.Value = fmt.Sprint(value)
+	}
+	return rows, encodeCursor(next)
+}
+
+// SortField returns the sort field name, without the "-" prefix.
+func (r Request) SortField() string {
+	return strings.TrimPrefix(r.Sort, "-")
+}
+
+func (r Request) qualify(column string) string {
+	if r.table == "" {
+		return column
+	}
+	return r.table + "." + column
+}
+
+// Page is the envelope of a list response.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Next is the request URL with the next cursor, empty on the last page
+	Next string `json:"next,omitempty"`
+}
+
+// NewPage wraps rows in the envelope, linking the next page from the URL of r.
+func NewPage[T any](r *http.Request, rows []T, limit int, nextCursor string) Page[T] {
+	if rows == nil {
+		rows = []T{}
+	}
+	page := Page[T]{Data: rows, Limit: limit, NextCursor: nextCursor}
+	if nextCursor != "" {
+		query := r.URL.Query()
+		query.Set(CursorParam, nextCursor)
+		next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		page.Next = next.String()
+	}
+	return page
+}
+
+// Bool parses an optional boolean filter.
+func Bool(filters url.Values, key string) (*bool, error) {
+	raw := filters.Get(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, badRequest("%s must be true or false", key)
+	}
+	return &value, nil
+}
+
+// Time parses an optional RFC 3339 timestamp filter.
+func Time(filters url.Values, key string) (*time.Time, error) {
+	raw := filters.Get(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, badRequest("%s must be an RFC 3339 timestamp", key)
+	}
+	return &value, nil
+}
+
+func encodeCursor(c cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(encoded string) (*cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+	if c.ID == "" {
+		return nil, fmt.Errorf("cursor has no id")
+	}
+	return &c, nil
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return httpHelpers.NewError(http.StatusBadRequest, fmt.Sprintf(format, args...))
+}
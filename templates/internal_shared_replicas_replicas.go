@@ -0,0 +1,245 @@
+package replicas
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"{{.Module}}/internal/shared/logger"
+
+	"gorm.io/gorm"
+)
+
+// pingTimeout bounds a single replica health check
+const pingTimeout = 2 * time.Second
+
+// routedKey stores the route taken by a statement, so it can be undone after the query
+const routedKey = "replicas:routed"
+
+type contextKey struct{}
+
+// WithPrimary marks ctx so every query made with it reads from the primary,
+// e.g. right after a write whose result must be visible despite replication lag.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, true)
+}
+
+// UsesPrimary reports whether ctx was marked by WithPrimary.
+func UsesPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(contextKey{}).(bool)
+	return primary
+}
+
+// Replica is a read-only connection pool that can be ejected while unhealthy.
+type Replica struct {
+	Name    string // shown in logs, e.g. the host
+	DB      *gorm.DB
+	healthy atomic.Bool
+}
+
+// NewReplica returns a replica considered healthy until a check or a query fails.
+func NewReplica(name string, db *gorm.DB) *Replica {
+	replica := &Replica{Name: name, DB: db}
+	replica.healthy.Store(true)
+	return replica
+}
+
+func (r *Replica) Healthy() bool {
+	return r.healthy.Load()
+}
+
+// Router is a GORM plugin sending reads to healthy replicas in round-robin order.
+// Writes, reads inside a transaction, locking reads and queries whose context
+// was marked by WithPrimary use the primary, as do all reads while no replica
+// is healthy.
+type Router struct {
+	log      *logger.Logger
+	replicas []*Replica
+	next     atomic.Uint64
+}
+
+var _ gorm.Plugin = (*Router)(nil)
+
+func NewRouter(logger *logger.Logger, replicas []*Replica) *Router {
+	return &Router{
+		log:      logger.With("component", "replicas"),
+		replicas: replicas,
+	}
+}
+
+func (r *Router) Name() string {
+	return "replicas"
+}
+
+// Initialize registers the routing callbacks around gorm:query and gorm:row.
+// Preloads run on the same replica as the query that loads them.
+func (r *Router) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Query().Before("gorm:query").Register("replicas:route", r.route); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().After("gorm:after_query").Register("replicas:release", r.release); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("replicas:route", r.route); err != nil {
+		return err
+	}
+	return db.Callback().Row().After("gorm:row").Register("replicas:release", r.release)
+}
+
+// Replicas returns the configured replicas.
+func (r *Router) Replicas() []*Replica {
+	return r.replicas
+}
+
+// Watch pings every replica each interval until ctx is done, ejecting the ones
+// that fail and readmitting them once they answer again.
+func (r *Router) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check pings every replica once and updates its health.
+func (r *Router) Check(ctx context.Context) {
+	for _, replica := range r.replicas {
+		err := ping(ctx, replica.DB)
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+		r.setHealthy(replica, err)
+	}
+}
+
+// Close releases every replica pool.
+func (r *Router) Close() error {
+	var errs []error
+	for _, replica := range r.replicas {
+		sqlDB, err := replica.DB.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// routed remembers the pool a statement had before it was sent to a replica
+type routed struct {
+	replica  *Replica
+	original gorm.ConnPool
+}
+
+func (r *Router) route(db *gorm.DB) {
+	if db.Error != nil || !readOnly(db) {
+		return
+	}
+	replica := r.pick()
+	if replica == nil {
+		return
+	}
+
+	db.Statement.Settings.Store(routedKey, routed{replica: replica, original: db.Statement.ConnPool})
+	db.Statement.ConnPool = replica.DB.ConnPool
+}
+
+// release restores the original pool, so later statements on the same chain
+// (e.g. an update after a lookup) do not run on the replica, and ejects the
+// replica when the query failed to reach it.
+func (r *Router) release(db *gorm.DB) {
+	value, ok := db.Statement.Settings.LoadAndDelete(routedKey)
+	if !ok {
+		return
+	}
+	route := value.(routed)
+	db.Statement.ConnPool = route.original
+
+	if isConnectionError(db.Error) {
+		r.setHealthy(route.replica, db.Error)
+	}
+}
+
+// pick returns the next healthy replica in round-robin order, or nil
+func (r *Router) pick() *Replica {
+	count := uint64(len(r.replicas))
+	if count == 0 {
+		return nil
+	}
+
+	start := r.next.Add(1)
+	for i := uint64(0); i < count; i++ {
+		if replica := r.replicas[(start+i)%count]; replica.Healthy() {
+			return replica
+		}
+	}
+	return nil
+}
+
+func (r *Router) setHealthy(replica *Replica, err error) {
+	healthy := err == nil
+	if replica.healthy.Swap(healthy) == healthy {
+		return
+	}
+
+	if healthy {
+		r.log.Info("database replica readmitted", "replica", replica.Name)
+	} else {
+		r.log.Warn("database replica ejected, reading from the remaining replicas or the primary", "replica", replica.Name, "error", err)
+	}
+}
+
+// readOnly reports whether the statement may run on a replica
+func readOnly(db *gorm.DB) bool {
+	statement := db.Statement
+	if statement.Context != nil && UsesPrimary(statement.Context) {
+		return false
+	}
+	// Inside a transaction every statement must use its connection
+	if _, inTransaction := statement.ConnPool.(gorm.TxCommitter); inTransaction {
+		return false
+	}
+	// SELECT ... FOR UPDATE / FOR SHARE
+	if _, locking := statement.Clauses["FOR"]; locking {
+		return false
+	}
+	// Raw SQL, e.g. UPDATE ... RETURNING scanned into a struct
+	if statement.SQL.Len() > 0 {
+		return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(statement.SQL.String())), "SELECT")
+	}
+	return true
+}
+
+func ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
+
+// isConnectionError reports errors meaning the replica could not be reached,
+// as opposed to errors in the query itself
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
@@ -0,0 +1,191 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"{{.Module}}/internal/shared/logger"
+)
+
+// Cipher policies for TLS 1.2 connections. TLS 1.3 suites are not configurable.
+const (
+	// CipherPolicyModern allows only forward-secret AEAD suites
+	CipherPolicyModern = "modern"
+	// CipherPolicyCompatible uses the Go default suites for older clients
+	CipherPolicyCompatible = "compatible"
+)
+
+var ErrUnknownCipherPolicy = errors.New("unknown cipher policy")
+
+var modernCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+type Config struct {
+	CertFile     string
+	KeyFile      string
+	MinVersion   string // "1.2" or "1.3"
+	CipherPolicy string
+}
+
+// ParseMinVersion converts "1.2" or "1.3" to the crypto/tls version constant.
+func ParseMinVersion(version string) (uint16, error) {
+	switch version {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS min version %q", version)
+	}
+}
+
+// CipherSuites returns the TLS 1.2 suites for policy; nil means the Go defaults.
+func CipherSuites(policy string) ([]uint16, error) {
+	switch policy {
+	case CipherPolicyModern:
+		return modernCipherSuites, nil
+	case CipherPolicyCompatible:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCipherPolicy, policy)
+	}
+}
+
+// NewServerConfig builds a TLS config that serves the reloader's current
+// certificate and offers HTTP/2 ahead of HTTP/1.1.
+func NewServerConfig(config Config, certificates *CertReloader) (*tls.Config, error) {
+	minVersion, err := ParseMinVersion(config.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := CipherSuites(config.CipherPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		GetCertificate: certificates.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}, nil
+}
+
+// CertReloader serves a certificate key pair from disk and swaps in a new one
+// when the files change, without dropping existing connections.
+type CertReloader struct {
+	log      *logger.Logger
+	certFile string
+	keyFile  string
+
+	certificate atomic.Pointer[tls.Certificate]
+	mu          sync.Mutex
+	modTimes    [2]time.Time
+}
+
+// NewCertReloader loads the key pair once and fails if it is invalid.
+func NewCertReloader(logger *logger.Logger, certFile string, keyFile string) (*CertReloader, error) {
+	reloader := &CertReloader{
+		log:      logger.With("component", "cert_reloader"),
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if _, err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// GetCertificate satisfies tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.certificate.Load(), nil
+}
+
+// Reload loads the key pair if either file changed since the last load and
+// reports whether a new certificate was swapped in. An invalid pair is rejected
+// and the current certificate kept.
+func (r *CertReloader) Reload() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTimes, err := r.fileModTimes()
+	if err != nil {
+		return false, err
+	}
+	if r.certificate.Load() != nil && modTimes == r.modTimes {
+		return false, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load certificate %s: %w", r.certFile, err)
+	}
+
+	r.certificate.Store(&certificate)
+	r.modTimes = modTimes
+	return true, nil
+}
+
+// Watch checks the certificate files every interval until ctx is done.
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				r.log.Error("certificate reload rejected", "cert_file", r.certFile, "error", err)
+				continue
+			}
+			if reloaded {
+				r.log.Info("certificate reloaded", "cert_file", r.certFile)
+			}
+		}
+	}
+}
+
+func (r *CertReloader) fileModTimes() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// RedirectHandler permanently redirects every request to the same URL over
+// HTTPS on httpsPort.
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if hostname, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = hostname
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,133 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"{{.Module}}/internal/shared/logger"
+
+	"gorm.io/gorm"
+)
+
+// PostgreSQL error codes worth retrying the whole transaction for
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+type contextKey struct{}
+
+// Options controls how transactions are run and retried.
+type Options struct {
+	// MaxAttempts bounds the runs of a transaction that hit serialization
+	// failures or deadlocks; 1 disables retries
+	MaxAttempts int
+	// Backoff is the wait after the first conflict, doubled on each retry with jitter
+	Backoff time.Duration
+	// TxOptions sets the isolation level of top-level transactions; nil uses the server default
+	TxOptions *sql.TxOptions
+}
+
+// Manager runs units of work spanning several datasources in one transaction.
+type Manager struct {
+	log  *logger.Logger
+	db   *gorm.DB
+	opts Options
+}
+
+func NewManager(logger *logger.Logger, db *gorm.DB, opts Options) *Manager {
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 1
+	}
+	return &Manager{
+		log:  logger.With("component", "transaction"),
+		db:   db,
+		opts: opts,
+	}
+}
+
+// Do runs fn in a transaction carried by the context passed to it; datasource
+// calls made with that context join the transaction. It commits when fn returns
+// nil and rolls back otherwise.
+//
+// Called inside another Do, fn runs in a savepoint that is rolled back on its
+// own when fn fails. A top-level transaction is retried from the start on
+// serialization failures and deadlocks, so fn must not have side effects
+// outside the database.
+func (m *Manager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if tx, ok := current(ctx); ok {
+		return tx.Transaction(func(nested *gorm.DB) error {
+			return fn(context.WithValue(ctx, contextKey{}, nested))
+		})
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, contextKey{}, tx))
+		}, m.opts.TxOptions)
+		if err == nil || !Retryable(err) || attempt >= m.opts.MaxAttempts {
+			return err
+		}
+
+		wait := m.backoff(attempt)
+		m.log.WithContext(ctx).Warn("transaction conflict, retrying",
+			"attempt", attempt,
+			"max_attempts", m.opts.MaxAttempts,
+			"retry_in", wait.String(),
+			"error", err,
+		)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// DB returns the transaction carried by ctx, or db outside a transaction,
+// bound to ctx. Datasources use it instead of db.WithContext(ctx).
+func DB(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := current(ctx); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// InTransaction reports whether ctx carries a transaction.
+func InTransaction(ctx context.Context) bool {
+	_, ok := current(ctx)
+	return ok
+}
+
+// Retryable reports whether err is a serialization failure or a deadlock,
+// after which the transaction can succeed when run again.
+func Retryable(err error) bool {
+	var pgErr interface{ SQLState() string }
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	state := pgErr.SQLState()
+	return state == serializationFailure || state == deadlockDetected
+}
+
+func current(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(contextKey{}).(*gorm.DB)
+	return tx, ok && tx != nil
+}
+
+// backoff doubles Backoff per failed attempt, with jitter over the upper half
+// so conflicting transactions do not collide again
+func (m *Manager) backoff(attempt int) time.Duration {
+	wait := m.opts.Backoff << (attempt - 1)
+	if wait <= 0 {
+		return 0
+	}
+	half := wait / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,132 @@
+package models_test
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"{{.Module}}/internal/audit/models"
+	"{{.Module}}/internal/shared/logger"
+)
+
+type record struct {
+	Name      string    `json:"name"`
+	Banned    bool      `json:"banned"`
+	Tags      []string  `json:"tags"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func TestDiff(t *testing.T) {
+	before := &record{Name: "Ada", Tags: []string{"a"}, UpdatedAt: time.Unix(1, 0)}
+	after := &record{Name: "Ada", Banned: true, Tags: []string{"a", "b"}, UpdatedAt: time.Unix(2, 0)}
+
+	changes, err := models.Diff(before, after)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	want := models.Changes{
+		"banned": {Before: false, After: true},
+		"tags":   {Before: []interface{}{"a"}, After: []interface{}{"a", "b"}},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Diff() = %+v, want %+v", changes, want)
+	}
+}
+
+func TestDiff_CreatedAndDeleted(t *testing.T) {
+	value := &record{Name: "Ada"}
+
+	created, err := models.Diff(nil, value)
+	if err != nil || created["name"] != (models.Change{After: "Ada"}) {
+		t.Errorf("Diff(nil, value) = %+v, %v, want every field as added", created, err)
+	}
+	if _, ok := created["updated_at"]; ok {
+		t.Error("Diff() kept updated_at")
+	}
+
+	var missing *record
+	deleted, err := models.Diff(value, missing)
+	if err != nil || deleted["name"] != (models.Change{Before: "Ada"}) {
+		t.Errorf("Diff(value, nil) = %+v, %v, want every field as removed", deleted, err)
+	}
+
+	if _, err := models.Diff([]string{"not", "an", "object"}, nil); err == nil {
+		t.Error("Diff() accepted a value that isn't a JSON object")
+	}
+}
+
+func TestChanges_Redact(t *testing.T) {
+	changes := models.Changes{
+		"name":   {Before: "Ada", After: "Grace"},
+		"banned": {Before: false, After: true},
+	}
+
+	changes.Redact("name", "email")
+
+	want := models.Changes{
+		"name":   {Redacted: true},
+		"banned": {Before: false, After: true},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Redact() = %+v, want %+v", changes, want)
+	}
+}
+
+func TestChanges_ValueAndScan(t *testing.T) {
+	changes := models.Changes{"name": {Before: "Ada", After: "Grace"}}
+
+	value, err := changes.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+
+	var scanned models.Changes
+	if err := scanned.Scan([]byte(value.(string))); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if !reflect.DeepEqual(scanned, changes) {
+		t.Errorf("Scan() = %+v, want %+v", scanned, changes)
+	}
+
+	if err := scanned.Scan(42); err == nil {
+		t.Error("Scan() accepted an int")
+	}
+}
+
+func TestActorFromContext(t *testing.T) {
+	ctx := context.Background()
+	if got := models.ActorFromContext(ctx); got != (models.Actor{Type: models.ActorSystem}) {
+		t.Errorf("ActorFromContext(empty) = %+v, want the system actor", got)
+	}
+
+	ctx = logger.ContextWithUserID(ctx, "user_1")
+	if got := models.ActorFromContext(ctx); got != (models.Actor{Type: models.ActorUser, ID: "user_1"}) {
+		t.Errorf("ActorFromContext(user) = %+v, want the signed-in user", got)
+	}
+
+	admin := models.Actor{Type: models.ActorAdmin}
+	if got := models.ActorFromContext(models.ContextWithActor(ctx, admin)); got != admin {
+		t.Errorf("ActorFromContext(actor) = %+v, want %+v", got, admin)
+	}
+}
+
+func TestNewEventFilter(t *testing.T) {
+	filter, err := models.NewEventFilter(url.Values{
+		"resource_type": {"user"},
+		"resource_id":   {" usr_1 "},
+		"created_after": {"2024-01-01T00:00:00Z"},
+	})
+	if err != nil {
+		t.Fatalf("NewEventFilter() error = %v", err)
+	}
+	if filter.ResourceType != "user" || filter.ResourceID != "usr_1" || filter.CreatedAfter == nil || filter.CreatedBefore != nil {
+		t.Errorf("NewEventFilter() = %+v", filter)
+	}
+
+	if _, err := models.NewEventFilter(url.Values{"created_before": {"yesterday"}}); err == nil {
+		t.Error("NewEventFilter() accepted an invalid time")
+	}
+}
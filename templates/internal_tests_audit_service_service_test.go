@@ -0,0 +1,102 @@
+package audit_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	auditDatasource "{{.Module}}/internal/audit/datasource"
+	"{{.Module}}/internal/audit/models"
+	audit "{{.Module}}/internal/audit/service"
+	"{{.Module}}/internal/shared/logger"
+)
+
+type fakeEvents struct {
+	auditDatasource.AuditDatasource
+	events []models.Event
+}
+
+func (d *fakeEvents) CreateEvent(ctx context.Context, event *models.Event) error {
+	d.events = append(d.events, *event)
+	return nil
+}
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func setup() (audit.AuditService, *fakeEvents) {
+	log := logger.NewLogger(&logger.Config{Level: slog.LevelError, Writer: io.Discard})
+	data := &fakeEvents{}
+	return audit.NewService(log, data), data
+}
+
+func TestRecord_StoresEventWithRequestContext(t *testing.T) {
+	service, data := setup()
+	ctx := logger.ContextWithRequestID(context.Background(), "req_1")
+	ctx = logger.ContextWithClientIP(ctx, "203.0.113.7")
+	ctx = models.ContextWithActor(ctx, models.Actor{Type: models.ActorClerk, ID: "msg_1"})
+
+	err := service.Record(ctx, models.Entry{
+		Action:       models.ActionUserUpdated,
+		ResourceType: models.ResourceUser,
+		ResourceID:   "usr_1",
+		Before:       &user{Name: "Ada"},
+		After:        &user{Name: "Grace"},
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	if len(data.events) != 1 {
+		t.Fatalf("events = %+v, want one", data.events)
+	}
+	event := data.events[0]
+	if event.ID == "" || event.CreatedAt.IsZero() {
+		t.Errorf("event = %+v, want an ID and a creation time", event)
+	}
+	if event.ActorType != models.ActorClerk || event.ActorID != "msg_1" || event.RequestID != "req_1" || event.ClientIP != "203.0.113.7" {
+		t.Errorf("event = %+v, want the actor, request ID and client IP of the context", event)
+	}
+	if event.ResourceID != "usr_1" || event.Changes["name"] != (models.Change{Before: "Ada", After: "Grace"}) {
+		t.Errorf("event changes = %+v, want the name change", event.Changes)
+	}
+}
+
+func TestRecord_SkipsUpdateWithoutChanges(t *testing.T) {
+	service, data := setup()
+
+	err := service.Record(context.Background(), models.Entry{
+		Action:       models.ActionUserUpdated,
+		ResourceType: models.ResourceUser,
+		ResourceID:   "usr_1",
+		Before:       &user{Name: "Ada"},
+		After:        &user{Name: "Ada"},
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if len(data.events) != 0 {
+		t.Errorf("events = %+v, want none", data.events)
+	}
+}
+
+func TestRecord_RedactsPersonalFields(t *testing.T) {
+	service, data := setup()
+
+	err := service.Record(context.Background(), models.Entry{
+		Action:       models.ActionUserCreated,
+		ResourceType: models.ResourceUser,
+		ResourceID:   "usr_1",
+		After:        &user{Name: "Ada"},
+		Personal:     []string{"name"},
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	if len(data.events) != 1 || data.events[0].Changes["name"] != (models.Change{Redacted: true}) {
+		t.Errorf("events = %+v, want the name change without its values", data.events)
+	}
+}
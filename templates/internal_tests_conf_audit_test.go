@@ -0,0 +1,127 @@
+package conf_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"{{.Module}}/internal/conf"
+)
+
+// safeConfig returns a production config with no audit findings.
+func safeConfig() *conf.ConfigVars {
+	cfg := &conf.ConfigVars{}
+	cfg.Server.Environment = conf.EnvironmentProduction
+	cfg.Database.DatabaseSSLMode = "require"
+	cfg.Database.DatabasePassword = "a-long-random-password"
+	cfg.CSRF.AuthKey = strings.Repeat("k", 32)
+	cfg.CSRF.Secure = true
+	cfg.Security.CSPPolicy = "default-src 'self'"
+	cfg.Security.HSTSMaxAge = 31536000
+	cfg.Clerk.Key = "pk_live"
+	cfg.Clerk.Secret = "sk_live"
+	cfg.Clerk.WebhookSecret = "whsec_c2lnbmluZy1rZXk="
+	cfg.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	cfg.Logging.Level = "info"
+	return cfg
+}
+
+func TestAudit_SafeProductionConfig(t *testing.T) {
+	report := conf.Audit(safeConfig(), conf.DefaultAuditRules())
+
+	if len(report.Findings) != 0 {
+		t.Fatalf("expected no findings, got %v", report.Findings)
+	}
+	if err := report.Enforce(false); err != nil {
+		t.Errorf("Enforce() returned error: %v", err)
+	}
+}
+
+func TestAudit_BlocksUnsafeProductionBoot(t *testing.T) {
+	cfg := safeConfig()
+	cfg.Database.DatabaseSSLMode = "disable"
+	cfg.Database.DatabasePassword = "root"
+	cfg.CSRF.Secure = false
+	cfg.Security.CSPPolicy = ""
+	cfg.Logging.Level = "debug"
+	cfg.Clerk.WebhookSecret = ""
+
+	report := conf.Audit(cfg, conf.DefaultAuditRules())
+
+	rules := make(map[string]conf.Severity)
+	for _, finding := range report.Findings {
+		rules[finding.Rule] = finding.Severity
+	}
+	for _, rule := range []string{"database-ssl-disabled", "database-sample-password", "csrf-insecure-cookie", "security-empty-csp", "clerk-webhook-secret-missing"} {
+		if rules[rule] != conf.SeverityCritical {
+			t.Errorf("rule %s severity = %q, want critical", rule, rules[rule])
+		}
+	}
+	if rules["logging-debug-level"] != conf.SeverityWarning {
+		t.Errorf("logging-debug-level severity = %q, want warning", rules["logging-debug-level"])
+	}
+
+	if err := report.Enforce(false); !errors.Is(err, conf.ErrUnsafeConfig) {
+		t.Errorf("Enforce(false) = %v, want %v", err, conf.ErrUnsafeConfig)
+	}
+	if err := report.Enforce(true); err != nil {
+		t.Errorf("Enforce(true) should allow the override, got %v", err)
+	}
+
+	var output bytes.Buffer
+	report.Print(&output)
+	if !strings.Contains(output.String(), "[critical") || !strings.Contains(output.String(), conf.EnvPrefix+"DATABASE_SSL_MODE") {
+		t.Errorf("Print() output missing severity or key:\n%s", output.String())
+	}
+}
+
+func TestAudit_DevelopmentNeverBlocks(t *testing.T) {
+	cfg := safeConfig()
+	cfg.Server.Environment = "development"
+	cfg.Database.DatabaseSSLMode = "disable"
+	cfg.Database.DatabasePassword = "root"
+
+	report := conf.Audit(cfg, conf.DefaultAuditRules())
+
+	if len(report.Findings) != 1 || report.Findings[0].Severity != conf.SeverityInfo {
+		t.Errorf("expected a single info finding, got %v", report.Findings)
+	}
+	if err := report.Enforce(false); err != nil {
+		t.Errorf("Enforce() returned error outside production: %v", err)
+	}
+}
+
+func TestAudit_SSLModesWithoutEncryption(t *testing.T) {
+	for _, mode := range []string{"disable", "allow", "prefer"} {
+		cfg := safeConfig()
+		cfg.Database.DatabaseSSLMode = mode
+
+		report := conf.Audit(cfg, conf.DefaultAuditRules())
+		if len(report.Findings) != 1 || report.Findings[0].Rule != "database-ssl-disabled" || report.Findings[0].Severity != conf.SeverityCritical {
+			t.Errorf("sslmode %s: findings = %v, want database-ssl-disabled as critical", mode, report.Findings)
+		}
+	}
+
+	for _, mode := range []string{"require", "verify-ca", "verify-full"} {
+		cfg := safeConfig()
+		cfg.Database.DatabaseSSLMode = mode
+		if report := conf.Audit(cfg, conf.DefaultAuditRules()); len(report.Findings) != 0 {
+			t.Errorf("sslmode %s: findings = %v, want none", mode, report.Findings)
+		}
+	}
+}
+
+func TestAudit_UnknownEnvironmentIsProduction(t *testing.T) {
+	cfg := safeConfig()
+	cfg.Server.Environment = "prod"
+	cfg.Database.DatabaseSSLMode = "disable"
+
+	report := conf.Audit(cfg, conf.DefaultAuditRules())
+	if len(report.Findings) != 1 || report.Findings[0].Severity != conf.SeverityCritical {
+		t.Errorf("findings = %v, want the production severity", report.Findings)
+	}
+	if err := report.Enforce(false); !errors.Is(err, conf.ErrUnsafeConfig) {
+		t.Errorf("Enforce(false) = %v, want %v", err, conf.ErrUnsafeConfig)
+	}
+}
@@ -0,0 +1,107 @@
+package conf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"{{.Module}}/internal/conf"
+)
+
+func TestCORSVars_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    conf.CORSVars
+		wantErr error
+	}{
+		{
+			name: "exact origins with credentials",
+			vars: conf.CORSVars{AllowedOrigins: []string{"https://app.example.com", "http://localhost:3000"}, AllowCredentials: true},
+		},
+		{
+			name: "wildcard subdomain origins without credentials",
+			vars: conf.CORSVars{AllowedOrigins: []string{"https://*.example.com", "https://*.app.example.co.uk:8443"}},
+		},
+		{
+			name:    "wildcard subdomain origin with credentials",
+			vars:    conf.CORSVars{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true},
+			wantErr: conf.ErrInsecureCORS,
+		},
+		{
+			name: "allow all without credentials",
+			vars: conf.CORSVars{AllowedOrigins: []string{"*"}},
+		},
+		{
+			name:    "allow all with credentials",
+			vars:    conf.CORSVars{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			wantErr: conf.ErrInsecureCORS,
+		},
+		{
+			name:    "origin with a path",
+			vars:    conf.CORSVars{AllowedOrigins: []string{"https://example.com/app"}},
+			wantErr: conf.ErrMalformedValue,
+		},
+		{
+			name:    "origin without a scheme",
+			vars:    conf.CORSVars{AllowedOrigins: []string{"example.com"}},
+			wantErr: conf.ErrMalformedValue,
+		},
+		{
+			name:    "wildcard host",
+			vars:    conf.CORSVars{AllowedOrigins: []string{"https://*"}},
+			wantErr: conf.ErrMalformedValue,
+		},
+		{
+			name:    "wildcard in front of a top-level domain",
+			vars:    conf.CORSVars{AllowedOrigins: []string{"https://*.com"}},
+			wantErr: conf.ErrMalformedValue,
+		},
+		{
+			name:    "wildcard in front of a public suffix",
+			vars:    conf.CORSVars{WebhookAllowedOrigins: []string{"https://*.co.uk"}},
+			wantErr: conf.ErrMalformedValue,
+		},
+		{
+			name:    "wildcard in front of an IP address",
+			vars:    conf.CORSVars{AllowedOrigins: []string{"http://*.10.0.0.1"}},
+			wantErr: conf.ErrMalformedValue,
+		},
+		{
+			name:    "wildcard inside a label",
+			vars:    conf.CORSVars{AllowedOrigins: []string{"https://app-*.example.com"}},
+			wantErr: conf.ErrMalformedValue,
+		},
+		{
+			name:    "more than one wildcard",
+			vars:    conf.CORSVars{WebhookAllowedOrigins: []string{"https://*.*.example.com"}},
+			wantErr: conf.ErrMalformedValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.vars.Validate()
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("Validate() returned error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigVars_RejectsCredentialsWithWildcardOrigin(t *testing.T) {
+	dir := t.TempDir()
+	writeBaseConfig(t, dir)
+	p := conf.EnvPrefix
+	writeConfigFile(t, dir, ".env.local", p+"DATABASE_PASSWORD=pass\n"+p+"CLERK_SECRET=sk\n"+p+"CORS_ALLOWED_ORIGINS=*\n"+p+"CORS_ALLOW_CREDENTIALS=true\n")
+
+	_, err := conf.LoadConfigVars(context.Background(), conf.LoadOptions{Dir: dir})
+	if !errors.Is(err, conf.ErrInsecureCORS) {
+		t.Fatalf("LoadConfigVars() = %v, want %v", err, conf.ErrInsecureCORS)
+	}
+}
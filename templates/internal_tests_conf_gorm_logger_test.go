@@ -0,0 +1,131 @@
+package conf_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"{{.Module}}/internal/conf"
+	"{{.Module}}/internal/shared/logger"
+
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// gormLogEntries runs fn against a GormLogger writing to a buffer and returns the decoded entries
+func gormLogEntries(t *testing.T, config conf.GormLoggerConfig, fn func(gl *conf.GormLogger)) []map[string]interface{} {
+	t.Helper()
+
+	var buf bytes.Buffer
+	fn(conf.NewGormLogger(logger.NewLogger(&logger.Config{Writer: &buf, Level: slog.LevelDebug}), config))
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line is not JSON: %s", line)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func query(sql string, rows int64) func() (string, int64) {
+	return func() (string, int64) { return sql, rows }
+}
+
+func TestGormLogger_TraceLogsStructuredOperation(t *testing.T) {
+	ctx := logger.ContextWithRequestID(logger.ContextWithTraceID(context.Background(), "trace-1"), "req-1")
+
+	entries := gormLogEntries(t, conf.GormLoggerConfig{SlowThreshold: time.Second}, func(gl *conf.GormLogger) {
+		gl.Trace(ctx, time.Now(), query(`SELECT * FROM "users" WHERE "users"."id" = $1`, 1), nil)
+	})
+
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1: %v", len(entries), entries)
+	}
+	entry := entries[0]
+	want := map[string]interface{}{
+		"msg":           "Database operation completed",
+		"operation":     "SELECT",
+		"table":         "users",
+		"rows_affected": float64(1),
+		"trace_id":      "trace-1",
+		"request_id":    "req-1",
+		"component":     "gorm",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+}
+
+func TestGormLogger_TraceErrors(t *testing.T) {
+	entries := gormLogEntries(t, conf.GormLoggerConfig{}, func(gl *conf.GormLogger) {
+		gl.Trace(context.Background(), time.Now(), query(`INSERT INTO "organizations" ("id") VALUES ($1)`, 0), errors.New("duplicate key"))
+		gl.Trace(context.Background(), time.Now(), query(`SELECT * FROM "organizations" LIMIT 1`, 0), gorm.ErrRecordNotFound)
+	})
+
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[0]["level"] != "ERROR" || entries[0]["error"] != "duplicate key" || entries[0]["table"] != "organizations" {
+		t.Errorf("failed insert entry = %v, want an error for organizations", entries[0])
+	}
+	if entries[1]["level"] != "DEBUG" {
+		t.Errorf("record not found logged at %v, want DEBUG", entries[1]["level"])
+	}
+}
+
+func TestGormLogger_SlowQuery(t *testing.T) {
+	entries := gormLogEntries(t, conf.GormLoggerConfig{SlowThreshold: 10 * time.Millisecond}, func(gl *conf.GormLogger) {
+		gl.Trace(context.Background(), time.Now().Add(-50*time.Millisecond), query(`UPDATE "users" SET "email"=$1`, 3), nil)
+	})
+
+	var slow map[string]interface{}
+	for _, entry := range entries {
+		if entry["msg"] == "Slow database query" {
+			slow = entry
+		}
+	}
+	if slow == nil {
+		t.Fatalf("no slow query entry in %v", entries)
+	}
+	if slow["level"] != "WARN" || slow["operation"] != "UPDATE" || slow["threshold_ms"] != float64(10) {
+		t.Errorf("slow query entry = %v", slow)
+	}
+}
+
+func TestGormLogger_LogModeSilent(t *testing.T) {
+	entries := gormLogEntries(t, conf.GormLoggerConfig{}, func(gl *conf.GormLogger) {
+		gl.LogMode(gormLogger.Silent).Trace(context.Background(), time.Now(), query(`SELECT 1`, 1), errors.New("boom"))
+	})
+	if len(entries) != 0 {
+		t.Errorf("silent logger wrote %v", entries)
+	}
+}
+
+func TestGormLogger_ParamsFilter(t *testing.T) {
+	sql := `SELECT * FROM "users" WHERE "email" = $1`
+	var buf bytes.Buffer
+	log := logger.NewLogger(&logger.Config{Writer: &buf})
+
+	redacting := conf.NewGormLogger(log, conf.GormLoggerConfig{})
+	if _, params := redacting.ParamsFilter(context.Background(), sql, "someone@example.com"); params != nil {
+		t.Errorf("ParamsFilter kept %v, want parameters dropped by default", params)
+	}
+
+	verbose := conf.NewGormLogger(log, conf.GormLoggerConfig{LogParams: true})
+	if _, params := verbose.ParamsFilter(context.Background(), sql, "someone@example.com"); len(params) != 1 {
+		t.Errorf("ParamsFilter = %v, want parameters kept with LogParams", params)
+	}
+}
@@ -0,0 +1,166 @@
+package conf_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"{{.Module}}/internal/conf"
+)
+
+type loaderNested struct {
+	Enabled bool `env:"NESTED_ENABLED" default:"true"`
+}
+
+type loaderTarget struct {
+	Name     string        `env:"NAME,required"`
+	Port     int           `env:"PORT" default:"8080"`
+	MaxSize  int64         `env:"MAX_SIZE" default:"1024"`
+	Timeout  time.Duration `env:"TIMEOUT" default:"5s"`
+	Origins  []string      `env:"ORIGINS"`
+	Weights  []int         `env:"WEIGHTS"`
+	Optional string        `env:"OPTIONAL"`
+	Nested   loaderNested
+	ignored  string
+}
+
+func lookupFrom(values map[string]string) conf.LookupFunc {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func TestLoadStruct_AppliesValuesAndDefaults(t *testing.T) {
+	target := &loaderTarget{}
+	err := conf.LoadStruct("APP_", target, lookupFrom(map[string]string{
+		"APP_NAME":    "api",
+		"APP_PORT":    "9090",
+		"APP_ORIGINS": "https://a.example.com, https://b.example.com",
+		"APP_WEIGHTS": "1,2,3",
+	}))
+	if err != nil {
+		t.Fatalf("LoadStruct returned error: %v", err)
+	}
+
+	if target.Name != "api" {
+		t.Errorf("Name = %q, want %q", target.Name, "api")
+	}
+	if target.Port != 9090 {
+		t.Errorf("Port = %d, want 9090", target.Port)
+	}
+	if target.MaxSize != 1024 {
+		t.Errorf("MaxSize = %d, want default 1024", target.MaxSize)
+	}
+	if target.Timeout != 5*time.Second {
+		t.Errorf("Timeout = %v, want default 5s", target.Timeout)
+	}
+	if !reflect.DeepEqual(target.Origins, []string{"https://a.example.com", "https://b.example.com"}) {
+		t.Errorf("Origins = %v", target.Origins)
+	}
+	if !reflect.DeepEqual(target.Weights, []int{1, 2, 3}) {
+		t.Errorf("Weights = %v", target.Weights)
+	}
+	if target.Optional != "" {
+		t.Errorf("Optional = %q, want empty", target.Optional)
+	}
+	if !target.Nested.Enabled {
+		t.Error("Nested.Enabled should use its default")
+	}
+}
+
+func TestLoadStruct_AggregatesErrors(t *testing.T) {
+	target := &loaderTarget{}
+	err := conf.LoadStruct("APP_", target, lookupFrom(map[string]string{
+		"APP_PORT":           "not-a-number",
+		"APP_TIMEOUT":        "soon",
+		"APP_NESTED_ENABLED": "maybe",
+	}))
+
+	var loadErr *conf.LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatalf("expected *conf.LoadError, got %v", err)
+	}
+
+	if len(loadErr.Errors) != 4 {
+		t.Fatalf("expected 4 field errors, got %d: %v", len(loadErr.Errors), loadErr)
+	}
+
+	keys := map[string]error{}
+	for _, fe := range loadErr.Errors {
+		keys[fe.Key] = fe.Err
+	}
+
+	if !errors.Is(keys["APP_NAME"], conf.ErrMissingValue) {
+		t.Errorf("APP_NAME should be reported missing, got %v", keys["APP_NAME"])
+	}
+	for _, key := range []string{"APP_PORT", "APP_TIMEOUT", "APP_NESTED_ENABLED"} {
+		if !errors.Is(keys[key], conf.ErrMalformedValue) {
+			t.Errorf("%s should be reported malformed, got %v", key, keys[key])
+		}
+	}
+}
+
+func TestLoadStruct_EmptyValueFallsBackToDefault(t *testing.T) {
+	target := &loaderTarget{}
+	err := conf.LoadStruct("APP_", target, lookupFrom(map[string]string{
+		"APP_NAME": "api",
+		"APP_PORT": "",
+	}))
+	if err != nil {
+		t.Fatalf("LoadStruct returned error: %v", err)
+	}
+	if target.Port != 8080 {
+		t.Errorf("Port = %d, want default 8080", target.Port)
+	}
+}
+
+func TestLoadStruct_RejectsNonPointer(t *testing.T) {
+	if err := conf.LoadStruct("APP_", loaderTarget{}, lookupFrom(nil)); err == nil {
+		t.Error("expected error for non-pointer target")
+	}
+}
+
+func TestLoadStruct_ConfigVars(t *testing.T) {
+	vars := &conf.ConfigVars{}
+	err := conf.LoadStruct(conf.EnvPrefix, vars, lookupFrom(map[string]string{
+		conf.EnvPrefix + "CLERK_KEY":         "pk_test",
+		conf.EnvPrefix + "CLERK_SECRET":      "sk_test",
+		conf.EnvPrefix + "SERVER_NAME":       "api",
+		conf.EnvPrefix + "SERVER_VERSION":    "1.0.0",
+		conf.EnvPrefix + "DATABASE_HOST":     "localhost",
+		conf.EnvPrefix + "DATABASE_NAME":     "api_db",
+		conf.EnvPrefix + "DATABASE_USER":     "postgres",
+		conf.EnvPrefix + "DATABASE_PASSWORD": "secret",
+		conf.EnvPrefix + "CSRF_SECURE":       "true",
+	}))
+	if err != nil {
+		t.Fatalf("LoadStruct returned error: %v", err)
+	}
+
+	if !vars.CSRF.Secure {
+		t.Error("CSRF.Secure should be true")
+	}
+	if vars.Security.HSTSMaxAge != 31536000 {
+		t.Errorf("Security.HSTSMaxAge = %d, want default", vars.Security.HSTSMaxAge)
+	}
+	if vars.RequestLimits.MaxRequestSize != 10485760 {
+		t.Errorf("RequestLimits.MaxRequestSize = %d, want default", vars.RequestLimits.MaxRequestSize)
+	}
+	if vars.Server.Environment != "development" {
+		t.Errorf("Server.Environment = %q, want default", vars.Server.Environment)
+	}
+}
+
+func TestAdminVars_Validate(t *testing.T) {
+	if err := (conf.AdminVars{Enabled: false}).Validate(); err != nil {
+		t.Errorf("disabled admin listener returned error: %v", err)
+	}
+	if err := (conf.AdminVars{Enabled: true, Token: "token"}).Validate(); err != nil {
+		t.Errorf("admin listener with token returned error: %v", err)
+	}
+	if err := (conf.AdminVars{Enabled: true}).Validate(); !errors.Is(err, conf.ErrMissingValue) {
+		t.Errorf("admin listener without token = %v, want %v", err, conf.ErrMissingValue)
+	}
+}
@@ -0,0 +1,106 @@
+package conf_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"{{.Module}}/internal/conf"
+	"{{.Module}}/internal/shared/logger"
+)
+
+func newTestReloader(t *testing.T, dir string) *conf.Reloader {
+	t.Helper()
+	opts := conf.LoadOptions{Dir: dir}
+	initial, err := conf.LoadConfigVars(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("LoadConfigVars returned error: %v", err)
+	}
+	testLogger := logger.NewLogger(&logger.Config{Writer: io.Discard})
+	return conf.NewReloader(testLogger, initial, opts)
+}
+
+func TestReloader_AppliesValidConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeBaseConfig(t, dir)
+	writeConfigFile(t, dir, ".env.local", conf.EnvPrefix+"DATABASE_PASSWORD=pass\n"+conf.EnvPrefix+"CLERK_SECRET=sk\n"+conf.EnvPrefix+"RATE_LIMIT_RPS=10\n")
+
+	reloader := newTestReloader(t, dir)
+	initialVersion := reloader.Version()
+
+	var applied float64
+	reloader.OnReload(func(cfg *conf.ConfigVars) (func(), error) {
+		return func() { applied = cfg.RateLimit.RequestsPerSecond }, nil
+	})
+
+	writeConfigFile(t, dir, ".env.local", conf.EnvPrefix+"DATABASE_PASSWORD=pass\n"+conf.EnvPrefix+"CLERK_SECRET=sk\n"+conf.EnvPrefix+"RATE_LIMIT_RPS=50\n")
+	if err := reloader.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload returned error: %v", err)
+	}
+
+	if applied != 50 {
+		t.Errorf("hook applied rate %v, want 50", applied)
+	}
+	if reloader.Current().RateLimit.RequestsPerSecond != 50 {
+		t.Errorf("Current() was not swapped")
+	}
+	if reloader.Version() == initialVersion {
+		t.Errorf("Version() should change after a reload, still %s", initialVersion)
+	}
+}
+
+func TestReloader_RejectsInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeBaseConfig(t, dir)
+	writeConfigFile(t, dir, ".env.local", conf.EnvPrefix+"DATABASE_PASSWORD=pass\n"+conf.EnvPrefix+"CLERK_SECRET=sk\n")
+
+	reloader := newTestReloader(t, dir)
+	initialVersion := reloader.Version()
+
+	applied := false
+	reloader.OnReload(func(cfg *conf.ConfigVars) (func(), error) {
+		return func() { applied = true }, nil
+	})
+	reloader.OnReload(func(cfg *conf.ConfigVars) (func(), error) {
+		return nil, errors.New("rejected by hook")
+	})
+
+	if err := reloader.Reload(context.Background()); err == nil {
+		t.Fatal("expected the hook error to reject the reload")
+	}
+	if applied {
+		t.Error("no hook should be applied when any hook rejects the reload")
+	}
+
+	writeConfigFile(t, dir, ".env.local", conf.EnvPrefix+"DATABASE_PASSWORD=pass\n"+conf.EnvPrefix+"CLERK_SECRET=sk\n"+conf.EnvPrefix+"LOG_LEVEL=loud\n")
+	if err := reloader.Reload(context.Background()); err == nil {
+		t.Fatal("expected an invalid log level to reject the reload")
+	}
+
+	if reloader.Version() != initialVersion {
+		t.Errorf("Version() changed after rejected reloads: %s", reloader.Version())
+	}
+}
+
+func TestReloader_KeepsNonReloadableSections(t *testing.T) {
+	dir := t.TempDir()
+	writeBaseConfig(t, dir)
+	writeConfigFile(t, dir, ".env.local", conf.EnvPrefix+"DATABASE_PASSWORD=pass\n"+conf.EnvPrefix+"CLERK_SECRET=sk\n")
+
+	reloader := newTestReloader(t, dir)
+	initial := reloader.Current()
+
+	writeConfigFile(t, dir, ".env.local", conf.EnvPrefix+"DATABASE_PASSWORD=changed\n"+conf.EnvPrefix+"CLERK_SECRET=sk\n"+conf.EnvPrefix+"LOG_LEVEL=error\n")
+	if err := reloader.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload returned error: %v", err)
+	}
+
+	current := reloader.Current()
+	if current.Logging.Level != "error" {
+		t.Errorf("Logging.Level = %q, want the reloaded error", current.Logging.Level)
+	}
+	if current.Database.DatabasePassword != initial.Database.DatabasePassword {
+		t.Errorf("Current() took the reloaded database password, which needs a restart")
+	}
+}
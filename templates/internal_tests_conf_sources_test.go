@@ -0,0 +1,97 @@
+package conf_test
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"{{.Module}}/internal/conf"
+)
+
+func writeConfigFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
+
+func TestLayers_LaterSourcesWin(t *testing.T) {
+	layers := conf.NewLayers(
+		conf.NewMapSource("base", map[string]string{"A": "base", "B": "base"}),
+		conf.NewMapSource("override", map[string]string{"A": "override", "B": ""}),
+	)
+
+	if value, _ := layers.Lookup("A"); value != "override" {
+		t.Errorf("A = %q, want override", value)
+	}
+	if value, _ := layers.Lookup("B"); value != "base" {
+		t.Errorf("B = %q, want base (empty values do not override)", value)
+	}
+	if _, ok := layers.Lookup("C"); ok {
+		t.Error("C should not be found")
+	}
+
+	origins := layers.Origins()
+	if _, ok := origins["C"]; ok {
+		t.Error("C should have no origin")
+	}
+	if origins["A"] != "override" || origins["B"] != "base" {
+		t.Errorf("unexpected origins: %v", origins)
+	}
+}
+
+func TestLoadYAMLFile_FlattensKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "config.yaml", "server:\n  port: 9090\ncors:\n  allowed-origins:\n    - https://a.example.com\n    - https://b.example.com\n")
+
+	source, err := conf.LoadYAMLFile(filepath.Join(dir, "config.yaml"), "APP_")
+	if err != nil {
+		t.Fatalf("LoadYAMLFile returned error: %v", err)
+	}
+
+	if value, _ := source.Lookup("APP_SERVER_PORT"); value != "9090" {
+		t.Errorf("APP_SERVER_PORT = %q, want 9090", value)
+	}
+	if value, _ := source.Lookup("APP_CORS_ALLOWED_ORIGINS"); value != "https://a.example.com,https://b.example.com" {
+		t.Errorf("APP_CORS_ALLOWED_ORIGINS = %q", value)
+	}
+}
+
+func TestLoadConfigVars_LayerPriority(t *testing.T) {
+	dir := t.TempDir()
+	p := conf.EnvPrefix
+
+	writeConfigFile(t, dir, ".env", p+"CLERK_KEY=pk\n"+p+"CLERK_SECRET=sk\n"+p+"SERVER_NAME=api\n"+p+"SERVER_VERSION=1.0.0\n"+
+		p+"DATABASE_HOST=base-host\n"+p+"DATABASE_NAME=db\n"+p+"DATABASE_USER=user\n"+p+"DATABASE_PASSWORD=pass\n"+
+		p+"SERVER_ENV=staging\n"+p+"SERVER_PORT=1000\n")
+	writeConfigFile(t, dir, "config.staging.yaml", "database:\n  host: staging-host\nserver:\n  port: 2000\n")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := conf.RegisterFlags(fs, p, &conf.ConfigVars{})
+	if err := fs.Parse([]string{"--server-port", "3000"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+
+	vars, err := conf.LoadConfigVars(context.Background(), conf.LoadOptions{Dir: dir, Flags: flags})
+	if err != nil {
+		t.Fatalf("LoadConfigVars returned error: %v", err)
+	}
+
+	if vars.Database.DatabaseHost != "staging-host" {
+		t.Errorf("DatabaseHost = %q, want value from config.staging.yaml", vars.Database.DatabaseHost)
+	}
+	if vars.Server.Port != "3000" {
+		t.Errorf("Port = %q, want value from flag", vars.Server.Port)
+	}
+	if vars.Origins[p+"SERVER_PORT"] != "flag" {
+		t.Errorf("SERVER_PORT origin = %q, want flag", vars.Origins[p+"SERVER_PORT"])
+	}
+	if vars.Origins[p+"DATABASE_HOST"] != "file:config.staging.yaml" {
+		t.Errorf("DATABASE_HOST origin = %q", vars.Origins[p+"DATABASE_HOST"])
+	}
+	if vars.Origins[p+"DATABASE_NAME"] != "file:.env" {
+		t.Errorf("DATABASE_NAME origin = %q", vars.Origins[p+"DATABASE_NAME"])
+	}
+}
@@ -0,0 +1,31 @@
+package health_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	health "{{.Module}}/internal/health/service"
+	"{{.Module}}/internal/shared/buildinfo"
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/tests/testutil"
+)
+
+func TestGetHealth_UnhealthyKeepsBuildAndConfigInfo(t *testing.T) {
+	// A pool that isn't a *sql.DB can't be pinged, so the database reports unhealthy
+	db := testutil.OpenDB(t, &testutil.Pool{})
+	log := logger.NewLogger(&logger.Config{Level: slog.LevelError, Writer: io.Discard})
+	service := health.NewService(log, db, func() string { return "cfg_1" }, nil)
+
+	status, err := service.GetHealth(context.Background())
+	if err == nil {
+		t.Fatal("GetHealth() returned no error for an unreachable database")
+	}
+	if status.Status != "unhealthy" || status.Services["database"] != "unhealthy" {
+		t.Errorf("status = %+v, want the database unhealthy", status)
+	}
+	if status.Version != buildinfo.Get().Version || status.Uptime == "" || status.ConfigVersion != "cfg_1" {
+		t.Errorf("status = %+v, want the version, uptime and config version", status)
+	}
+}
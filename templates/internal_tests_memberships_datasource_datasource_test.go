@@ -0,0 +1,123 @@
+package datasource_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"{{.Module}}/internal/memberships/datasource"
+	"{{.Module}}/internal/memberships/models"
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/tests/testutil"
+)
+
+func setup(t *testing.T) (datasource.MembershipsDatasource, *testutil.Pool) {
+	t.Helper()
+	pool := &testutil.Pool{}
+	db := testutil.OpenDB(t, pool)
+
+	log := logger.NewLogger(&logger.Config{Level: slog.LevelError, Writer: io.Discard})
+	return datasource.NewDatasource(log, db), pool
+}
+
+func TestUpsertMembership(t *testing.T) {
+	ds, pool := setup(t)
+
+	ds.UpsertMembership(context.Background(), &models.Membership{
+		ClerkMembershipID: "orgmem_1",
+		UserID:            "usr_1",
+		OrganizationID:    "org_1",
+		Role:              "org:admin",
+	})
+
+	statement := pool.Last()
+	for _, want := range []string{
+		`INSERT INTO "organization_memberships"`,
+		`ON CONFLICT ("user_id","organization_id") DO UPDATE SET "clerk_membership_id"="excluded"."clerk_membership_id","role"="excluded"."role"`,
+		"RETURNING",
+	} {
+		if !strings.Contains(statement, want) {
+			t.Errorf("statement = %q, want it to contain %q", statement, want)
+		}
+	}
+	if strings.Contains(statement, `INSERT INTO "users"`) || strings.Contains(statement, `INSERT INTO "organizations"`) {
+		t.Errorf("statement = %q, want associations left alone", statement)
+	}
+}
+
+func TestListMemberships_SkipSoftDeleted(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(ds datasource.MembershipsDatasource) error
+		want []string
+	}{
+		{
+			name: "organization members",
+			run: func(ds datasource.MembershipsDatasource) error {
+				_, err := ds.ListMembershipsByOrganizationID(context.Background(), "org_1")
+				return err
+			},
+			want: []string{`INNER JOIN "users" "User"`, `"User"."deleted_at" IS NULL`, "organization_memberships.organization_id = $1"},
+		},
+		{
+			name: "user organizations",
+			run: func(ds datasource.MembershipsDatasource) error {
+				_, err := ds.ListMembershipsByUserID(context.Background(), "usr_1")
+				return err
+			},
+			want: []string{`INNER JOIN "organizations" "Organization"`, `"Organization"."deleted_at" IS NULL`, "organization_memberships.user_id = $1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, pool := setup(t)
+			tt.run(ds)
+
+			statement := pool.Last()
+			for _, want := range tt.want {
+				if !strings.Contains(statement, want) {
+					t.Errorf("statement = %q, want it to contain %q", statement, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteMembershipByClerkID(t *testing.T) {
+	ds, pool := setup(t)
+
+	deleted, err := ds.DeleteMembershipByClerkID(context.Background(), "orgmem_1")
+	if err != nil || !deleted {
+		t.Fatalf("DeleteMembershipByClerkID() = %v, %v, want true, nil", deleted, err)
+	}
+	if statement := pool.Last(); !strings.HasPrefix(statement, `DELETE FROM "organization_memberships" WHERE clerk_membership_id = $1`) {
+		t.Errorf("statement = %q, want a delete by clerk membership id", statement)
+	}
+
+	if _, err := ds.DeleteMembershipByClerkID(context.Background(), ""); err == nil {
+		t.Error("DeleteMembershipByClerkID(\"\") error = nil, want an error")
+	}
+}
+
+func TestSharesOrganization(t *testing.T) {
+	ds, pool := setup(t)
+	ds.SharesOrganization(context.Background(), "usr_1", "usr_2")
+
+	statement := pool.Last()
+	for _, want := range []string{
+		"JOIN organization_memberships other ON other.organization_id = organization_memberships.organization_id",
+		"organizations.deleted_at IS NULL",
+		"organization_memberships.user_id = $1 AND other.user_id = $2",
+	} {
+		if !strings.Contains(statement, want) {
+			t.Errorf("statement = %q, want it to contain %q", statement, want)
+		}
+	}
+
+	if _, err := ds.SharesOrganization(context.Background(), "usr_1", ""); err == nil {
+		t.Error("SharesOrganization(\"usr_1\", \"\") error = nil, want an error")
+	}
+}
@@ -0,0 +1,202 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	membershipsDatasource "{{.Module}}/internal/memberships/datasource"
+	"{{.Module}}/internal/memberships/models"
+	memberships "{{.Module}}/internal/memberships/service"
+	organizationsDatasource "{{.Module}}/internal/organizations/datasource"
+	organizationsModels "{{.Module}}/internal/organizations/models"
+	httpHelpers "{{.Module}}/internal/shared/http"
+	"{{.Module}}/internal/shared/logger"
+	usersDatasource "{{.Module}}/internal/users/datasource"
+	usersModels "{{.Module}}/internal/users/models"
+
+	"gorm.io/gorm"
+)
+
+type fakeUsers struct {
+	usersDatasource.UsersDatasource
+	users map[string]*usersModels.User // by Clerk user ID
+}
+
+func (d *fakeUsers) GetUserByClerkUserID(ctx context.Context, clerkUserID string) (*usersModels.User, error) {
+	user, ok := d.users[clerkUserID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+type fakeOrganizations struct {
+	organizationsDatasource.OrganizationsDatasource
+	orgs map[string]*organizationsModels.Organization // by Clerk org ID
+}
+
+func (d *fakeOrganizations) GetOrganizationByClerkOrgID(ctx context.Context, clerkOrgID string) (*organizationsModels.Organization, error) {
+	org, ok := d.orgs[clerkOrgID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return org, nil
+}
+
+type fakeMemberships struct {
+	membershipsDatasource.MembershipsDatasource
+	memberships []models.Membership
+}
+
+// UpsertMembership keeps the stored ID when the user already belongs to the organization
+func (d *fakeMemberships) UpsertMembership(ctx context.Context, membership *models.Membership) (*models.Membership, error) {
+	for i, m := range d.memberships {
+		if m.UserID == membership.UserID && m.OrganizationID == membership.OrganizationID {
+			membership.ID = m.ID
+			d.memberships[i] = *membership
+			return membership, nil
+		}
+	}
+	d.memberships = append(d.memberships, *membership)
+	return membership, nil
+}
+
+func (d *fakeMemberships) GetMembership(ctx context.Context, userID string, orgID string) (*models.Membership, error) {
+	for _, m := range d.memberships {
+		if m.UserID == userID && m.OrganizationID == orgID {
+			return &m, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (d *fakeMemberships) ListMembershipsByOrganizationID(ctx context.Context, orgID string) ([]models.Membership, error) {
+	var found []models.Membership
+	for _, m := range d.memberships {
+		if m.OrganizationID == orgID {
+			found = append(found, m)
+		}
+	}
+	return found, nil
+}
+
+func (d *fakeMemberships) ListMembershipsByUserID(ctx context.Context, userID string) ([]models.Membership, error) {
+	var found []models.Membership
+	for _, m := range d.memberships {
+		if m.UserID == userID {
+			found = append(found, m)
+		}
+	}
+	return found, nil
+}
+
+// setup has usr_1 in org_1 and usr_2 in org_2
+func setup() memberships.MembershipsService {
+	service, _ := setupWithData()
+	return service
+}
+
+func setupWithData() (memberships.MembershipsService, *fakeMemberships) {
+	log := logger.NewLogger(&logger.Config{Level: slog.LevelError, Writer: io.Discard})
+	users := &fakeUsers{users: map[string]*usersModels.User{
+		"clerk_1": {ID: "usr_1"},
+		"clerk_2": {ID: "usr_2"},
+	}}
+	data := &fakeMemberships{memberships: []models.Membership{
+		{UserID: "usr_1", OrganizationID: "org_1", Role: models.RoleAdmin},
+		{UserID: "usr_2", OrganizationID: "org_2", Role: models.RoleMember},
+	}}
+	orgs := &fakeOrganizations{orgs: map[string]*organizationsModels.Organization{
+		"org_clerk_1": {ID: "org_1", ClerkOrgID: "org_clerk_1"},
+		"org_clerk_2": {ID: "org_2", ClerkOrgID: "org_clerk_2"},
+	}}
+	return memberships.NewService(log, data, users, orgs), data
+}
+
+func membershipRequest(event, role string) *models.ClerkMembershipRequest {
+	return &models.ClerkMembershipRequest{
+		Type:      event,
+		Object:    "event",
+		Timestamp: 1,
+		Data: models.MembershipData{
+			ID:             "orgmem_1",
+			Role:           role,
+			Organization:   models.MembershipOrg{ID: "org_clerk_1"},
+			PublicUserData: models.MembershipPublicUser{UserID: "clerk_2"},
+			CreatedAt:      1,
+			UpdatedAt:      1,
+		},
+	}
+}
+
+func TestSaveMembership_CreatedAndUpdated(t *testing.T) {
+	service, data := setupWithData()
+
+	created, err := service.SaveMembership(context.Background(), membershipRequest("organizationMembership.created", models.RoleMember))
+	if err != nil {
+		t.Fatalf("SaveMembership(created) error = %v", err)
+	}
+	if created.ID == "" || created.UserID != "usr_2" || created.OrganizationID != "org_1" || created.Role != models.RoleMember {
+		t.Errorf("created membership = %+v, want usr_2 as a member of org_1", created)
+	}
+
+	updated, err := service.SaveMembership(context.Background(), membershipRequest("organizationMembership.updated", models.RoleAdmin))
+	if err != nil {
+		t.Fatalf("SaveMembership(updated) error = %v", err)
+	}
+	if updated.ID != created.ID || !updated.IsAdmin() {
+		t.Errorf("updated membership = %+v, want %s promoted to admin", updated, created.ID)
+	}
+	if len(data.memberships) != 3 {
+		t.Errorf("memberships = %+v, want the one membership added", data.memberships)
+	}
+}
+
+func TestSaveMembership_UnknownUser(t *testing.T) {
+	service := setup()
+	request := membershipRequest("organizationMembership.created", models.RoleMember)
+	request.Data.PublicUserData.UserID = "clerk_unknown"
+
+	if _, err := service.SaveMembership(context.Background(), request); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("SaveMembership() error = %v, want %v so the webhook is retried", err, gorm.ErrRecordNotFound)
+	}
+}
+
+func assertStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var httpErr *httpHelpers.Error
+	if !errors.As(err, &httpErr) || httpErr.Status != status {
+		t.Errorf("error = %v, want status %d", err, status)
+	}
+}
+
+func TestListOrganizationMembers(t *testing.T) {
+	service := setup()
+
+	members, err := service.ListOrganizationMembers(context.Background(), "clerk_1", "org_1")
+	if err != nil || len(members) != 1 {
+		t.Fatalf("ListOrganizationMembers(member) = %+v, %v, want the one member", members, err)
+	}
+
+	_, err = service.ListOrganizationMembers(context.Background(), "clerk_1", "org_2")
+	assertStatus(t, err, http.StatusForbidden)
+
+	_, err = service.ListOrganizationMembers(context.Background(), "clerk_unknown", "org_1")
+	assertStatus(t, err, http.StatusForbidden)
+}
+
+func TestListUserOrganizations(t *testing.T) {
+	service := setup()
+
+	organizations, err := service.ListUserOrganizations(context.Background(), "clerk_1", "usr_1")
+	if err != nil || len(organizations) != 1 {
+		t.Fatalf("ListUserOrganizations(self) = %+v, %v, want the one organization", organizations, err)
+	}
+
+	_, err = service.ListUserOrganizations(context.Background(), "clerk_1", "usr_2")
+	assertStatus(t, err, http.StatusForbidden)
+}
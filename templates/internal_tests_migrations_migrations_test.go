@@ -0,0 +1,149 @@
+package migrations_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"{{.Module}}/internal/migrations"
+)
+
+func TestEmbedded(t *testing.T) {
+	embedded, err := migrations.Embedded()
+	if err != nil {
+		t.Fatalf("Embedded() error = %v", err)
+	}
+	if len(embedded) < 2 {
+		t.Fatalf("Embedded() returned %d migrations, want at least 2", len(embedded))
+	}
+
+	for i, migration := range embedded {
+		if migration.Version != int64(i+1) {
+			t.Errorf("migration %d has version %d, want consecutive versions", i, migration.Version)
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("migration %06d_%s has no down SQL", migration.Version, migration.Name)
+		}
+	}
+
+	// The initial schema must keep the unique indexes the models rely on
+	var schema strings.Builder
+	for _, migration := range embedded {
+		schema.WriteString(migration.Up)
+	}
+	for _, constraint := range []string{
+		"uni_organizations_clerk_org_id",
+		"uni_organizations_slug",
+		"uni_users_clerk_user_id",
+		"uni_users_email",
+	} {
+		if !strings.Contains(schema.String(), constraint) {
+			t.Errorf("initial migrations do not create %s", constraint)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000002_add_posts.up.sql":   {Data: []byte("CREATE TABLE posts (id TEXT);")},
+		"000002_add_posts.down.sql": {Data: []byte("DROP TABLE posts;")},
+		"000001_init.up.sql":        {Data: []byte("CREATE TABLE things (id TEXT);")},
+		"README.md":                 {Data: []byte("ignored")},
+	}
+
+	loaded, err := migrations.Load(fsys)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("Load() returned %d migrations, want 2", len(loaded))
+	}
+	if loaded[0].Version != 1 || loaded[0].Name != "init" || loaded[0].Down != "" {
+		t.Errorf("first migration = %+v, want 000001_init without down SQL", loaded[0])
+	}
+	if loaded[1].Version != 2 || loaded[1].Down != "DROP TABLE posts;" {
+		t.Errorf("second migration = %+v, want 000002_add_posts with down SQL", loaded[1])
+	}
+	if loaded[0].Checksum == "" || loaded[0].Checksum == loaded[1].Checksum {
+		t.Errorf("checksums = %q, %q, want distinct values", loaded[0].Checksum, loaded[1].Checksum)
+	}
+}
+
+func TestLoad_ChecksumTracksUpSQL(t *testing.T) {
+	load := func(up string) string {
+		loaded, err := migrations.Load(fstest.MapFS{"000001_init.up.sql": {Data: []byte(up)}})
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		return loaded[0].Checksum
+	}
+
+	if load("CREATE TABLE a (id TEXT);") != load("CREATE TABLE a (id TEXT);") {
+		t.Error("checksum differs for identical SQL")
+	}
+	if load("CREATE TABLE a (id TEXT);") == load("CREATE TABLE a (id BIGINT);") {
+		t.Error("checksum unchanged after editing the SQL")
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"invalid name", fstest.MapFS{"init.sql": {Data: []byte("SELECT 1;")}}},
+		{"missing up", fstest.MapFS{"000001_init.down.sql": {Data: []byte("SELECT 1;")}}},
+		{"conflicting names", fstest.MapFS{
+			"000001_init.up.sql":  {Data: []byte("SELECT 1;")},
+			"000001_other.up.sql": {Data: []byte("SELECT 1;")},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := migrations.Load(tt.fsys); err == nil {
+				t.Error("Load() error = nil, want an error")
+			}
+		})
+	}
+
+	_, err := migrations.Load(fstest.MapFS{"init.sql": {Data: []byte("SELECT 1;")}})
+	if !errors.Is(err, migrations.ErrInvalidFilename) {
+		t.Errorf("Load() error = %v, want ErrInvalidFilename", err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sql")
+
+	up, down, err := migrations.Create(dir, "Create Users")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if filepath.Base(up) != "000001_create_users.up.sql" || filepath.Base(down) != "000001_create_users.down.sql" {
+		t.Errorf("Create() = %s, %s, want 000001_create_users files", up, down)
+	}
+
+	up, _, err = migrations.Create(dir, "add-posts")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if filepath.Base(up) != "000002_add_posts.up.sql" {
+		t.Errorf("Create() = %s, want the next version", up)
+	}
+
+	loaded, err := migrations.Load(os.DirFS(dir))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Errorf("Load() returned %d migrations, want 2", len(loaded))
+	}
+
+	if _, _, err := migrations.Create(dir, "  "); !errors.Is(err, migrations.ErrInvalidFilename) {
+		t.Errorf("Create() with an empty name error = %v, want ErrInvalidFilename", err)
+	}
+}
@@ -0,0 +1,53 @@
+package models_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"{{.Module}}/internal/organizations/models"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Acme":                   "acme",
+		"Acme Café, Inc.":        "acme-cafe-inc",
+		"  --Hello   World--  ":  "hello-world",
+		"R&D 2024":               "r-d-2024",
+		"日本":                     "",
+		strings.Repeat("a ", 40): strings.TrimSuffix(strings.Repeat("a-", 32), "-"),
+	}
+
+	for name, want := range tests {
+		if got := models.Slugify(name); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestValidateSlug(t *testing.T) {
+	for _, slug := range []string{"acme", "acme-2", "a1"} {
+		if err := models.ValidateSlug(slug); err != nil {
+			t.Errorf("ValidateSlug(%q) error = %v", slug, err)
+		}
+	}
+	for _, slug := range []string{"", "a", "Acme", "acme--inc", "-acme", "acme-", "acme_inc", strings.Repeat("a", models.SlugMaxLength+1)} {
+		if err := models.ValidateSlug(slug); err == nil {
+			t.Errorf("ValidateSlug(%q) accepted an invalid slug", slug)
+		}
+	}
+}
+
+func TestSuggestSlugs(t *testing.T) {
+	got := models.SuggestSlugs("acme", []string{"acme", "acme-2", "acme-4"}, 3)
+	if want := []string{"acme-3", "acme-5", "acme-6"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SuggestSlugs() = %v, want %v", got, want)
+	}
+
+	long := strings.Repeat("a", models.SlugMaxLength)
+	for _, suggestion := range models.SuggestSlugs(long, []string{long}, 2) {
+		if err := models.ValidateSlug(suggestion); err != nil {
+			t.Errorf("SuggestSlugs() returned invalid slug %q: %v", suggestion, err)
+		}
+	}
+}
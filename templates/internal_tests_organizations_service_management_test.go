@@ -0,0 +1,559 @@
+package organizations_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	auditModels "{{.Module}}/internal/audit/models"
+	auditService "{{.Module}}/internal/audit/service"
+	membershipsDatasource "{{.Module}}/internal/memberships/datasource"
+	membershipsModels "{{.Module}}/internal/memberships/models"
+	organizationsDatasource "{{.Module}}/internal/organizations/datasource"
+	"{{.Module}}/internal/organizations/identity"
+	"{{.Module}}/internal/organizations/models"
+	organizations "{{.Module}}/internal/organizations/service"
+	"{{.Module}}/internal/shared/concurrency"
+	httpHelpers "{{.Module}}/internal/shared/http"
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/transaction"
+	usersDatasource "{{.Module}}/internal/users/datasource"
+	usersModels "{{.Module}}/internal/users/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// txPool only opens transactions; the fake datasources never query it
+type txPool struct {
+	rollbacks int
+}
+
+func (p *txPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *txPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+func (p *txPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *txPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *txPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return &txConn{txPool: p}, nil
+}
+
+type txConn struct {
+	*txPool
+}
+
+func (tx *txConn) Commit() error { return nil }
+
+func (tx *txConn) Rollback() error {
+	tx.rollbacks++
+	return nil
+}
+
+// fakeProvider records what the service pushes to the identity provider
+type fakeProvider struct {
+	created []identity.CreateParams
+	updated []string
+	deleted []string
+	err     error
+}
+
+func (p *fakeProvider) CreateOrganization(ctx context.Context, params identity.CreateParams) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	p.created = append(p.created, params)
+	return "org_clerk_new", nil
+}
+
+func (p *fakeProvider) UpdateOrganization(ctx context.Context, providerID string, params identity.UpdateParams) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.updated = append(p.updated, providerID)
+	return nil
+}
+
+func (p *fakeProvider) DeleteOrganization(ctx context.Context, providerID string) error {
+	p.deleted = append(p.deleted, providerID)
+	return nil
+}
+
+// uniqueViolationError is the error PostgreSQL returns for a unique index conflict
+type uniqueViolationError struct{}
+
+func (uniqueViolationError) Error() string    { return "duplicate key value violates unique constraint" }
+func (uniqueViolationError) SQLState() string { return "23505" }
+
+type fakeOrganizations struct {
+	organizationsDatasource.OrganizationsDatasource
+	orgs      map[string]*models.Organization
+	updateErr error
+	// racing is committed by a concurrent transaction during the next create,
+	// which then fails on the slug
+	racing *models.Organization
+}
+
+func (d *fakeOrganizations) CreateOrganization(ctx context.Context, org *models.Organization) (*models.Organization, error) {
+	if d.racing != nil {
+		d.orgs[d.racing.ID] = d.racing
+		d.racing = nil
+		return nil, uniqueViolationError{}
+	}
+	stored := *org
+	d.orgs[org.ID] = &stored
+	return org, nil
+}
+
+func (d *fakeOrganizations) GetOrganizationByClerkOrgID(ctx context.Context, clerkOrgID string) (*models.Organization, error) {
+	for _, org := range d.orgs {
+		if org.ClerkOrgID == clerkOrgID {
+			copied := *org
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (d *fakeOrganizations) UpdateOrganization(ctx context.Context, org *models.Organization) (bool, error) {
+	for id, stored := range d.orgs {
+		if stored.ClerkOrgID == org.ClerkOrgID {
+			updated := *org
+			updated.Version = stored.Version + 1
+			d.orgs[id] = &updated
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (d *fakeOrganizations) GetOrganizationByID(ctx context.Context, id string) (*models.Organization, error) {
+	org, ok := d.orgs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *org
+	return &copied, nil
+}
+
+// ListTakenSlugs matches like the datasource: slug and the "slug-" prefixed slugs
+func (d *fakeOrganizations) ListTakenSlugs(ctx context.Context, slug string) ([]string, error) {
+	var taken []string
+	for _, org := range d.orgs {
+		if org.Slug == slug || strings.HasPrefix(org.Slug, slug+"-") {
+			taken = append(taken, org.Slug)
+		}
+	}
+	return taken, nil
+}
+
+func (d *fakeOrganizations) FilterTakenSlugs(ctx context.Context, slugs []string) ([]string, error) {
+	var taken []string
+	for _, org := range d.orgs {
+		if slices.Contains(slugs, org.Slug) {
+			taken = append(taken, org.Slug)
+		}
+	}
+	return taken, nil
+}
+
+func (d *fakeOrganizations) UpdateOrganizationByID(ctx context.Context, id string, changes map[string]interface{}, version int64) (*models.Organization, error) {
+	if d.updateErr != nil {
+		return nil, d.updateErr
+	}
+	org, ok := d.orgs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if version > 0 && version != org.Version {
+		return nil, concurrency.ErrVersionConflict
+	}
+	org.Version++
+	for column, value := range changes {
+		switch column {
+		case "name":
+			org.Name = value.(string)
+		case "slug":
+			org.Slug = value.(string)
+		case "clerk_org_id":
+			org.ClerkOrgID = value.(string)
+		}
+	}
+	copied := *org
+	return &copied, nil
+}
+
+func (d *fakeOrganizations) DeleteOrganizationByClerkID(ctx context.Context, clerkID string) (bool, error) {
+	for id, org := range d.orgs {
+		if org.ClerkOrgID == clerkID {
+			delete(d.orgs, id)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type fakeUsers struct {
+	usersDatasource.UsersDatasource
+	users map[string]*usersModels.User // by Clerk user ID
+}
+
+func (d *fakeUsers) GetUserByClerkUserID(ctx context.Context, clerkUserID string) (*usersModels.User, error) {
+	user, ok := d.users[clerkUserID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (d *fakeUsers) UpdateUserOrganization(ctx context.Context, clerkUserID string, orgID string) (bool, error) {
+	return true, nil
+}
+
+type fakeMemberships struct {
+	membershipsDatasource.MembershipsDatasource
+	memberships []membershipsModels.Membership
+}
+
+func (d *fakeMemberships) UpsertMembership(ctx context.Context, membership *membershipsModels.Membership) (*membershipsModels.Membership, error) {
+	d.memberships = append(d.memberships, *membership)
+	return membership, nil
+}
+
+func (d *fakeMemberships) GetMembership(ctx context.Context, userID string, orgID string) (*membershipsModels.Membership, error) {
+	for _, m := range d.memberships {
+		if m.UserID == userID && m.OrganizationID == orgID {
+			return &m, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// fakeAudit keeps the recorded entries
+type fakeAudit struct {
+	auditService.AuditService
+	entries []auditModels.Entry
+}
+
+func (a *fakeAudit) Record(ctx context.Context, entry auditModels.Entry) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+type fixture struct {
+	service     organizations.OrganizationsService
+	orgs        *fakeOrganizations
+	memberships *fakeMemberships
+	provider    *fakeProvider
+	audit       *fakeAudit
+	pool        *txPool
+}
+
+// setup has an admin (user_admin) and a plain member (user_member) of org_1,
+// slug "acme", already known to the provider as org_clerk_1
+func setup(t *testing.T) *fixture {
+	t.Helper()
+	pool := &txPool{}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: pool}), &gorm.Config{
+		Logger:               gormLogger.Discard,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+	log := logger.NewLogger(&logger.Config{Level: slog.LevelError, Writer: io.Discard})
+
+	f := &fixture{
+		orgs: &fakeOrganizations{orgs: map[string]*models.Organization{
+			"org_1": {ID: "org_1", ClerkOrgID: "org_clerk_1", Name: "Acme", Slug: "acme", Version: 1},
+		}},
+		memberships: &fakeMemberships{memberships: []membershipsModels.Membership{
+			{UserID: "usr_admin", OrganizationID: "org_1", Role: membershipsModels.RoleAdmin},
+			{UserID: "usr_member", OrganizationID: "org_1", Role: membershipsModels.RoleMember},
+		}},
+		provider: &fakeProvider{},
+		audit:    &fakeAudit{},
+		pool:     pool,
+	}
+	users := &fakeUsers{users: map[string]*usersModels.User{
+		"user_admin":  {ID: "usr_admin", ClerkUserID: "user_admin"},
+		"user_member": {ID: "usr_member", ClerkUserID: "user_member"},
+	}}
+	manager := transaction.NewManager(log, db, transaction.Options{MaxAttempts: 1})
+	f.service = organizations.NewService(log, f.orgs, users, f.memberships, manager, f.provider, f.audit)
+	return f
+}
+
+func assertStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var httpErr *httpHelpers.Error
+	if !errors.As(err, &httpErr) || httpErr.Status != status {
+		t.Errorf("error = %v, want status %d", err, status)
+	}
+}
+
+func TestCreateOrganizationAsUser_PushesAndMakesCallerAdmin(t *testing.T) {
+	f := setup(t)
+
+	org, err := f.service.CreateOrganizationAsUser(context.Background(), "user_member", models.CreateOrganizationRequest{Name: "Acme"})
+	if err != nil {
+		t.Fatalf("CreateOrganizationAsUser() error = %v", err)
+	}
+
+	// "acme" is taken, so the derived slug gets a suffix
+	if org.Slug != "acme-2" || org.ClerkOrgID != "org_clerk_new" {
+		t.Errorf("organization = %+v, want slug acme-2 and the provider's ID", org)
+	}
+	if len(f.provider.created) != 1 || f.provider.created[0].CreatedBy != "user_member" || f.provider.created[0].Slug != "acme-2" {
+		t.Errorf("provider creates = %+v", f.provider.created)
+	}
+
+	membership, err := f.memberships.GetMembership(context.Background(), "usr_member", org.ID)
+	if err != nil || !membership.IsAdmin() {
+		t.Errorf("caller membership = %+v, %v, want an admin membership", membership, err)
+	}
+
+	// The event records the organization as stored, with the provider's ID
+	if len(f.audit.entries) != 1 {
+		t.Fatalf("audit entries = %+v, want one", f.audit.entries)
+	}
+	entry := f.audit.entries[0]
+	if entry.Action != auditModels.ActionOrganizationCreated || entry.ResourceID != org.ID || entry.Before != nil {
+		t.Errorf("audit entry = %+v, want organization.created for %s", entry, org.ID)
+	}
+	if after, ok := entry.After.(*models.Organization); !ok || after.ClerkOrgID != "org_clerk_new" {
+		t.Errorf("audited organization = %+v, want the provider's ID", entry.After)
+	}
+}
+
+func TestCreateOrganizationAsUser_RequestedSlugTaken(t *testing.T) {
+	f := setup(t)
+
+	_, err := f.service.CreateOrganizationAsUser(context.Background(), "user_member", models.CreateOrganizationRequest{Name: "Other", Slug: "acme"})
+
+	var slugErr *models.SlugTakenError
+	if !errors.As(err, &slugErr) || len(slugErr.Suggestions) != models.SlugSuggestions || slugErr.Suggestions[0] != "acme-2" {
+		t.Fatalf("error = %v, want a SlugTakenError with suggestions", err)
+	}
+	if len(f.provider.created) != 0 {
+		t.Error("a taken slug was pushed to the provider")
+	}
+}
+
+func TestCreateOrganizationAsUser_UndoesPushWhenStoreFails(t *testing.T) {
+	f := setup(t)
+	// Storing the provider's ID is the step after the push
+	f.orgs.updateErr = errors.New("connection reset")
+
+	if _, err := f.service.CreateOrganizationAsUser(context.Background(), "user_member", models.CreateOrganizationRequest{Name: "New Co"}); err == nil {
+		t.Fatal("CreateOrganizationAsUser() succeeded despite the failing store")
+	}
+	if len(f.provider.deleted) != 1 || f.provider.deleted[0] != "org_clerk_new" {
+		t.Errorf("provider deletes = %v, want the pushed organization removed", f.provider.deleted)
+	}
+	if f.pool.rollbacks != 1 {
+		t.Errorf("rollbacks = %d, want 1", f.pool.rollbacks)
+	}
+}
+
+func TestCreateOrganizationAsUser_DerivedSlugAtLengthLimit(t *testing.T) {
+	f := setup(t)
+	long := strings.Repeat("a", models.SlugMaxLength)
+	// The first suggestion truncates the slug, so it doesn't share its prefix
+	truncated := strings.Repeat("a", models.SlugMaxLength-2) + "-2"
+	f.orgs.orgs["org_long"] = &models.Organization{ID: "org_long", ClerkOrgID: "org_long", Slug: long}
+	f.orgs.orgs["org_truncated"] = &models.Organization{ID: "org_truncated", ClerkOrgID: "org_truncated", Slug: truncated}
+
+	org, err := f.service.CreateOrganizationAsUser(context.Background(), "user_member", models.CreateOrganizationRequest{Name: long})
+	if err != nil {
+		t.Fatalf("CreateOrganizationAsUser() error = %v", err)
+	}
+	if want := strings.Repeat("a", models.SlugMaxLength-2) + "-3"; org.Slug != want {
+		t.Errorf("slug = %q, want %q", org.Slug, want)
+	}
+
+	availability, err := f.service.CheckSlug(context.Background(), long)
+	if err != nil || slices.Contains(availability.Suggestions, truncated) {
+		t.Errorf("CheckSlug() = %+v, %v, want suggestions without %q", availability, err, truncated)
+	}
+}
+
+func TestCreateOrganization_WebhookMakesCreatorAdmin(t *testing.T) {
+	f := setup(t)
+
+	org, err := f.service.CreateOrganization(context.Background(), &models.ClerkOrganizationRequest{
+		Data: models.OrganizationData{ID: "org_clerk_2", Name: "Beta", Slug: "beta", CreatedBy: "user_member"},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrganization() error = %v", err)
+	}
+
+	membership, err := f.memberships.GetMembership(context.Background(), "usr_member", org.ID)
+	if err != nil || !membership.IsAdmin() {
+		t.Errorf("creator membership = %+v, %v, want an admin membership", membership, err)
+	}
+
+	// A creator not synced yet is made admin by the membership webhook
+	if _, err := f.service.CreateOrganization(context.Background(), &models.ClerkOrganizationRequest{
+		Data: models.OrganizationData{ID: "org_clerk_3", Name: "Gamma", Slug: "gamma", CreatedBy: "user_unknown"},
+	}); err != nil {
+		t.Errorf("CreateOrganization(unknown creator) error = %v", err)
+	}
+}
+
+func TestCreateOrganization_WebhookRacingTheAPICreate(t *testing.T) {
+	f := setup(t)
+	// The API stores and pushes Beta, and commits while the webhook inserts it
+	f.orgs.racing = &models.Organization{ID: "org_api", ClerkOrgID: "org_clerk_2", Name: "Beta", Slug: "beta", Version: 2}
+
+	org, err := f.service.CreateOrganization(context.Background(), &models.ClerkOrganizationRequest{
+		Data: models.OrganizationData{ID: "org_clerk_2", Name: "Beta Inc", Slug: "beta"},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrganization() error = %v", err)
+	}
+	if org.ID != "org_api" || org.Name != "Beta Inc" || len(f.orgs.orgs) != 2 {
+		t.Errorf("organization = %+v, want the API's row updated in place", org)
+	}
+	if len(f.audit.entries) != 1 || f.audit.entries[0].Action != auditModels.ActionOrganizationUpdated {
+		t.Errorf("audit entries = %+v, want one organization.updated", f.audit.entries)
+	}
+	if f.pool.rollbacks != 1 {
+		t.Errorf("rollbacks = %d, want the failed insert rolled back", f.pool.rollbacks)
+	}
+}
+
+func TestUpdateOrganizationAsUser_RequiresAdmin(t *testing.T) {
+	f := setup(t)
+	name := "Renamed"
+
+	_, err := f.service.UpdateOrganizationAsUser(context.Background(), "user_member", "org_1", models.UpdateOrganizationRequest{Name: &name}, 0)
+	assertStatus(t, err, http.StatusForbidden)
+
+	_, err = f.service.UpdateOrganizationAsUser(context.Background(), "user_unknown", "org_1", models.UpdateOrganizationRequest{Name: &name}, 0)
+	assertStatus(t, err, http.StatusForbidden)
+
+	if _, err = f.service.UpdateOrganizationAsUser(context.Background(), "user_admin", "org_missing", models.UpdateOrganizationRequest{Name: &name}, 0); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("error = %v, want gorm.ErrRecordNotFound for a missing organization", err)
+	}
+	if len(f.provider.updated) != 0 {
+		t.Error("a refused update was pushed to the provider")
+	}
+	if len(f.audit.entries) != 0 {
+		t.Errorf("audit entries = %+v, want none for refused updates", f.audit.entries)
+	}
+}
+
+func TestUpdateOrganizationAsUser_Pushes(t *testing.T) {
+	f := setup(t)
+	name, slug := "Acme Labs", "acme-labs"
+
+	org, err := f.service.UpdateOrganizationAsUser(context.Background(), "user_admin", "org_1", models.UpdateOrganizationRequest{Name: &name, Slug: &slug}, 1)
+	if err != nil {
+		t.Fatalf("UpdateOrganizationAsUser() error = %v", err)
+	}
+	if org.Name != name || org.Slug != slug {
+		t.Errorf("organization = %+v, want the new name and slug", org)
+	}
+	if len(f.provider.updated) != 1 || f.provider.updated[0] != "org_clerk_1" {
+		t.Errorf("provider updates = %v, want org_clerk_1", f.provider.updated)
+	}
+
+	if len(f.audit.entries) != 1 {
+		t.Fatalf("audit entries = %+v, want one", f.audit.entries)
+	}
+	entry := f.audit.entries[0]
+	before, ok := entry.Before.(*models.Organization)
+	if entry.Action != auditModels.ActionOrganizationUpdated || !ok || before.Slug != "acme" || entry.After.(*models.Organization).Slug != slug {
+		t.Errorf("audit entry = %+v, want organization.updated from acme to %s", entry, slug)
+	}
+}
+
+func TestUpdateOrganizationAsUser_StaleVersion(t *testing.T) {
+	f := setup(t)
+	name := "Acme Labs"
+
+	if _, err := f.service.UpdateOrganizationAsUser(context.Background(), "user_admin", "org_1", models.UpdateOrganizationRequest{Name: &name}, 1); err != nil {
+		t.Fatalf("UpdateOrganizationAsUser() error = %v", err)
+	}
+
+	// The first update moved org_1 to version 2, so requests at version 1 are
+	// stale, whether or not they change anything
+	other := "Acme Research"
+	requests := []models.UpdateOrganizationRequest{
+		{Name: &other},
+		{Name: &name},
+	}
+	for _, request := range requests {
+		_, err := f.service.UpdateOrganizationAsUser(context.Background(), "user_admin", "org_1", request, 1)
+		if !errors.Is(err, concurrency.ErrVersionConflict) {
+			t.Errorf("error = %v, want concurrency.ErrVersionConflict", err)
+		}
+	}
+	if len(f.provider.updated) != 1 {
+		t.Errorf("provider updates = %v, want only the first update pushed", f.provider.updated)
+	}
+}
+
+func TestUpdateOrganizationAsUser_ProviderFailureRollsBack(t *testing.T) {
+	f := setup(t)
+	f.provider.err = errors.New("clerk unavailable")
+	name := "Acme Labs"
+
+	_, err := f.service.UpdateOrganizationAsUser(context.Background(), "user_admin", "org_1", models.UpdateOrganizationRequest{Name: &name}, 0)
+	assertStatus(t, err, http.StatusBadGateway)
+	if f.pool.rollbacks != 1 {
+		t.Errorf("rollbacks = %d, want 1", f.pool.rollbacks)
+	}
+}
+
+func TestDeleteOrganizationAsUser(t *testing.T) {
+	f := setup(t)
+
+	assertStatus(t, f.service.DeleteOrganizationAsUser(context.Background(), "user_member", "org_1"), http.StatusForbidden)
+
+	if err := f.service.DeleteOrganizationAsUser(context.Background(), "user_admin", "org_1"); err != nil {
+		t.Fatalf("DeleteOrganizationAsUser() error = %v", err)
+	}
+	if len(f.provider.deleted) != 1 || f.provider.deleted[0] != "org_clerk_1" {
+		t.Errorf("provider deletes = %v, want org_clerk_1", f.provider.deleted)
+	}
+	if len(f.audit.entries) != 1 || f.audit.entries[0].Action != auditModels.ActionOrganizationDeleted || f.audit.entries[0].After != nil {
+		t.Errorf("audit entries = %+v, want one organization.deleted", f.audit.entries)
+	}
+}
+
+func TestCheckSlug(t *testing.T) {
+	f := setup(t)
+
+	taken, err := f.service.CheckSlug(context.Background(), "acme")
+	if err != nil || taken.Available || len(taken.Suggestions) == 0 {
+		t.Errorf("CheckSlug(acme) = %+v, %v, want unavailable with suggestions", taken, err)
+	}
+
+	free, err := f.service.CheckSlug(context.Background(), "globex")
+	if err != nil || !free.Available || free.Suggestions != nil {
+		t.Errorf("CheckSlug(globex) = %+v, %v, want available", free, err)
+	}
+
+	_, err = f.service.CheckSlug(context.Background(), "Not A Slug")
+	assertStatus(t, err, http.StatusBadRequest)
+}
@@ -0,0 +1,138 @@
+package admin_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"{{.Module}}/internal/shared/admin"
+)
+
+func TestNewHandler_RequiresToken(t *testing.T) {
+	handler := admin.NewHandler(admin.Options{Token: "s3cret-admin-token"})
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{"no token", nil, http.StatusUnauthorized},
+		{"wrong token", map[string]string{"Authorization": "Bearer wrong"}, http.StatusUnauthorized},
+		{"bearer token", map[string]string{"Authorization": "Bearer s3cret-admin-token"}, http.StatusOK},
+		{"admin token header", map[string]string{"X-Admin-Token": "s3cret-admin-token"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestNewHandler_EmptyTokenRejectsEverything(t *testing.T) {
+	handler := admin.NewHandler(admin.Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestNewHandler_Endpoints(t *testing.T) {
+	handler := admin.NewHandler(admin.Options{
+		Token: "token",
+		DBStats: func() sql.DBStats {
+			return sql.DBStats{MaxOpenConnections: 10, OpenConnections: 3, InUse: 1, Idle: 2, WaitDuration: time.Second}
+		},
+	})
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/goroutine?debug=1", "/debug/vars", "/debug/runtime"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("X-Admin-Token", "token")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("GET %s status = %d, want %d", path, rr.Code, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestNewHandler_RuntimeStats(t *testing.T) {
+	handler := admin.NewHandler(admin.Options{
+		Token: "token",
+		DBStats: func() sql.DBStats {
+			return sql.DBStats{MaxOpenConnections: 10, OpenConnections: 3, InUse: 1, Idle: 2}
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/runtime", nil)
+	req.Header.Set("X-Admin-Token", "token")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var stats admin.RuntimeStats
+	if err := json.NewDecoder(rr.Body).Decode(&stats); err != nil {
+		t.Fatalf("failed to decode runtime stats: %v", err)
+	}
+	if stats.Goroutines <= 0 {
+		t.Errorf("goroutines = %d, want > 0", stats.Goroutines)
+	}
+	if stats.Build.GoVersion == "" {
+		t.Error("build go_version is empty")
+	}
+	if stats.Database == nil || stats.Database.OpenConnections != 3 || stats.Database.InUse != 1 {
+		t.Errorf("database stats = %+v, want the pool stats", stats.Database)
+	}
+}
+
+func TestCollectRuntimeStats_WithoutDatabase(t *testing.T) {
+	stats := admin.CollectRuntimeStats(nil)
+	if stats.Database != nil {
+		t.Errorf("database stats = %+v, want nil", stats.Database)
+	}
+	if stats.Memory.SysBytes == 0 {
+		t.Error("memory sys_bytes is zero")
+	}
+}
+
+func TestNewHandler_MountsAPI(t *testing.T) {
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := admin.NewHandler(admin.Options{Token: "token", API: api})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users/deleted", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("status without token = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/users/deleted", nil)
+	req.Header.Set("X-Admin-Token", "token")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusTeapot || rr.Header().Get("X-Path") != "/admin/users/deleted" {
+		t.Errorf("status = %d, path = %q, want the API to serve /admin/users/deleted", rr.Code, rr.Header().Get("X-Path"))
+	}
+}
@@ -0,0 +1,53 @@
+package buildinfo_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"{{.Module}}/internal/shared/buildinfo"
+)
+
+func TestGet_UsesInjectedValues(t *testing.T) {
+	defer func(version, commit, buildTime string) {
+		buildinfo.Version, buildinfo.Commit, buildinfo.BuildTime = version, commit, buildTime
+	}(buildinfo.Version, buildinfo.Commit, buildinfo.BuildTime)
+
+	buildinfo.Version = "v1.4.2"
+	buildinfo.Commit = "0123456789abcdef"
+	buildinfo.BuildTime = "2024-05-01T10:00:00Z"
+
+	info := buildinfo.Get()
+	if info.Version != "v1.4.2" || info.Commit != "0123456789abcdef" || info.BuildTime != "2024-05-01T10:00:00Z" {
+		t.Errorf("Get() = %+v, want the injected values", info)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("GoVersion = %s, want %s", info.GoVersion, runtime.Version())
+	}
+}
+
+func TestGet_FallsBackWhenNotInjected(t *testing.T) {
+	defer func(version, commit, buildTime string) {
+		buildinfo.Version, buildinfo.Commit, buildinfo.BuildTime = version, commit, buildTime
+	}(buildinfo.Version, buildinfo.Commit, buildinfo.BuildTime)
+
+	buildinfo.Version, buildinfo.Commit, buildinfo.BuildTime = "", "", ""
+
+	// Test binaries carry no VCS settings, so every gap gets a placeholder
+	info := buildinfo.Get()
+	if info.Version == "" || info.Commit == "" || info.CommitTime == "" {
+		t.Errorf("Get() = %+v, want no empty fields", info)
+	}
+	// The commit time is never passed off as the build time
+	if info.BuildTime != buildinfo.Unknown {
+		t.Errorf("BuildTime = %q, want %q when not injected", info.BuildTime, buildinfo.Unknown)
+	}
+}
+
+func TestUptime(t *testing.T) {
+	first := buildinfo.Uptime()
+	time.Sleep(time.Millisecond)
+	if second := buildinfo.Uptime(); second <= first {
+		t.Errorf("Uptime() = %s after %s, want it to grow", second, first)
+	}
+}
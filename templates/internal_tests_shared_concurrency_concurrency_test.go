@@ -0,0 +1,78 @@
+package concurrency_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"{{.Module}}/internal/shared/concurrency"
+	httpHelpers "{{.Module}}/internal/shared/http"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		version int64
+		status  int // 0 for no error
+	}{
+		{name: "absent", header: ""},
+		{name: "any", header: "*"},
+		{name: "our tag", header: `"7"`, version: 7},
+		{name: "weak tag", header: `W/"7"`, status: http.StatusPreconditionFailed},
+		{name: "foreign tag", header: `"abc"`, status: http.StatusPreconditionFailed},
+		{name: "unquoted", header: "7", status: http.StatusPreconditionFailed},
+		{name: "several tags", header: `"6", "7"`, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/users/me", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+
+			version, err := concurrency.IfMatch(r)
+			if tt.status == 0 {
+				if err != nil || version != tt.version {
+					t.Errorf("IfMatch() = %d, %v, want %d", version, err, tt.version)
+				}
+				return
+			}
+			var httpErr *httpHelpers.Error
+			if !errors.As(err, &httpErr) || httpErr.Status != tt.status {
+				t.Errorf("IfMatch() error = %v, want status %d", err, tt.status)
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: `"3"`, want: true},
+		{header: `W/"3"`, want: true},
+		{header: `"2", "3"`, want: true},
+		{header: "*", want: true},
+		{header: `"2"`, want: false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/users/me", nil)
+		if tt.header != "" {
+			r.Header.Set("If-None-Match", tt.header)
+		}
+		w := httptest.NewRecorder()
+
+		if got := concurrency.NotModified(w, r, 3); got != tt.want {
+			t.Errorf("NotModified(If-None-Match: %s) = %v, want %v", tt.header, got, tt.want)
+		}
+		if etag := w.Header().Get("ETag"); etag != `"3"` {
+			t.Errorf("ETag = %q, want %q", etag, `"3"`)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package http_test
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -423,3 +424,23 @@ func BenchmarkHandlerFunc_ServeHTTP(b *testing.B) {
 		handler.ServeHTTP(w, r)
 	}
 }
+
+func TestRespondWithError_Status(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	err := httpHelpers.RespondWithError(w, fmt.Errorf("parsing query: %w", httpHelpers.NewError(http.StatusBadRequest, "invalid limit")))
+	if err != nil {
+		t.Fatalf("RespondWithError returned error: %v", err)
+	}
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if body["error"] != "parsing query: invalid limit" {
+		t.Errorf("Expected the wrapped message, got %q", body["error"])
+	}
+}
@@ -0,0 +1,362 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"{{.Module}}/internal/shared/lifecycle"
+	"{{.Module}}/internal/shared/logger"
+)
+
+// recorder collects start and stop events in the order they happen
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) component(name string, dependsOn ...string) lifecycle.Component {
+	return lifecycle.Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(ctx context.Context) error {
+			r.record("start " + name)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			r.record("stop " + name)
+			return nil
+		},
+	}
+}
+
+func (r *recorder) record(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func newTestManager() *lifecycle.Manager {
+	return lifecycle.NewManager(logger.NewLogger(&logger.Config{Writer: io.Discard}), lifecycle.Options{})
+}
+
+func mustRegister(t *testing.T, m *lifecycle.Manager, components ...lifecycle.Component) {
+	t.Helper()
+	for _, component := range components {
+		if err := m.Register(component); err != nil {
+			t.Fatalf("Register(%s) returned error: %v", component.Name, err)
+		}
+	}
+}
+
+func TestManager_StartsInDependencyOrderAndStopsInReverse(t *testing.T) {
+	rec := &recorder{}
+	m := newTestManager()
+	mustRegister(t, m,
+		rec.component("worker", "http"),
+		rec.component("http", "database"),
+		rec.component("database"),
+	)
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+
+	want := []string{"start database", "start http", "start worker", "stop worker", "stop http", "stop database"}
+	if !reflect.DeepEqual(rec.events, want) {
+		t.Errorf("events = %v, want %v", rec.events, want)
+	}
+}
+
+func TestManager_FailedStartRollsBack(t *testing.T) {
+	rec := &recorder{}
+	m := newTestManager()
+	broken := rec.component("http", "database")
+	broken.Start = func(ctx context.Context) error {
+		return errors.New("port in use")
+	}
+	mustRegister(t, m, rec.component("database"), broken, rec.component("worker", "http"))
+
+	if err := m.Start(context.Background()); err == nil {
+		t.Fatal("expected Start to fail")
+	}
+
+	want := []string{"start database", "stop database"}
+	if !reflect.DeepEqual(rec.events, want) {
+		t.Errorf("events = %v, want %v", rec.events, want)
+	}
+}
+
+func TestManager_FailedStartCancelsWorkWithinShutdownTimeout(t *testing.T) {
+	m := lifecycle.NewManager(logger.NewLogger(&logger.Config{Writer: io.Discard}), lifecycle.Options{
+		ShutdownTimeout: 50 * time.Millisecond,
+	})
+	stopped := make(chan struct{})
+	mustRegister(t, m,
+		lifecycle.Component{
+			Name: "worker",
+			Start: func(ctx context.Context) error {
+				m.Go("poll", func(ctx context.Context) {
+					<-ctx.Done()
+					close(stopped)
+				})
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			},
+		},
+		lifecycle.Component{
+			Name:      "http",
+			DependsOn: []string{"worker"},
+			Start: func(ctx context.Context) error {
+				return errors.New("port in use")
+			},
+		},
+	)
+
+	begin := time.Now()
+	if err := m.Start(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Start() = %v, want the rollback to hit the shutdown deadline", err)
+	}
+	if time.Since(begin) > 500*time.Millisecond {
+		t.Errorf("rollback should give up at the shutdown deadline, took %s", time.Since(begin))
+	}
+
+	select {
+	case <-stopped:
+	default:
+		t.Error("background work should be cancelled and awaited during rollback")
+	}
+}
+
+func TestManager_RejectsInvalidDependencies(t *testing.T) {
+	rec := &recorder{}
+
+	missing := newTestManager()
+	mustRegister(t, missing, rec.component("http", "database"))
+	if err := missing.Start(context.Background()); !errors.Is(err, lifecycle.ErrUnknownDependency) {
+		t.Errorf("Start() = %v, want %v", err, lifecycle.ErrUnknownDependency)
+	}
+
+	cycle := newTestManager()
+	mustRegister(t, cycle, rec.component("a", "b"), rec.component("b", "a"))
+	if err := cycle.Start(context.Background()); !errors.Is(err, lifecycle.ErrDependencyCycle) {
+		t.Errorf("Start() = %v, want %v", err, lifecycle.ErrDependencyCycle)
+	}
+
+	duplicate := newTestManager()
+	mustRegister(t, duplicate, rec.component("a"))
+	if err := duplicate.Register(rec.component("a")); !errors.Is(err, lifecycle.ErrDuplicateComponent) {
+		t.Errorf("Register() = %v, want %v", err, lifecycle.ErrDuplicateComponent)
+	}
+
+	if len(rec.events) != 0 {
+		t.Errorf("no component should start, got %v", rec.events)
+	}
+}
+
+func TestManager_StartTimeout(t *testing.T) {
+	m := newTestManager()
+	mustRegister(t, m, lifecycle.Component{
+		Name:         "slow",
+		StartTimeout: 20 * time.Millisecond,
+		Start: func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		},
+	})
+
+	begin := time.Now()
+	err := m.Start(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Start() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if time.Since(begin) > 500*time.Millisecond {
+		t.Errorf("Start should give up at the timeout, took %s", time.Since(begin))
+	}
+}
+
+func TestManager_RunStopsOnFailure(t *testing.T) {
+	rec := &recorder{}
+	m := newTestManager()
+	mustRegister(t, m, rec.component("database"), rec.component("http", "database"))
+
+	go m.Fail("http", errors.New("listener closed"))
+
+	err := m.Run(context.Background())
+	if err == nil {
+		t.Fatal("expected Run to return the component failure")
+	}
+
+	want := []string{"start database", "start http", "stop http", "stop database"}
+	if !reflect.DeepEqual(rec.events, want) {
+		t.Errorf("events = %v, want %v", rec.events, want)
+	}
+}
+
+func TestManager_ReadyAndBackgroundWork(t *testing.T) {
+	m := newTestManager()
+	mustRegister(t, m, (&recorder{}).component("database"))
+
+	if m.Ready() {
+		t.Error("Ready() should be false before Start")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	m.Go("worker", func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+
+	result := make(chan error, 1)
+	go func() { result <- m.Run(ctx) }()
+
+	deadline := time.Now().Add(time.Second)
+	for !m.Ready() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !m.Ready() {
+		t.Fatal("Ready() should be true once every component started")
+	}
+
+	cancel()
+	if err := <-result; err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	select {
+	case <-stopped:
+	default:
+		t.Error("background work should be cancelled and awaited during shutdown")
+	}
+	if m.Ready() {
+		t.Error("Ready() should be false after shutdown")
+	}
+}
+
+func TestManager_ForcedShutdownReportsRunningWork(t *testing.T) {
+	m := lifecycle.NewManager(logger.NewLogger(&logger.Config{Writer: io.Discard}), lifecycle.Options{
+		ShutdownTimeout: 50 * time.Millisecond,
+	})
+	mustRegister(t, m, lifecycle.Component{
+		Name: "http",
+		Stop: func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		},
+	})
+
+	done := m.Requests().Track("GET /slow")
+	defer done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	begin := time.Now()
+	if err := m.Run(ctx); !errors.Is(err, lifecycle.ErrShutdownTimeout) {
+		t.Errorf("Run() = %v, want %v", err, lifecycle.ErrShutdownTimeout)
+	}
+	if time.Since(begin) > 500*time.Millisecond {
+		t.Errorf("Run should give up at the shutdown deadline, took %s", time.Since(begin))
+	}
+	if active := m.Requests().Active(); active["GET /slow"] != 1 {
+		t.Errorf("Requests().Active() = %v, want the slow request", active)
+	}
+}
+
+func TestManager_DrainCountsAgainstShutdownTimeout(t *testing.T) {
+	m := lifecycle.NewManager(logger.NewLogger(&logger.Config{Writer: io.Discard}), lifecycle.Options{
+		DrainDelay:      time.Second,
+		ShutdownTimeout: 50 * time.Millisecond,
+	})
+	mustRegister(t, m, lifecycle.Component{Name: "http"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	begin := time.Now()
+	if err := m.Run(ctx); !errors.Is(err, lifecycle.ErrShutdownTimeout) {
+		t.Errorf("Run() = %v, want %v", err, lifecycle.ErrShutdownTimeout)
+	}
+	if time.Since(begin) > 500*time.Millisecond {
+		t.Errorf("the drain should end at the shutdown deadline, took %s", time.Since(begin))
+	}
+}
+
+func TestManager_SecondSignalEndsDrain(t *testing.T) {
+	// Keeps a signal sent after Run returns from killing the test binary
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGTERM)
+	defer signal.Stop(guard)
+
+	m := lifecycle.NewManager(logger.NewLogger(&logger.Config{Writer: io.Discard}), lifecycle.Options{
+		DrainDelay:      10 * time.Second,
+		ShutdownTimeout: 20 * time.Second,
+	})
+	mustRegister(t, m, (&recorder{}).component("http"))
+
+	result := make(chan error, 1)
+	go func() { result <- m.Run(context.Background()) }()
+
+	deadline := time.Now().Add(time.Second)
+	for !m.Ready() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The first signal starts the drain, a later one ends it
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-result:
+			if err != nil {
+				t.Errorf("Run() = %v, want nil", err)
+			}
+			return
+		case <-ticker.C:
+			syscall.Kill(os.Getpid(), syscall.SIGTERM)
+		case <-time.After(2 * time.Second):
+			t.Fatal("a second signal should end the drain")
+		}
+	}
+}
+
+func TestTracker_WaitsForWork(t *testing.T) {
+	tracker := lifecycle.NewTracker()
+	first := tracker.Track("job")
+	second := tracker.Track("job")
+
+	if tracker.Count() != 2 {
+		t.Errorf("Count() = %d, want 2", tracker.Count())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tracker.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() = %v, want %v while work is running", err, context.DeadlineExceeded)
+	}
+
+	first()
+	first() // ending the same work twice is a no-op
+	second()
+
+	if err := tracker.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() returned error once idle: %v", err)
+	}
+	if len(tracker.Active()) != 0 {
+		t.Errorf("Active() = %v, want empty", tracker.Active())
+	}
+}
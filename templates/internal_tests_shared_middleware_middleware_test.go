@@ -1,14 +1,22 @@
 package middleware_test
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/clerkinc/clerk-sdk-go/clerk"
+	"{{.Module}}/internal/shared/logger"
 	"{{.Module}}/internal/shared/middleware"
+	"{{.Module}}/internal/shared/replicas"
 )
 
 func TestNewMiddleware(t *testing.T) {
@@ -64,6 +72,50 @@ func TestLoggerMiddleware(t *testing.T) {
 	}
 }
 
+func TestRequestContextMiddleware(t *testing.T) {
+	m := middleware.NewMiddleware(nil, "test-secret")
+
+	var requestID, clientIP string
+	handler := m.RequestContextMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID, _ = r.Context().Value(logger.RequestIDKey).(string)
+		clientIP, _ = r.Context().Value(logger.ClientIPKey).(string)
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "caller ID kept", header: "abc-123", keep: true},
+		{name: "missing ID generated", header: ""},
+		{name: "invalid ID replaced", header: "bad id\n", keep: false},
+		{name: "oversized ID replaced", header: strings.Repeat("a", 129), keep: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.RemoteAddr = "203.0.113.7:52100"
+			if tt.header != "" {
+				req.Header.Set(middleware.RequestIDHeader, tt.header)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if requestID == "" || rr.Header().Get(middleware.RequestIDHeader) != requestID {
+				t.Errorf("request ID = %q, response header = %q, want the same non-empty ID", requestID, rr.Header().Get(middleware.RequestIDHeader))
+			}
+			if tt.keep != (requestID == tt.header) {
+				t.Errorf("request ID = %q, keep caller's %q = %v", requestID, tt.header, tt.keep)
+			}
+			if clientIP != "203.0.113.7" {
+				t.Errorf("client IP = %q, want 203.0.113.7", clientIP)
+			}
+		})
+	}
+}
+
 func TestRateLimiterMiddleware(t *testing.T) {
 	m := middleware.NewMiddleware(nil, "test-secret")
 
@@ -90,22 +142,35 @@ func TestRateLimiterMiddleware(t *testing.T) {
 	}
 }
 
+// fakeClerkClient accepts only "valid-token" as a session token
+type fakeClerkClient struct {
+	clerk.Client
+}
+
+func (fakeClerkClient) VerifyToken(token string, opts ...clerk.VerifyTokenOption) (*clerk.SessionClaims, error) {
+	if token != "valid-token" {
+		return nil, errors.New("invalid token")
+	}
+	claims := &clerk.SessionClaims{SessionID: "sess_123"}
+	claims.Subject = "user_123"
+	return claims, nil
+}
+
 func TestClerkAuthMiddleware(t *testing.T) {
-	m := middleware.NewMiddleware(nil, "test-secret")
+	m := middleware.NewMiddleware(fakeClerkClient{}, "test-secret")
 
 	// Create a test handler
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Check that user context was set
-		userID := r.Context().Value("user_id")
-		sessionID := r.Context().Value("session_id")
-
-		if userID == nil {
-			http.Error(w, "user_id not set", http.StatusInternalServerError)
+		// Check that the verified caller was set
+		userID, ok := middleware.ClerkUserID(r.Context())
+		if !ok || userID != "user_123" {
+			http.Error(w, "user id not set", http.StatusInternalServerError)
 			return
 		}
 
-		if sessionID == nil {
-			http.Error(w, "session_id not set", http.StatusInternalServerError)
+		claims, ok := middleware.SessionClaims(r.Context())
+		if !ok || claims.SessionID != "sess_123" {
+			http.Error(w, "session claims not set", http.StatusInternalServerError)
 			return
 		}
 
@@ -128,6 +193,12 @@ func TestClerkAuthMiddleware(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectedBody:   "authenticated",
 		},
+		{
+			name:           "rejected token",
+			authHeader:     "Bearer forged-token",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Invalid or expired session token\n",
+		},
 		{
 			name:           "missing authorization header",
 			authHeader:     "",
@@ -175,41 +246,127 @@ func TestClerkAuthMiddleware(t *testing.T) {
 	}
 }
 
+func TestClerkAuthMiddleware_NoClient(t *testing.T) {
+	m := middleware.NewMiddleware(nil, "test-secret")
+	authHandler := m.ClerkAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called without a clerk client")
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	w := httptest.NewRecorder()
+	authHandler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+// signWebhook signs a webhook body the way Svix does for the given whsec_ secret
+func signWebhook(t *testing.T, secret, id, timestamp, body string) string {
+	t.Helper()
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + timestamp + "." + body))
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
 func TestClerkWebhookMiddleware(t *testing.T) {
+	secret := "whsec_" + base64.StdEncoding.EncodeToString([]byte("webhook-signing-key"))
 	m := middleware.NewMiddleware(nil, "test-secret")
+	m.ClerkWebhookSecret = secret
 
-	// Create a test handler
+	// Create a test handler that echoes the body to prove it was restored
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("webhook processed"))
+		w.Write([]byte("webhook processed: " + string(body)))
 	})
 
 	// Wrap with webhook middleware
 	webhookHandler := m.ClerkWebhookMiddleware(handler)
 
+	body := `{"type":"organizationMembership.created"}`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	valid := signWebhook(t, secret, "msg_1", now, body)
+
 	tests := []struct {
 		name           string
+		id             string
+		timestamp      string
 		signature      string
 		expectedStatus int
 		expectedBody   string
 	}{
 		{
 			name:           "valid signature",
-			signature:      "valid-signature",
+			id:             "msg_1",
+			timestamp:      now,
+			signature:      valid,
 			expectedStatus: http.StatusOK,
-			expectedBody:   "webhook processed",
+			expectedBody:   "webhook processed: " + body,
+		},
+		{
+			name:           "valid signature among rotated ones",
+			id:             "msg_1",
+			timestamp:      now,
+			signature:      "v1,b2xkLXNpZ25hdHVyZQ== " + valid,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "webhook processed: " + body,
 		},
 		{
 			name:           "missing signature",
-			signature:      "",
+			id:             "msg_1",
+			timestamp:      now,
 			expectedStatus: http.StatusUnauthorized,
 			expectedBody:   "Missing svix-signature header\n",
 		},
+		{
+			name:           "missing id",
+			timestamp:      now,
+			signature:      valid,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Missing svix-id or svix-timestamp header\n",
+		},
+		{
+			name:           "forged signature",
+			id:             "msg_1",
+			timestamp:      now,
+			signature:      "v1,valid-signature",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Invalid webhook signature\n",
+		},
+		{
+			name:           "signature of another message",
+			id:             "msg_2",
+			timestamp:      now,
+			signature:      valid,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Invalid webhook signature\n",
+		},
+		{
+			name:           "stale timestamp",
+			id:             "msg_1",
+			timestamp:      stale,
+			signature:      signWebhook(t, secret, "msg_1", stale, body),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Webhook timestamp outside tolerance\n",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest("POST", "/webhook", nil)
+			req := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+			if tt.id != "" {
+				req.Header.Set("svix-id", tt.id)
+			}
+			if tt.timestamp != "" {
+				req.Header.Set("svix-timestamp", tt.timestamp)
+			}
 			if tt.signature != "" {
 				req.Header.Set("svix-signature", tt.signature)
 			}
@@ -228,6 +385,26 @@ func TestClerkWebhookMiddleware(t *testing.T) {
 	}
 }
 
+func TestClerkWebhookMiddleware_NoSecret(t *testing.T) {
+	m := middleware.NewMiddleware(nil, "test-secret")
+
+	called := false
+	handler := m.ClerkWebhookMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader("{}"))
+	req.Header.Set("svix-id", "msg_1")
+	req.Header.Set("svix-timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+	req.Header.Set("svix-signature", "v1,anything")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable || called {
+		t.Errorf("Expected webhooks to be refused without a secret, got %d (handler called: %v)", w.Code, called)
+	}
+}
+
 func TestSecurityHeadersMiddleware(t *testing.T) {
 	m := middleware.NewMiddleware(nil, "test-secret")
 
@@ -308,6 +485,46 @@ func TestSecurityHeadersMiddleware_EmptyConfig(t *testing.T) {
 	}
 }
 
+func TestCORSMiddleware_RouteOverrides(t *testing.T) {
+	m := middleware.NewMiddleware(nil, "test-secret")
+	m.SetCORSConfig(middleware.CORSConfig{
+		AllowedOrigins: []string{"https://*.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		Routes: map[string]middleware.CORSConfig{
+			"/api/v1/identity": {},
+		},
+	})
+
+	handler := m.CORSMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		path       string
+		origin     string
+		wantOrigin string
+	}{
+		{"wildcard subdomain allowed", "/api/v1/health", "https://app.example.com", "https://app.example.com"},
+		{"unknown origin refused", "/api/v1/health", "https://evil.com", ""},
+		{"webhook route refuses browsers", "/api/v1/identity/clerk", "https://app.example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+		})
+	}
+}
+
 func TestRequestSizeLimitMiddleware(t *testing.T) {
 	m := middleware.NewMiddleware(nil, "test-secret")
 
@@ -548,3 +765,57 @@ func BenchmarkSecurityHeadersMiddleware(b *testing.B) {
 		securityHandler.ServeHTTP(w, req)
 	}
 }
+
+func TestDatabaseMiddleware(t *testing.T) {
+	mw := middleware.NewMiddleware(nil, "test-secret")
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	available := false
+	handler := mw.DatabaseMiddleware(func() bool { return available })(next)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/organizations/1", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("status while unavailable = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After header while unavailable")
+	}
+
+	available = true
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/organizations/1", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("status once available = %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	rr = httptest.NewRecorder()
+	mw.DatabaseMiddleware(nil)(next).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("status with nil check = %d, want %d", rr.Code, http.StatusOK)
+	}
+}
+
+func TestPrimaryReadsMiddleware(t *testing.T) {
+	mw := middleware.NewMiddleware(nil, "test-secret")
+
+	for method, wantPrimary := range map[string]bool{
+		http.MethodGet:    false,
+		http.MethodHead:   false,
+		http.MethodPost:   true,
+		http.MethodPatch:  true,
+		http.MethodDelete: true,
+	} {
+		var primary bool
+		handler := mw.PrimaryReadsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			primary = replicas.UsesPrimary(r.Context())
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/api/v1/organizations/1", nil))
+
+		if primary != wantPrimary {
+			t.Errorf("%s reads from primary = %v, want %v", method, primary, wantPrimary)
+		}
+	}
+}
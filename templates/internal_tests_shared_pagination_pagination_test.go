@@ -0,0 +1,162 @@
+package pagination_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	httpHelpers "{{.Module}}/internal/shared/http"
+	"{{.Module}}/internal/shared/pagination"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+type row struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+}
+
+var options = pagination.Options{
+	Table: "rows",
+	SortFields: map[string]pagination.Field{
+		"created_at": {Column: "created_at", Time: true},
+		"name":       {Column: "name"},
+	},
+	DefaultSort: "-created_at",
+	Filters:     []string{"name"},
+}
+
+func key(r row) (interface{}, string) {
+	return r.CreatedAt, r.ID
+}
+
+// dryRun returns the SQL and bound values Apply produces for a list query
+func dryRun(t *testing.T, req pagination.Request) (string, []interface{}) {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		Logger:               gormLogger.Discard,
+		DisableAutomaticPing: true,
+		DryRun:               true,
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+
+	var rows []row
+	stmt := req.Apply(db.Model(&row{})).Find(&rows).Statement
+	return stmt.SQL.String(), stmt.Vars
+}
+
+func TestParse_Defaults(t *testing.T) {
+	req, err := pagination.Parse(url.Values{"name": {"acme"}}, options)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if req.Limit != pagination.DefaultLimit || req.Sort != "-created_at" || req.Filters.Get("name") != "acme" {
+		t.Errorf("Parse() = limit %d, sort %q, filters %v", req.Limit, req.Sort, req.Filters)
+	}
+
+	sql, _ := dryRun(t, req)
+	if !strings.Contains(sql, "ORDER BY rows.created_at DESC, rows.id DESC LIMIT $1") {
+		t.Errorf("SQL = %q, want descending order and limit + 1", sql)
+	}
+}
+
+func TestParse_Rejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		query url.Values
+	}{
+		{"unknown parameter", url.Values{"password": {"x"}}},
+		{"repeated parameter", url.Values{"name": {"a", "b"}}},
+		{"limit too large", url.Values{"limit": {"1000"}}},
+		{"limit not a number", url.Values{"limit": {"ten"}}},
+		{"unknown sort", url.Values{"sort": {"email"}}},
+		{"garbage cursor", url.Values{"cursor": {"not-a-cursor"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pagination.Parse(tt.query, options)
+			var httpErr *httpHelpers.Error
+			if !errors.As(err, &httpErr) || httpErr.Status != http.StatusBadRequest {
+				t.Errorf("Parse() error = %v, want a 400 error", err)
+			}
+		})
+	}
+}
+
+func TestTrim_CursorRoundTrip(t *testing.T) {
+	req, err := pagination.Parse(url.Values{"limit": {"2"}}, options)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC)
+	rows := []row{
+		{ID: "c", CreatedAt: created.Add(2 * time.Second)},
+		{ID: "b", CreatedAt: created},
+		{ID: "a", CreatedAt: created.Add(-time.Second)},
+	}
+	page, next := pagination.Trim(rows, req, key)
+	if len(page) != 2 || next == "" {
+		t.Fatalf("Trim() = %d rows, cursor %q, want 2 rows and a cursor", len(page), next)
+	}
+
+	nextReq, err := pagination.Parse(url.Values{"limit": {"2"}, "cursor": {next}}, options)
+	if err != nil {
+		t.Fatalf("Parse(next cursor) error = %v", err)
+	}
+	sql, vars := dryRun(t, nextReq)
+	if !strings.Contains(sql, "(rows.created_at, rows.id) < ($1, $2)") {
+		t.Errorf("SQL = %q, want a keyset condition after the cursor", sql)
+	}
+	if len(vars) < 2 || !vars[0].(time.Time).Equal(created) || vars[1] != "b" {
+		t.Errorf("vars = %v, want the last row's created_at and id", vars)
+	}
+
+	// The last page has no cursor
+	if _, last := pagination.Trim(rows[:2], req, key); last != "" {
+		t.Errorf("Trim() on the last page returned cursor %q", last)
+	}
+}
+
+func TestParse_CursorBoundToSort(t *testing.T) {
+	req, _ := pagination.Parse(url.Values{"limit": {"1"}}, options)
+	_, next := pagination.Trim([]row{
+		{ID: "a"},
+		{ID: "b"},
+	}, req, key)
+
+	_, err := pagination.Parse(url.Values{"cursor": {next}, "sort": {"name"}}, options)
+	if err == nil {
+		t.Error("Parse() accepted a cursor issued for another sort order")
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/rows?limit=2&name=acme", nil)
+
+	page := pagination.NewPage(r, []row{
+		{ID: "a"},
+	}, 2, "abc")
+	next, err := url.Parse(page.Next)
+	if err != nil {
+		t.Fatalf("invalid next link %q: %v", page.Next, err)
+	}
+	if next.Path != "/api/v1/rows" || next.Query().Get("cursor") != "abc" || next.Query().Get("name") != "acme" {
+		t.Errorf("Next = %q, want the request URL with the cursor", page.Next)
+	}
+
+	last := pagination.NewPage[row](r, nil, 2, "")
+	if last.Next != "" || last.Data == nil {
+		t.Errorf("last page = %+v, want no link and an empty list", last)
+	}
+}
@@ -0,0 +1,187 @@
+package replicas_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"log/slog"
+	"testing"
+
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/replicas"
+	"{{.Module}}/internal/tests/testutil"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type record struct {
+	ID   string
+	Name string
+}
+
+func newLogger() *logger.Logger {
+	return logger.NewLogger(&logger.Config{Level: slog.LevelError, Writer: io.Discard})
+}
+
+// setup returns a primary routing reads to the named replicas; every statement
+// fails with the name of the pool that ran it
+func setup(t *testing.T, names ...string) (*gorm.DB, *testutil.Pool, []*testutil.Pool, *replicas.Router) {
+	t.Helper()
+	primary := &testutil.Pool{Name: "primary"}
+	db := testutil.OpenDB(t, primary)
+
+	pools := make([]*testutil.Pool, 0, len(names))
+	pool := make([]*replicas.Replica, 0, len(names))
+	for _, name := range names {
+		replicaPool := &testutil.Pool{Name: name}
+		pools = append(pools, replicaPool)
+		pool = append(pool, replicas.NewReplica(name, testutil.OpenDB(t, replicaPool)))
+	}
+
+	router := replicas.NewRouter(newLogger(), pool)
+	if err := db.Use(router); err != nil {
+		t.Fatalf("failed to register router: %v", err)
+	}
+	return db, primary, pools, router
+}
+
+// ranOn returns the pool name a failed statement reports
+func ranOn(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestRouter_ReadsRoundRobin(t *testing.T) {
+	db, _, _, _ := setup(t, "replica-a", "replica-b")
+
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		var found record
+		seen[ranOn(db.Where("id = ?", "1").First(&found).Error)]++
+	}
+
+	if seen["replica-a"] != 2 || seen["replica-b"] != 2 {
+		t.Errorf("reads = %v, want 2 on each replica", seen)
+	}
+}
+
+func TestRouter_WritesUsePrimary(t *testing.T) {
+	db, primary, pools, _ := setup(t, "replica-a")
+
+	err := db.Create(&record{ID: "1", Name: "a"}).Error
+	if ranOn(err) != "primary" {
+		t.Errorf("create ran on %q, want primary", ranOn(err))
+	}
+	err = db.Model(&record{}).Where("id = ?", "1").Update("name", "b").Error
+	if ranOn(err) != "primary" {
+		t.Errorf("update ran on %q, want primary", ranOn(err))
+	}
+
+	if _, execs := primary.Counts(); execs == 0 {
+		t.Error("primary ran no writes")
+	}
+	if queries, execs := pools[0].Counts(); queries+execs != 0 {
+		t.Errorf("replica ran %d queries and %d writes, want none", queries, execs)
+	}
+}
+
+func TestRouter_PrimaryReads(t *testing.T) {
+	db, _, _, _ := setup(t, "replica-a")
+
+	tests := []struct {
+		name string
+		run  func(db *gorm.DB) error
+	}{
+		{"context flag", func(db *gorm.DB) error {
+			var found record
+			return db.WithContext(replicas.WithPrimary(context.Background())).First(&found).Error
+		}},
+		{"locking read", func(db *gorm.DB) error {
+			var found record
+			return db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&found).Error
+		}},
+		{"raw write", func(db *gorm.DB) error {
+			var found record
+			return db.Raw("UPDATE records SET name = 'a' RETURNING *").Scan(&found).Error
+		}},
+		{"in transaction", func(db *gorm.DB) error {
+			var found record
+			var err error
+			db.Transaction(func(tx *gorm.DB) error {
+				err = tx.First(&found).Error
+				return nil
+			})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ranOn(tt.run(db)); got != "primary" {
+				t.Errorf("read ran on %q, want primary", got)
+			}
+		})
+	}
+
+	var found record
+	if got := ranOn(db.Raw("SELECT * FROM records").Scan(&found).Error); got != "replica-a" {
+		t.Errorf("raw select ran on %q, want replica-a", got)
+	}
+}
+
+func TestRouter_EjectsUnreachableReplica(t *testing.T) {
+	db, _, pools, router := setup(t, "replica-a", "replica-b")
+	pools[0].Fail(driver.ErrBadConn)
+
+	for i := 0; i < 4; i++ {
+		var found record
+		db.First(&found)
+	}
+
+	if router.Replicas()[0].Healthy() {
+		t.Error("replica-a is still healthy after a connection error")
+	}
+	if !router.Replicas()[1].Healthy() {
+		t.Error("replica-b was ejected after query errors of its own")
+	}
+	if queries, _ := pools[0].Counts(); queries != 1 {
+		t.Errorf("replica-a ran %d queries, want 1 before being ejected", queries)
+	}
+}
+
+func TestRouter_FallsBackToPrimary(t *testing.T) {
+	db, _, pools, _ := setup(t, "replica-a")
+	pools[0].Fail(driver.ErrBadConn)
+
+	var found record
+	db.First(&found)
+	if got := ranOn(db.First(&found).Error); got != "primary" {
+		t.Errorf("read ran on %q with every replica ejected, want primary", got)
+	}
+}
+
+func TestRouter_ReleasesReplicaAfterRead(t *testing.T) {
+	db, _, _, _ := setup(t, "replica-a")
+
+	// Later statements on the chain reuse its statement, and must not write to the replica
+	chain := db.Model(&record{}).Where("id = ?", "1")
+	var found record
+	if got := ranOn(chain.First(&found).Error); got != "replica-a" {
+		t.Fatalf("read ran on %q, want replica-a", got)
+	}
+	if chain.Statement.ConnPool != db.ConnPool {
+		t.Error("statement still uses the replica pool after the read")
+	}
+}
+
+func TestWithPrimary(t *testing.T) {
+	if replicas.UsesPrimary(context.Background()) {
+		t.Error("UsesPrimary() = true for a plain context")
+	}
+	if !replicas.UsesPrimary(replicas.WithPrimary(context.Background())) {
+		t.Error("UsesPrimary() = false after WithPrimary")
+	}
+}
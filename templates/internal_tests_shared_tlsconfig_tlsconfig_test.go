@@ -0,0 +1,297 @@
+package tlsconfig_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/tlsconfig"
+)
+
+// writeCertificate writes a self-signed key pair for commonName and returns its paths
+func writeCertificate(t *testing.T, dir string, commonName string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return certFile, keyFile
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+// touch moves the modification time forward so the reloader sees a change
+func touch(t *testing.T, paths ...string) {
+	t.Helper()
+	later := time.Now().Add(time.Minute)
+	for _, path := range paths {
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatalf("failed to touch %s: %v", path, err)
+		}
+	}
+}
+
+func newTestLogger() *logger.Logger {
+	return logger.NewLogger(&logger.Config{Writer: io.Discard})
+}
+
+func servedCommonName(t *testing.T, reloader *tlsconfig.CertReloader) string {
+	t.Helper()
+	certificate, err := reloader.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate returned error: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse served certificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestParseMinVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    uint16
+		wantErr bool
+	}{
+		{"1.2", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, false},
+		{"1.1", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := tlsconfig.ParseMinVersion(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMinVersion(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMinVersion(%q) = %x, want %x", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCipherSuites(t *testing.T) {
+	modern, err := tlsconfig.CipherSuites(tlsconfig.CipherPolicyModern)
+	if err != nil {
+		t.Fatalf("CipherSuites(modern) returned error: %v", err)
+	}
+	if len(modern) == 0 {
+		t.Fatal("modern policy has no cipher suites")
+	}
+	for _, id := range modern {
+		suite := tls.CipherSuiteName(id)
+		if !strings.Contains(suite, "ECDHE") || !(strings.Contains(suite, "GCM") || strings.Contains(suite, "CHACHA20")) {
+			t.Errorf("modern policy includes %s", suite)
+		}
+	}
+
+	compatible, err := tlsconfig.CipherSuites(tlsconfig.CipherPolicyCompatible)
+	if err != nil || compatible != nil {
+		t.Errorf("CipherSuites(compatible) = %v, %v, want Go defaults", compatible, err)
+	}
+
+	if _, err := tlsconfig.CipherSuites("legacy"); !errors.Is(err, tlsconfig.ErrUnknownCipherPolicy) {
+		t.Errorf("CipherSuites(legacy) error = %v, want ErrUnknownCipherPolicy", err)
+	}
+}
+
+func TestNewCertReloader_InvalidPair(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeFile(t, certFile, []byte("not a certificate"))
+	writeFile(t, keyFile, []byte("not a key"))
+
+	if _, err := tlsconfig.NewCertReloader(newTestLogger(), certFile, keyFile); err == nil {
+		t.Error("NewCertReloader accepted an invalid key pair")
+	}
+	if _, err := tlsconfig.NewCertReloader(newTestLogger(), filepath.Join(dir, "missing.pem"), keyFile); err == nil {
+		t.Error("NewCertReloader accepted a missing certificate")
+	}
+}
+
+func TestCertReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir, "first")
+
+	reloader, err := tlsconfig.NewCertReloader(newTestLogger(), certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewCertReloader returned error: %v", err)
+	}
+	if name := servedCommonName(t, reloader); name != "first" {
+		t.Fatalf("served certificate = %s, want first", name)
+	}
+
+	// Unchanged files are not reloaded
+	if reloaded, err := reloader.Reload(); err != nil || reloaded {
+		t.Errorf("Reload() on unchanged files = %v, %v, want false, nil", reloaded, err)
+	}
+
+	// A renewed certificate is swapped in
+	writeCertificate(t, dir, "second")
+	touch(t, certFile, keyFile)
+	if reloaded, err := reloader.Reload(); err != nil || !reloaded {
+		t.Fatalf("Reload() after renewal = %v, %v, want true, nil", reloaded, err)
+	}
+	if name := servedCommonName(t, reloader); name != "second" {
+		t.Errorf("served certificate = %s, want second", name)
+	}
+
+	// A broken pair is rejected and the current certificate kept
+	writeFile(t, keyFile, []byte("truncated"))
+	touch(t, keyFile)
+	if _, err := reloader.Reload(); err == nil {
+		t.Error("Reload() accepted an invalid key pair")
+	}
+	if name := servedCommonName(t, reloader); name != "second" {
+		t.Errorf("served certificate after rejected reload = %s, want second", name)
+	}
+}
+
+func TestNewServerConfig_ServesHTTP2(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, t.TempDir(), "localhost")
+	reloader, err := tlsconfig.NewCertReloader(newTestLogger(), certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewCertReloader returned error: %v", err)
+	}
+
+	config, err := tlsconfig.NewServerConfig(tlsconfig.Config{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		MinVersion:   "1.2",
+		CipherPolicy: tlsconfig.CipherPolicyModern,
+	}, reloader)
+	if err != nil {
+		t.Fatalf("NewServerConfig returned error: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		}),
+		TLSConfig: config,
+	}
+	go server.ServeTLS(listener, "", "")
+	defer server.Shutdown(context.Background())
+
+	roots := x509.NewCertPool()
+	pemData, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatalf("failed to read certificate: %v", err)
+	}
+	roots.AppendCertsFromPEM(pemData)
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots},
+			ForceAttemptHTTP2: true,
+		},
+		Timeout: 5 * time.Second,
+	}
+
+	resp, err := client.Get("https://" + listener.Addr().String())
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Proto != "HTTP/2.0" {
+		t.Errorf("negotiated protocol = %s, want HTTP/2.0", resp.Proto)
+	}
+	if resp.TLS == nil || resp.TLS.Version < tls.VersionTLS12 {
+		t.Errorf("connection TLS state = %+v, want TLS 1.2 or later", resp.TLS)
+	}
+}
+
+func TestNewServerConfig_InvalidSettings(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, t.TempDir(), "localhost")
+	reloader, err := tlsconfig.NewCertReloader(newTestLogger(), certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewCertReloader returned error: %v", err)
+	}
+
+	if _, err := tlsconfig.NewServerConfig(tlsconfig.Config{MinVersion: "1.0", CipherPolicy: tlsconfig.CipherPolicyModern}, reloader); err == nil {
+		t.Error("NewServerConfig accepted TLS 1.0")
+	}
+	if _, err := tlsconfig.NewServerConfig(tlsconfig.Config{MinVersion: "1.2", CipherPolicy: "legacy"}, reloader); err == nil {
+		t.Error("NewServerConfig accepted an unknown cipher policy")
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsPort string
+		target    string
+		want      string
+	}{
+		{"custom port", "8443", "http://example.com:8080/api/v1/health?full=true", "https://example.com:8443/api/v1/health?full=true"},
+		{"default port", "443", "http://example.com/users", "https://example.com/users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tlsconfig.RedirectHandler(tt.httpsPort).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if rr.Code != http.StatusPermanentRedirect {
+				t.Errorf("status = %d, want %d", rr.Code, http.StatusPermanentRedirect)
+			}
+			if location := rr.Header().Get("Location"); location != tt.want {
+				t.Errorf("Location = %s, want %s", location, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package transaction_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/transaction"
+	"{{.Module}}/internal/tests/testutil"
+
+	"gorm.io/gorm"
+)
+
+// sqlStateError mimics a PostgreSQL error with an SQLSTATE code
+type sqlStateError struct {
+	code string
+}
+
+func (e *sqlStateError) Error() string    { return "ERROR: SQLSTATE " + e.code }
+func (e *sqlStateError) SQLState() string { return e.code }
+
+func setup(t *testing.T, opts transaction.Options) (*gorm.DB, *testutil.Pool, *transaction.Manager) {
+	t.Helper()
+	pool := &testutil.Pool{}
+	db := testutil.OpenDB(t, pool)
+
+	log := logger.NewLogger(&logger.Config{Level: slog.LevelError, Writer: io.Discard})
+	return db, pool, transaction.NewManager(log, db, opts)
+}
+
+// write runs a statement the way a datasource does
+func write(ctx context.Context, db *gorm.DB, statement string) error {
+	return transaction.DB(ctx, db).Exec(statement).Error
+}
+
+func TestManager_Do_CommitsSharedTransaction(t *testing.T) {
+	db, pool, manager := setup(t, transaction.Options{MaxAttempts: 1})
+
+	err := manager.Do(context.Background(), func(ctx context.Context) error {
+		if !transaction.InTransaction(ctx) {
+			t.Error("InTransaction() = false inside Do")
+		}
+		if err := write(ctx, db, "INSERT INTO organizations"); err != nil {
+			return err
+		}
+		return write(ctx, db, "UPDATE users")
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	want := []string{"tx:INSERT INTO organizations", "tx:UPDATE users"}
+	if fmt.Sprint(pool.Statements()) != fmt.Sprint(want) {
+		t.Errorf("statements = %v, want %v", pool.Statements(), want)
+	}
+	if begins, commits, rollbacks := pool.Transactions(); begins != 1 || commits != 1 || rollbacks != 0 {
+		t.Errorf("begins/commits/rollbacks = %d/%d/%d, want 1/1/0", begins, commits, rollbacks)
+	}
+}
+
+func TestManager_Do_RollsBackOnError(t *testing.T) {
+	db, pool, manager := setup(t, transaction.Options{MaxAttempts: 3, Backoff: time.Millisecond})
+	failure := errors.New("user not found")
+
+	err := manager.Do(context.Background(), func(ctx context.Context) error {
+		if err := write(ctx, db, "INSERT INTO organizations"); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Do() error = %v, want %v", err, failure)
+	}
+	if begins, commits, rollbacks := pool.Transactions(); begins != 1 || commits != 0 || rollbacks != 1 {
+		t.Errorf("begins/commits/rollbacks = %d/%d/%d, want 1/0/1 without retries", begins, commits, rollbacks)
+	}
+}
+
+func TestManager_Do_RetriesConflicts(t *testing.T) {
+	for _, code := range []string{"40001", "40P01"} {
+		t.Run(code, func(t *testing.T) {
+			db, pool, manager := setup(t, transaction.Options{MaxAttempts: 3, Backoff: time.Millisecond})
+
+			runs := 0
+			err := manager.Do(context.Background(), func(ctx context.Context) error {
+				runs++
+				if err := write(ctx, db, "UPDATE users"); err != nil {
+					return err
+				}
+				if runs < 3 {
+					return fmt.Errorf("update failed: %w", &sqlStateError{code: code})
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if runs != 3 {
+				t.Errorf("runs = %d, want 3", runs)
+			}
+			if _, commits, rollbacks := pool.Transactions(); rollbacks != 2 || commits != 1 {
+				t.Errorf("commits/rollbacks = %d/%d, want 1/2", commits, rollbacks)
+			}
+		})
+	}
+}
+
+func TestManager_Do_GivesUpAfterMaxAttempts(t *testing.T) {
+	_, pool, manager := setup(t, transaction.Options{MaxAttempts: 2, Backoff: time.Millisecond})
+
+	runs := 0
+	err := manager.Do(context.Background(), func(ctx context.Context) error {
+		runs++
+		return &sqlStateError{code: "40001"}
+	})
+	if !transaction.Retryable(err) {
+		t.Fatalf("Do() error = %v, want the serialization failure", err)
+	}
+	if begins, _, _ := pool.Transactions(); runs != 2 || begins != 2 {
+		t.Errorf("runs/begins = %d/%d, want 2/2", runs, begins)
+	}
+}
+
+func TestManager_Do_NestedSavepoints(t *testing.T) {
+	db, pool, manager := setup(t, transaction.Options{MaxAttempts: 1})
+	failure := errors.New("optional step failed")
+
+	err := manager.Do(context.Background(), func(ctx context.Context) error {
+		if err := write(ctx, db, "INSERT INTO organizations"); err != nil {
+			return err
+		}
+
+		nestedErr := manager.Do(ctx, func(ctx context.Context) error {
+			if err := write(ctx, db, "UPDATE users"); err != nil {
+				return err
+			}
+			return failure
+		})
+		if !errors.Is(nestedErr, failure) {
+			t.Errorf("nested Do() error = %v, want %v", nestedErr, failure)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	joined := strings.Join(pool.Statements(), "; ")
+	if !strings.Contains(joined, "tx:SAVEPOINT") || !strings.Contains(joined, "tx:ROLLBACK TO SAVEPOINT") {
+		t.Errorf("statements = %v, want a savepoint rolled back", pool.Statements())
+	}
+	if begins, commits, _ := pool.Transactions(); begins != 1 || commits != 1 {
+		t.Errorf("begins/commits = %d/%d, want 1/1", begins, commits)
+	}
+}
+
+func TestDB_OutsideTransaction(t *testing.T) {
+	db, pool, _ := setup(t, transaction.Options{})
+
+	if err := write(context.Background(), db, "UPDATE users"); err != nil {
+		t.Fatalf("write error = %v", err)
+	}
+	if fmt.Sprint(pool.Statements()) != "[UPDATE users]" {
+		t.Errorf("statements = %v, want the update outside a transaction", pool.Statements())
+	}
+	if transaction.InTransaction(context.Background()) {
+		t.Error("InTransaction() = true outside Do")
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&sqlStateError{code: "40001"}, true},
+		{fmt.Errorf("wrapped: %w", &sqlStateError{code: "40P01"}), true},
+		{&sqlStateError{code: "23505"}, false},
+		{errors.New("plain"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := transaction.Retryable(tt.err); got != tt.want {
+			t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Package testutil holds helpers shared by the tests under internal/tests.
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// Pool is a gorm.ConnPool that records every statement instead of running it.
+// Writes report one affected row and queries fail, since there is no database to
+// read from. Statements run in a transaction are recorded with a "tx:" prefix.
+type Pool struct {
+	// Name, when set, is the error every statement fails with, so the error
+	// tells which pool ran it
+	Name string
+
+	mu         sync.Mutex
+	err        error
+	statements []string
+	queries    int
+	execs      int
+	begins     int
+	commits    int
+	rollbacks  int
+}
+
+// OpenDB opens gorm on pool without a connection check or default transactions
+func OpenDB(t *testing.T, pool *Pool) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: pool}), &gorm.Config{
+		Logger:                 gormLogger.Discard,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+	return db
+}
+
+// Fail makes every later statement fail with err, e.g. driver.ErrBadConn
+func (p *Pool) Fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// Statements returns the recorded statements in order
+func (p *Pool) Statements() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.statements...)
+}
+
+// Last returns the last recorded statement, empty when there is none
+func (p *Pool) Last() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.statements) == 0 {
+		return ""
+	}
+	return p.statements[len(p.statements)-1]
+}
+
+// Counts returns the number of queries and writes run
+func (p *Pool) Counts() (int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queries, p.execs
+}
+
+// Transactions returns the number of transactions begun, committed and rolled back
+func (p *Pool) Transactions() (int, int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.begins, p.commits, p.rollbacks
+}
+
+// run records a statement and returns the error it fails with, if any
+func (p *Pool) run(statement string, query bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.statements = append(p.statements, statement)
+	if query {
+		p.queries++
+	} else {
+		p.execs++
+	}
+
+	switch {
+	case p.err != nil:
+		return p.err
+	case p.Name != "":
+		return errors.New(p.Name)
+	case query:
+		return errors.New("not supported")
+	}
+	return nil
+}
+
+func (p *Pool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *Pool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := p.run(query, false); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (p *Pool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, p.run(query, true)
+}
+
+func (p *Pool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *Pool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.begins++
+	return &poolTx{pool: p}, nil
+}
+
+// poolTx records the statements of a transaction on its pool
+type poolTx struct {
+	pool *Pool
+}
+
+func (tx *poolTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (tx *poolTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := tx.pool.run("tx:"+query, false); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (tx *poolTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, tx.pool.run("tx:"+query, true)
+}
+
+func (tx *poolTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (tx *poolTx) Commit() error {
+	tx.pool.mu.Lock()
+	defer tx.pool.mu.Unlock()
+	tx.pool.commits++
+	return nil
+}
+
+func (tx *poolTx) Rollback() error {
+	tx.pool.mu.Lock()
+	defer tx.pool.mu.Unlock()
+	tx.pool.rollbacks++
+	return nil
+}
@@ -0,0 +1,39 @@
+package datasource_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"{{.Module}}/internal/shared/pagination"
+	"{{.Module}}/internal/users/models"
+)
+
+func TestListUsers_Filters(t *testing.T) {
+	ds, pool := setup(t)
+
+	query := url.Values{"email": {"Ada@Example.com"}, "organization_id": {"org_1"}, "banned": {"false"}, "sort": {"last_name"}}
+	page, err := pagination.Parse(query, models.UserListOptions)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	filter, err := models.NewUserFilter(page.Filters)
+	if err != nil {
+		t.Fatalf("NewUserFilter() error = %v", err)
+	}
+	ds.ListUsers(context.Background(), filter, page)
+
+	statement := pool.Last()
+	for _, want := range []string{
+		"LOWER(users.email) = LOWER($1)",
+		"users.organization_id = $2 OR EXISTS",
+		"users.is_banned = $4",
+		`"users"."deleted_at" IS NULL`,
+		"ORDER BY users.last_name ASC, users.id ASC",
+	} {
+		if !strings.Contains(statement, want) {
+			t.Errorf("statement = %q, want it to contain %q", statement, want)
+		}
+	}
+}
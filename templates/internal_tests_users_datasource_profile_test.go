@@ -0,0 +1,38 @@
+package datasource_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"{{.Module}}/internal/users/models"
+)
+
+func TestUpdateUserProfile_OnlyEditableColumns(t *testing.T) {
+	ds, pool := setup(t)
+
+	// The recording pool fails queries, so only the statement is checked
+	_, _ = ds.UpdateUserProfile(context.Background(), "user_123", models.UserProfileUpdate{"business_name": "Acme"}, 0)
+
+	stmt := pool.Last()
+	for _, want := range []string{
+		`UPDATE "users" SET "business_name"=$1,"updated_at"=$2`,
+		`WHERE clerk_user_id = $3 AND "users"."deleted_at" IS NULL`,
+		`RETURNING *`,
+	} {
+		if !strings.Contains(stmt, want) {
+			t.Errorf("statement = %q, want it to contain %q", stmt, want)
+		}
+	}
+}
+
+func TestUpdateUserProfile_ConditionalOnVersion(t *testing.T) {
+	ds, pool := setup(t)
+
+	_, _ = ds.UpdateUserProfile(context.Background(), "user_123", models.UserProfileUpdate{"business_name": "Acme"}, 4)
+
+	stmt := pool.Last()
+	if want := `WHERE clerk_user_id = $3 AND version = $4 AND "users"."deleted_at" IS NULL`; !strings.Contains(stmt, want) {
+		t.Errorf("statement = %q, want it to contain %q", stmt, want)
+	}
+}
@@ -0,0 +1,94 @@
+package datasource_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/tests/testutil"
+	"{{.Module}}/internal/users/datasource"
+)
+
+func setup(t *testing.T) (datasource.UsersDatasource, *testutil.Pool) {
+	t.Helper()
+	pool := &testutil.Pool{}
+	db := testutil.OpenDB(t, pool)
+
+	log := logger.NewLogger(&logger.Config{Level: slog.LevelError, Writer: io.Discard})
+	return datasource.NewDatasource(log, db), pool
+}
+
+func TestDeleteUserByClerkID_SoftDeletes(t *testing.T) {
+	ds, pool := setup(t)
+
+	deleted, err := ds.DeleteUserByClerkID(context.Background(), "user_123")
+	if err != nil || !deleted {
+		t.Fatalf("DeleteUserByClerkID() = %v, %v, want true, nil", deleted, err)
+	}
+
+	statement := pool.Last()
+	if !strings.HasPrefix(statement, `UPDATE "users" SET "deleted_at"=`) {
+		t.Errorf("statement = %q, want an update of deleted_at", statement)
+	}
+	if !strings.Contains(statement, `"users"."deleted_at" IS NULL`) {
+		t.Errorf("statement = %q, want already deleted users left alone", statement)
+	}
+}
+
+func TestSoftDeleteStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		run    func(ds datasource.UsersDatasource) error
+		prefix string
+		where  string
+	}{
+		{
+			name: "restore",
+			run: func(ds datasource.UsersDatasource) error {
+				_, err := ds.RestoreUserByClerkID(context.Background(), "user_123")
+				return err
+			},
+			prefix: `UPDATE "users" SET "deleted_at"=$1`,
+			where:  "clerk_user_id = $3 AND deleted_at IS NOT NULL",
+		},
+		{
+			name: "purge",
+			run: func(ds datasource.UsersDatasource) error {
+				_, err := ds.PurgeUserByClerkID(context.Background(), "user_123")
+				return err
+			},
+			prefix: `DELETE FROM "users"`,
+			where:  "clerk_user_id = $1 AND deleted_at IS NOT NULL",
+		},
+		{
+			name: "purge before",
+			run: func(ds datasource.UsersDatasource) error {
+				_, err := ds.PurgeDeletedUsersBefore(context.Background(), time.Now())
+				return err
+			},
+			prefix: `DELETE FROM "users"`,
+			where:  "deleted_at IS NOT NULL AND deleted_at < $1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, pool := setup(t)
+			if err := tt.run(ds); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+
+			statement := pool.Last()
+			if !strings.HasPrefix(statement, tt.prefix) || !strings.Contains(statement, tt.where) {
+				t.Errorf("statement = %q, want %q ... %q", statement, tt.prefix, tt.where)
+			}
+			if strings.Contains(statement, `"users"."deleted_at" IS NULL`) {
+				t.Errorf("statement = %q, want the soft-delete scope removed", statement)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package datasource_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"{{.Module}}/internal/users/models"
+)
+
+func TestUpdateUser_WritesFalseFlags(t *testing.T) {
+	ds, pool := setup(t)
+
+	// An unban sets is_banned back to false, which an update from a struct would skip
+	user := &models.User{ClerkUserID: "user_123", FirstName: "Ada", LastName: "Lovelace", IsBanned: false}
+	if _, err := ds.UpdateUser(context.Background(), user); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+
+	statement := pool.Last()
+	for _, column := range []string{`"is_banned"=`, `"mfa_enabled"=`, `"two_factor_enabled"=`} {
+		if !strings.Contains(statement, column) {
+			t.Errorf("statement = %q, want %s written", statement, column)
+		}
+	}
+	// Without an email in the webhook the stored one is kept
+	for _, column := range []string{`"email"=`, `"organization_id"=`, `"business_name"=`, `"is_business_acount"=`} {
+		if strings.Contains(statement, column) {
+			t.Errorf("statement = %q, want %s left alone", statement, column)
+		}
+	}
+}
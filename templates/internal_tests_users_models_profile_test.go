@@ -0,0 +1,64 @@
+package models_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	httpHelpers "{{.Module}}/internal/shared/http"
+	"{{.Module}}/internal/users/models"
+)
+
+func TestNewUserProfileUpdate(t *testing.T) {
+	fields := map[string]json.RawMessage{
+		"business_name":       json.RawMessage(`"  Acme Ltd "`),
+		"is_business_account": json.RawMessage(`true`),
+	}
+
+	update, err := models.NewUserProfileUpdate(fields)
+	if err != nil {
+		t.Fatalf("NewUserProfileUpdate() error = %v", err)
+	}
+	if update["business_name"] != "Acme Ltd" || update["is_business_acount"] != true || len(update) != 2 {
+		t.Errorf("NewUserProfileUpdate() = %v, want the trimmed name and the account flag column", update)
+	}
+
+	cleared, err := models.NewUserProfileUpdate(map[string]json.RawMessage{"business_name": json.RawMessage(`null`)})
+	if err != nil {
+		t.Fatalf("NewUserProfileUpdate(null) error = %v", err)
+	}
+	if value, ok := cleared["business_name"]; !ok || value != nil {
+		t.Errorf("NewUserProfileUpdate(null) = %v, want business_name cleared", cleared)
+	}
+}
+
+func TestNewUserProfileUpdate_Rejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]json.RawMessage
+		status int
+	}{
+		{"clerk-owned email", map[string]json.RawMessage{"email": json.RawMessage(`"a@b.c"`)}, http.StatusForbidden},
+		{"clerk-owned ban flag", map[string]json.RawMessage{"is_banned": json.RawMessage(`false`)}, http.StatusForbidden},
+		{"clerk-owned next to an editable field", map[string]json.RawMessage{
+			"business_name": json.RawMessage(`"Acme"`),
+			"clerk_user_id": json.RawMessage(`"user_2"`),
+		}, http.StatusForbidden},
+		{"unknown field", map[string]json.RawMessage{"nickname": json.RawMessage(`"x"`)}, http.StatusBadRequest},
+		{"wrong type", map[string]json.RawMessage{"is_business_account": json.RawMessage(`"yes"`)}, http.StatusBadRequest},
+		{"name too long", map[string]json.RawMessage{"business_name": json.RawMessage(`"` + strings.Repeat("a", models.BusinessNameMaxLength+1) + `"`)}, http.StatusBadRequest},
+		{"empty body", map[string]json.RawMessage{}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := models.NewUserProfileUpdate(tt.fields)
+			var httpErr *httpHelpers.Error
+			if !errors.As(err, &httpErr) || httpErr.Status != tt.status {
+				t.Errorf("NewUserProfileUpdate() error = %v, want status %d", err, tt.status)
+			}
+		})
+	}
+}
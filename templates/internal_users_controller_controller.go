@@ -1,15 +1,22 @@
 package users
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"{{.Module}}/internal/shared/concurrency"
 	"{{.Module}}/internal/shared/constants"
 	httpHelpers "{{.Module}}/internal/shared/http"
 	"{{.Module}}/internal/shared/logger"
 	"{{.Module}}/internal/shared/middleware"
+	"{{.Module}}/internal/shared/pagination"
 	"{{.Module}}/internal/shared/validation"
 	"{{.Module}}/internal/users/models"
 	users "{{.Module}}/internal/users/service"
 
-	"net/http"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 const (
@@ -21,6 +28,13 @@ type UsersController interface {
 	CreateUserFromClerk(w http.ResponseWriter, r *http.Request) error
 	UpdateUserFromClerk(w http.ResponseWriter, r *http.Request) error
 	DeleteUser(w http.ResponseWriter, r *http.Request) error
+	ListUsers(w http.ResponseWriter, r *http.Request) error
+	GetCurrentUser(w http.ResponseWriter, r *http.Request) error
+	UpdateCurrentUser(w http.ResponseWriter, r *http.Request) error
+	GetUserByID(w http.ResponseWriter, r *http.Request) error
+	ListDeletedUsers(w http.ResponseWriter, r *http.Request) error
+	RestoreUser(w http.ResponseWriter, r *http.Request) error
+	PurgeUser(w http.ResponseWriter, r *http.Request) error
 }
 
 type ControllerImpl struct {
@@ -103,3 +117,186 @@ func (c *ControllerImpl) DeleteUser(w http.ResponseWriter, r *http.Request) erro
 
 	return httpHelpers.RespondWithJSON(w, http.StatusOK, deleted)
 }
+
+func (c *ControllerImpl) ListUsers(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "ListUsers")
+
+	page, err := pagination.Parse(r.URL.Query(), models.UserListOptions)
+	if err != nil {
+		l.Debug("invalid list users query", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+	filter, err := models.NewUserFilter(page.Filters)
+	if err != nil {
+		l.Debug("invalid list users filter", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	users, next, err := c.service.ListUsers(ctx, filter, page)
+	if err != nil {
+		l.Error("failed to list users", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, pagination.NewPage(r, users, page.Limit, next))
+}
+
+// GetCurrentUser returns the user of the verified session
+func (c *ControllerImpl) GetCurrentUser(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "GetCurrentUser")
+
+	clerkUserID, ok := middleware.ClerkUserID(ctx)
+	if !ok {
+		l.Debug("no clerk user id in request context")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusUnauthorized, "Unauthorized"))
+	}
+
+	user, err := c.service.GetUserByClerkUserID(ctx, clerkUserID)
+	if err != nil {
+		return c.respondWithUserError(w, l, err, "failed to get current user")
+	}
+
+	if concurrency.NotModified(w, r, user.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, user)
+}
+
+// UpdateCurrentUser applies a partial profile update to the user of the
+// verified session. Clerk-owned fields are rejected with 403. With If-Match,
+// only the tagged version is updated.
+func (c *ControllerImpl) UpdateCurrentUser(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "UpdateCurrentUser")
+
+	clerkUserID, ok := middleware.ClerkUserID(ctx)
+	if !ok {
+		l.Debug("no clerk user id in request context")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusUnauthorized, "Unauthorized"))
+	}
+
+	version, err := concurrency.IfMatch(r)
+	if err != nil {
+		l.Debug("rejected update current user precondition", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := middleware.SafeJSONDecoder(r, &fields, constants.JSONMaxSize); err != nil {
+		l.Debug("failed to decode update current user request", "error", err)
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusBadRequest, "request body must be a JSON object"))
+	}
+
+	update, err := models.NewUserProfileUpdate(fields)
+	if err != nil {
+		l.Debug("rejected update current user request", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	user, err := c.service.UpdateProfile(ctx, clerkUserID, update, version)
+	if err != nil {
+		return c.respondWithUserError(w, l, err, "failed to update current user")
+	}
+
+	concurrency.SetETag(w, user.Version)
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, user)
+}
+
+// GetUserByID returns a user the caller shares an organization with, and 404
+// for any other user
+func (c *ControllerImpl) GetUserByID(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "GetUserByID")
+
+	clerkUserID, ok := middleware.ClerkUserID(ctx)
+	if !ok {
+		l.Debug("no clerk user id in request context")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusUnauthorized, "Unauthorized"))
+	}
+
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		l.Debug("missing user id in path")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusBadRequest, "user id is required"))
+	}
+
+	user, err := c.service.GetUserAsUser(ctx, clerkUserID, id)
+	if err != nil {
+		return c.respondWithUserError(w, l, err, "failed to get user by id")
+	}
+
+	if concurrency.NotModified(w, r, user.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, user)
+}
+
+// respondWithUserError answers 404 for a missing user and 412 for a version
+// conflict, and logs anything else
+func (c *ControllerImpl) respondWithUserError(w http.ResponseWriter, l *logger.Logger, err error, msg string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		l.Debug("user not found")
+		return httpHelpers.RespondWithError(w, httpHelpers.NewError(http.StatusNotFound, "user not found"))
+	}
+	if errors.Is(err, concurrency.ErrVersionConflict) {
+		l.Debug("user version conflict")
+		return httpHelpers.RespondWithError(w, err)
+	}
+	l.Error(msg, "error", err)
+	return httpHelpers.RespondWithError(w, err)
+}
+
+func (c *ControllerImpl) ListDeletedUsers(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "ListDeletedUsers")
+
+	deleted, err := c.service.ListDeletedUsers(ctx)
+	if err != nil {
+		l.Error("failed to list deleted users", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, deleted)
+}
+
+func (c *ControllerImpl) RestoreUser(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "RestoreUser")
+
+	clerkID := mux.Vars(r)["clerk_id"]
+	if clerkID == "" {
+		l.Debug("missing clerk user id in path")
+		return httpHelpers.RespondWithError(w, errors.New("clerk user id is required"))
+	}
+
+	restored, err := c.service.RestoreUser(ctx, clerkID)
+	if err != nil {
+		l.Error("failed to restore user", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, restored)
+}
+
+func (c *ControllerImpl) PurgeUser(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	l := c.log.WithContext(ctx).With("method", "PurgeUser")
+
+	clerkID := mux.Vars(r)["clerk_id"]
+	if clerkID == "" {
+		l.Debug("missing clerk user id in path")
+		return httpHelpers.RespondWithError(w, errors.New("clerk user id is required"))
+	}
+
+	purged, err := c.service.PurgeUser(ctx, clerkID)
+	if err != nil {
+		l.Error("failed to purge user", "error", err)
+		return httpHelpers.RespondWithError(w, err)
+	}
+
+	return httpHelpers.RespondWithJSON(w, http.StatusOK, purged)
+}
@@ -4,13 +4,18 @@ package datasource
 
 import (
 	"context"
+	"time"
 
 	"{{.Module}}/internal/shared/assertions"
+	"{{.Module}}/internal/shared/concurrency"
 	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/pagination"
+	"{{.Module}}/internal/shared/transaction"
 	"{{.Module}}/internal/shared/uuid"
 	"{{.Module}}/internal/users/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
@@ -23,10 +28,21 @@ type UsersDatasource interface {
 	UpdateUser(ctx context.Context, user *models.User) (bool, error)
 	GetUserByClerkUserID(ctx context.Context, clerkUserID string) (*models.User, error)
 	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	GetDeletedUserByClerkID(ctx context.Context, clerkID string) (*models.User, error)
+	ListUsers(ctx context.Context, filter models.UserFilter, page pagination.Request) ([]models.User, string, error)
 	DeleteUserByClerkID(ctx context.Context, clerkID string) (bool, error)
 	UpdateUserOrganization(ctx context.Context, clerkUserID string, orgID string) (bool, error)
+	UpdateUserProfile(ctx context.Context, clerkUserID string, update models.UserProfileUpdate, version int64) (*models.User, error)
+	ListDeletedUsers(ctx context.Context) ([]models.User, error)
+	RestoreUserByClerkID(ctx context.Context, clerkID string) (bool, error)
+	PurgeUserByClerkID(ctx context.Context, clerkID string) (bool, error)
+	PurgeDeletedUsersBefore(ctx context.Context, cutoff time.Time) (int64, error)
 }
 
+// clerkColumns are the columns the user.updated webhook writes. They are
+// selected so that false values, such as an unban, are written too.
+var clerkColumns = []string{"first_name", "last_name", "profile_image_url", "mfa_enabled", "two_factor_enabled", "is_banned", "last_active_at", "updated_at"}
+
 type DatasourceImpl struct {
 	log *logger.Logger
 	db  *gorm.DB
@@ -45,7 +61,7 @@ func (d *DatasourceImpl) CreateUser(ctx context.Context, user *models.User) (*mo
 		user.ID = uuid.GenerateNamespaceUUID("usr")
 	}
 
-	if err := d.db.WithContext(ctx).Create(user).Error; err != nil {
+	if err := transaction.DB(ctx, d.db).Create(user).Error; err != nil {
 		l.Error("failed to create user", "error", err)
 		return nil, err
 	}
@@ -57,7 +73,12 @@ func (d *DatasourceImpl) CreateUser(ctx context.Context, user *models.User) (*mo
 func (d *DatasourceImpl) UpdateUser(ctx context.Context, user *models.User) (bool, error) {
 	l := d.log.WithContext(ctx).With("operation", "UpdateUser")
 
-	result := d.db.WithContext(ctx).Model(user).Where("clerk_user_id = ?", user.ClerkUserID).Updates(user)
+	columns := append([]string{}, clerkColumns...)
+	if user.Email != "" {
+		columns = append(columns, "email")
+	}
+
+	result := transaction.DB(ctx, d.db).Model(user).Select(columns).Where("clerk_user_id = ?", user.ClerkUserID).Updates(user)
 	if result.Error != nil {
 		l.Error("failed to update user", "error", result.Error)
 		return false, result.Error
@@ -76,7 +97,7 @@ func (d *DatasourceImpl) GetUserByClerkUserID(ctx context.Context, clerkUserID s
 	}
 
 	var user models.User
-	if err := d.db.WithContext(ctx).Where("clerk_user_id = ?", clerkUserID).First(&user).Error; err != nil {
+	if err := transaction.DB(ctx, d.db).Where("clerk_user_id = ?", clerkUserID).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			l.Debug("user not found", "clerk_user_id", clerkUserID)
 			return nil, err
@@ -98,7 +119,7 @@ func (d *DatasourceImpl) GetUserByID(ctx context.Context, id string) (*models.Us
 	}
 
 	var user models.User
-	if err := d.db.WithContext(ctx).Where("id = ?", id).First(&user).Error; err != nil {
+	if err := transaction.DB(ctx, d.db).Where("id = ?", id).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			l.Debug("user not found", "user_id", id)
 			return nil, err
@@ -111,6 +132,65 @@ func (d *DatasourceImpl) GetUserByID(ctx context.Context, id string) (*models.Us
 	return &user, nil
 }
 
+// GetDeletedUserByClerkID returns a soft-deleted user; a live user is not found
+func (d *DatasourceImpl) GetDeletedUserByClerkID(ctx context.Context, clerkID string) (*models.User, error) {
+	l := d.log.WithContext(ctx).With("operation", "GetDeletedUserByClerkID")
+
+	if err := assertions.AssertNonEmptyString(clerkID); err != nil {
+		l.Debug("invalid clerk user id", "error", err)
+		return nil, err
+	}
+
+	var user models.User
+	if err := transaction.DB(ctx, d.db).Unscoped().Where("clerk_user_id = ? AND deleted_at IS NOT NULL", clerkID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			l.Debug("deleted user not found", "clerk_user_id", clerkID)
+			return nil, err
+		}
+		l.Error("failed to get deleted user by clerk user id", "error", err)
+		return nil, err
+	}
+
+	l.Debug("deleted user retrieved successfully", "user_id", user.ID)
+	return &user, nil
+}
+
+// ListUsers returns a page of users and the cursor of the next page, empty on the last one
+func (d *DatasourceImpl) ListUsers(ctx context.Context, filter models.UserFilter, page pagination.Request) ([]models.User, string, error) {
+	l := d.log.WithContext(ctx).With("operation", "ListUsers")
+
+	query := transaction.DB(ctx, d.db).Model(&models.User{})
+	if filter.Email != "" {
+		query = query.Where("LOWER(users.email) = LOWER(?)", filter.Email)
+	}
+	if filter.OrganizationID != "" {
+		query = query.Where("users.organization_id = ? OR EXISTS (SELECT 1 FROM organization_memberships m WHERE m.user_id = users.id AND m.organization_id = ?)",
+			filter.OrganizationID, filter.OrganizationID)
+	}
+	if filter.IsBanned != nil {
+		query = query.Where("users.is_banned = ?", *filter.IsBanned)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("users.created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("users.created_at < ?", *filter.CreatedBefore)
+	}
+
+	var users []models.User
+	if err := page.Apply(query).Find(&users).Error; err != nil {
+		l.Error("failed to list users", "error", err)
+		return nil, "", err
+	}
+
+	users, next := pagination.Trim(users, page, func(u models.User) (interface{}, string) {
+		return u.SortValue(page.SortField()), u.ID
+	})
+
+	l.Debug("users listed successfully", "count", len(users), "has_next", next != "")
+	return users, next, nil
+}
+
 func (d *DatasourceImpl) DeleteUserByClerkID(ctx context.Context, clerkID string) (bool, error) {
 	l := d.log.WithContext(ctx).With("operation", "DeleteUserByClerkID")
 
@@ -119,7 +199,7 @@ func (d *DatasourceImpl) DeleteUserByClerkID(ctx context.Context, clerkID string
 		return false, err
 	}
 
-	result := d.db.WithContext(ctx).Where("clerk_user_id = ?", clerkID).Delete(&models.User{})
+	result := transaction.DB(ctx, d.db).Where("clerk_user_id = ?", clerkID).Delete(&models.User{})
 	if result.Error != nil {
 		l.Error("failed to delete user", "error", result.Error)
 		return false, result.Error
@@ -142,7 +222,7 @@ func (d *DatasourceImpl) UpdateUserOrganization(ctx context.Context, clerkUserID
 		return false, err
 	}
 
-	result := d.db.WithContext(ctx).Model(&models.User{}).Where("clerk_user_id = ?", clerkUserID).Update("organization_id", orgID)
+	result := transaction.DB(ctx, d.db).Model(&models.User{}).Where("clerk_user_id = ?", clerkUserID).Update("organization_id", orgID)
 	if result.Error != nil {
 		l.Error("failed to update user organization", "error", result.Error)
 		return false, result.Error
@@ -151,3 +231,118 @@ func (d *DatasourceImpl) UpdateUserOrganization(ctx context.Context, clerkUserID
 	l.Debug("user organization updated successfully", "clerk_user_id", clerkUserID, "organization_id", orgID, "rows_affected", result.RowsAffected)
 	return result.RowsAffected > 0, nil
 }
+
+// UpdateUserProfile writes the user-editable columns in update and returns the
+// updated user, or gorm.ErrRecordNotFound when no live user has clerkUserID. A
+// non-zero version makes the update conditional: it fails with
+// concurrency.ErrVersionConflict when the user is at another version.
+func (d *DatasourceImpl) UpdateUserProfile(ctx context.Context, clerkUserID string, update models.UserProfileUpdate, version int64) (*models.User, error) {
+	l := d.log.WithContext(ctx).With("operation", "UpdateUserProfile")
+
+	if err := assertions.AssertNonEmptyString(clerkUserID); err != nil {
+		l.Debug("invalid clerk user id", "error", err)
+		return nil, err
+	}
+
+	var user models.User
+	query := transaction.DB(ctx, d.db).
+		Model(&user).
+		Clauses(clause.Returning{}).
+		Where("clerk_user_id = ?", clerkUserID)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.Updates(map[string]interface{}(update))
+	if result.Error != nil {
+		l.Error("failed to update user profile", "error", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if version > 0 {
+			var count int64
+			if err := transaction.DB(ctx, d.db).Model(&models.User{}).Where("clerk_user_id = ?", clerkUserID).Count(&count).Error; err != nil {
+				l.Error("failed to check user version", "error", err)
+				return nil, err
+			}
+			if count > 0 {
+				l.Debug("user version conflict", "clerk_user_id", clerkUserID, "version", version)
+				return nil, concurrency.ErrVersionConflict
+			}
+		}
+		l.Debug("user not found", "clerk_user_id", clerkUserID)
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	l.Debug("user profile updated successfully", "user_id", user.ID)
+	return &user, nil
+}
+
+func (d *DatasourceImpl) ListDeletedUsers(ctx context.Context) ([]models.User, error) {
+	l := d.log.WithContext(ctx).With("operation", "ListDeletedUsers")
+
+	var users []models.User
+	if err := transaction.DB(ctx, d.db).Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&users).Error; err != nil {
+		l.Error("failed to list deleted users", "error", err)
+		return nil, err
+	}
+
+	l.Debug("deleted users retrieved successfully", "count", len(users))
+	return users, nil
+}
+
+func (d *DatasourceImpl) RestoreUserByClerkID(ctx context.Context, clerkID string) (bool, error) {
+	l := d.log.WithContext(ctx).With("operation", "RestoreUserByClerkID")
+
+	if err := assertions.AssertNonEmptyString(clerkID); err != nil {
+		l.Debug("invalid clerk user id", "error", err)
+		return false, err
+	}
+
+	result := transaction.DB(ctx, d.db).Unscoped().Model(&models.User{}).
+		Where("clerk_user_id = ? AND deleted_at IS NOT NULL", clerkID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		l.Error("failed to restore user", "error", result.Error)
+		return false, result.Error
+	}
+
+	l.Debug("user restored successfully", "clerk_user_id", clerkID, "rows_affected", result.RowsAffected)
+	return result.RowsAffected > 0, nil
+}
+
+// PurgeUserByClerkID permanently deletes a user; only soft-deleted users can be purged
+func (d *DatasourceImpl) PurgeUserByClerkID(ctx context.Context, clerkID string) (bool, error) {
+	l := d.log.WithContext(ctx).With("operation", "PurgeUserByClerkID")
+
+	if err := assertions.AssertNonEmptyString(clerkID); err != nil {
+		l.Debug("invalid clerk user id", "error", err)
+		return false, err
+	}
+
+	result := transaction.DB(ctx, d.db).Unscoped().
+		Where("clerk_user_id = ? AND deleted_at IS NOT NULL", clerkID).
+		Delete(&models.User{})
+	if result.Error != nil {
+		l.Error("failed to purge user", "error", result.Error)
+		return false, result.Error
+	}
+
+	l.Debug("user purged successfully", "clerk_user_id", clerkID, "rows_affected", result.RowsAffected)
+	return result.RowsAffected > 0, nil
+}
+
+// PurgeDeletedUsersBefore permanently deletes the users soft-deleted before cutoff
+func (d *DatasourceImpl) PurgeDeletedUsersBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	l := d.log.WithContext(ctx).With("operation", "PurgeDeletedUsersBefore")
+
+	result := transaction.DB(ctx, d.db).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Delete(&models.User{})
+	if result.Error != nil {
+		l.Error("failed to purge deleted users", "error", result.Error)
+		return 0, result.Error
+	}
+
+	l.Debug("deleted users purged successfully", "cutoff", cutoff, "rows_affected", result.RowsAffected)
+	return result.RowsAffected, nil
+}
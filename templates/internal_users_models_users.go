@@ -1,28 +1,159 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	httpHelpers "{{.Module}}/internal/shared/http"
+	"{{.Module}}/internal/shared/pagination"
 	"{{.Module}}/internal/shared/validation"
+
+	"gorm.io/gorm"
 )
 
 type User struct {
-	ID               string    `json:"id" gorm:"unique" validate:"required"`            // Custom ID for your system
-	ClerkUserID      string    `json:"clerk_user_id" gorm:"unique" validate:"required"` // Clerk's User ID
-	Email            string    `json:"email" gorm:"unique"`                             // User's email
-	FirstName        string    `json:"first_name" validate:"required"`
-	LastName         string    `json:"last_name" validate:"required"`
-	OrganizationID   string    `json:"organization_id"` // Organization ID
-	IsBusinessAcount bool      `json:"is_business_account"`
-	BusinessName     *string   `json:"business_name"`
-	ProfileImageURL  *string   `json:"profile_image_url"`
-	MFAEnabled       bool      `json:"mfa_enabled"`
-	TwoFactorEnabled bool      `json:"two_factor_enabled"`
-	IsBanned         bool      `json:"is_banned"`
-	LastActiveAt     time.Time `json:"last_active_at"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
-	DeletedAt        time.Time `json:"deleted_at"`
+	ID               string         `json:"id" gorm:"unique" validate:"required"`            // Custom ID for your system
+	ClerkUserID      string         `json:"clerk_user_id" gorm:"unique" validate:"required"` // Clerk's User ID
+	Email            string         `json:"email" gorm:"unique"`                             // User's email
+	FirstName        string         `json:"first_name" validate:"required"`
+	LastName         string         `json:"last_name" validate:"required"`
+	OrganizationID   string         `json:"organization_id"` // Organization ID
+	IsBusinessAcount bool           `json:"is_business_account"`
+	BusinessName     *string        `json:"business_name"`
+	ProfileImageURL  *string        `json:"profile_image_url"`
+	MFAEnabled       bool           `json:"mfa_enabled"`
+	TwoFactorEnabled bool           `json:"two_factor_enabled"`
+	IsBanned         bool           `json:"is_banned"`
+	LastActiveAt     time.Time      `json:"last_active_at"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"deleted_at" gorm:"index"`  // null until soft-deleted
+	Version          int64          `json:"version" gorm:"default:1"` // bumped by the database on every update
+}
+
+// UserListOptions are the sort fields and filters of the users list
+var UserListOptions = pagination.Options{
+	Table: "users",
+	SortFields: map[string]pagination.Field{
+		"created_at": {Column: "created_at", Time: true},
+		"updated_at": {Column: "updated_at", Time: true},
+		"first_name": {Column: "first_name"},
+		"last_name":  {Column: "last_name"},
+	},
+	DefaultSort: "-created_at",
+	Filters:     []string{"email", "organization_id", "banned", "created_after", "created_before"},
+}
+
+// UserFilter narrows the users list; zero fields match every user
+type UserFilter struct {
+	Email          string
+	OrganizationID string // member of, or assigned to, the organization
+	IsBanned       *bool
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+}
+
+// NewUserFilter reads the filters allowed by UserListOptions
+func NewUserFilter(filters url.Values) (UserFilter, error) {
+	filter := UserFilter{
+		Email:          strings.TrimSpace(filters.Get("email")),
+		OrganizationID: strings.TrimSpace(filters.Get("organization_id")),
+	}
+
+	var err error
+	if filter.IsBanned, err = pagination.Bool(filters, "banned"); err != nil {
+		return UserFilter{}, err
+	}
+	if filter.CreatedAfter, err = pagination.Time(filters, "created_after"); err != nil {
+		return UserFilter{}, err
+	}
+	if filter.CreatedBefore, err = pagination.Time(filters, "created_before"); err != nil {
+		return UserFilter{}, err
+	}
+	return filter, nil
+}
+
+// SortValue returns the value of a UserListOptions sort field, for the next page cursor
+func (u User) SortValue(field string) interface{} {
+	switch field {
+	case "updated_at":
+		return u.UpdatedAt
+	case "first_name":
+		return u.FirstName
+	case "last_name":
+		return u.LastName
+	default:
+		return u.CreatedAt
+	}
+}
+
+// BusinessNameMaxLength bounds business_name in profile updates
+const BusinessNameMaxLength = 200
+
+// clerkOwnedFields are synced from Clerk by the user webhooks and can't be
+// edited through the API
+var clerkOwnedFields = map[string]bool{
+	"id":                 true,
+	"clerk_user_id":      true,
+	"email":              true,
+	"first_name":         true,
+	"last_name":          true,
+	"organization_id":    true,
+	"profile_image_url":  true,
+	"mfa_enabled":        true,
+	"two_factor_enabled": true,
+	"is_banned":          true,
+	"last_active_at":     true,
+	"created_at":         true,
+	"updated_at":         true,
+	"deleted_at":         true,
+}
+
+// UserProfileUpdate maps the columns a user may change on their own profile to their new values
+type UserProfileUpdate map[string]interface{}
+
+// NewUserProfileUpdate reads a PATCH /users/me body. Clerk-owned fields are
+// rejected with 403 and unknown fields or invalid values with 400.
+func NewUserProfileUpdate(fields map[string]json.RawMessage) (UserProfileUpdate, error) {
+	update := UserProfileUpdate{}
+	for field, raw := range fields {
+		switch {
+		case field == "business_name":
+			var name *string
+			if err := json.Unmarshal(raw, &name); err != nil {
+				return nil, httpHelpers.NewError(http.StatusBadRequest, "business_name must be a string or null")
+			}
+			if name == nil {
+				update["business_name"] = nil
+				continue
+			}
+			trimmed := strings.TrimSpace(*name)
+			if utf8.RuneCountInString(trimmed) > BusinessNameMaxLength {
+				return nil, httpHelpers.NewError(http.StatusBadRequest, fmt.Sprintf("business_name must be at most %d characters", BusinessNameMaxLength))
+			}
+			update["business_name"] = trimmed
+		case field == "is_business_account":
+			var isBusiness bool
+			if err := json.Unmarshal(raw, &isBusiness); err != nil {
+				return nil, httpHelpers.NewError(http.StatusBadRequest, "is_business_account must be true or false")
+			}
+			update["is_business_acount"] = isBusiness
+		case clerkOwnedFields[field]:
+			return nil, httpHelpers.NewError(http.StatusForbidden, fmt.Sprintf("%s is managed by Clerk and can't be changed here", field))
+		default:
+			return nil, httpHelpers.NewError(http.StatusBadRequest, fmt.Sprintf("unknown field %q", field))
+		}
+	}
+
+	if len(update) == 0 {
+		return nil, httpHelpers.NewError(http.StatusBadRequest, "no fields to update")
+	}
+	return update, nil
 }
 
 type ClerkUserRequest struct {
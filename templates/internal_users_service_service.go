@@ -4,13 +4,25 @@ package users
 
 import (
 	"context"
-
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	auditModels "{{.Module}}/internal/audit/models"
+	auditService "{{.Module}}/internal/audit/service"
+	membershipsDatasource "{{.Module}}/internal/memberships/datasource"
 	"{{.Module}}/internal/shared/assertions"
+	httpHelpers "{{.Module}}/internal/shared/http"
 	"{{.Module}}/internal/shared/logger"
+	"{{.Module}}/internal/shared/pagination"
+	"{{.Module}}/internal/shared/transaction"
 	"{{.Module}}/internal/shared/uuid"
 	"{{.Module}}/internal/shared/validation"
 	usersDatasource "{{.Module}}/internal/users/datasource"
 	"{{.Module}}/internal/users/models"
+
+	"gorm.io/gorm"
 )
 
 const (
@@ -23,18 +35,41 @@ type UsersService interface {
 	UpdateUser(ctx context.Context, user *models.ClerkUserRequest) (bool, error)
 	GetUserByClerkUserID(ctx context.Context, clerkUserID string) (*models.User, error)
 	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	GetUserAsUser(ctx context.Context, clerkUserID string, id string) (*models.User, error)
+	ListUsers(ctx context.Context, filter models.UserFilter, page pagination.Request) ([]models.User, string, error)
 	DeleteUser(ctx context.Context, id string) (bool, error)
 	UpdateUserOrganization(ctx context.Context, clerkUserID string, orgID string) (bool, error)
+	UpdateProfile(ctx context.Context, clerkUserID string, update models.UserProfileUpdate, version int64) (*models.User, error)
+	ListDeletedUsers(ctx context.Context) ([]models.User, error)
+	RestoreUser(ctx context.Context, clerkID string) (bool, error)
+	PurgeUser(ctx context.Context, clerkID string) (bool, error)
+	PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+// ErrUnknownCaller answers a signed-in caller whose user.created webhook hasn't been received yet
+var ErrUnknownCaller = httpHelpers.NewError(http.StatusForbidden, "your account has not been synced yet")
+
+// Caller returns the stored user of the verified session, failing with
+// ErrUnknownCaller until the user.created webhook has been received
+func Caller(ctx context.Context, users usersDatasource.UsersDatasource, clerkUserID string) (*models.User, error) {
+	user, err := users.GetUserByClerkUserID(ctx, clerkUserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnknownCaller
+	}
+	return user, err
 }
 
 type ServiceImpl struct {
-	log  *logger.Logger
-	data usersDatasource.UsersDatasource
+	log         *logger.Logger
+	data        usersDatasource.UsersDatasource
+	memberships membershipsDatasource.MembershipsDatasource
+	tx          *transaction.Manager
+	audit       auditService.AuditService
 }
 
-func NewService(logger *logger.Logger, datasource usersDatasource.UsersDatasource) UsersService {
+func NewService(logger *logger.Logger, datasource usersDatasource.UsersDatasource, memberships membershipsDatasource.MembershipsDatasource, tx *transaction.Manager, audit auditService.AuditService) UsersService {
 	serviceLogger := logger.With("package", pkgName, "layer", layer)
-	return &ServiceImpl{log: serviceLogger, data: datasource}
+	return &ServiceImpl{log: serviceLogger, data: datasource, memberships: memberships, tx: tx, audit: audit}
 }
 
 func (s *ServiceImpl) CreateUser(ctx context.Context, request *models.ClerkUserRequest) (*models.User, error) {
@@ -48,7 +83,15 @@ func (s *ServiceImpl) CreateUser(ctx context.Context, request *models.ClerkUserR
 		return &models.User{}, err
 	}
 
-	created, err := s.data.CreateUser(ctx, &user)
+	// Each change is stored together with its audit event or not at all
+	var created *models.User
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if created, err = s.data.CreateUser(ctx, &user); err != nil {
+			return err
+		}
+		return s.record(ctx, auditModels.ActionUserCreated, nil, created)
+	})
 	if err != nil {
 		return &models.User{}, err
 	}
@@ -65,7 +108,34 @@ func (s *ServiceImpl) UpdateUser(ctx context.Context, request *models.ClerkUserR
 		return false, err
 	}
 
-	updated, err := s.data.UpdateUser(ctx, &user)
+	var updated bool
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		before, err := s.data.GetUserByClerkUserID(ctx, user.ClerkUserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			updated = false
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if updated, err = s.data.UpdateUser(ctx, &user); err != nil || !updated {
+			return err
+		}
+		after, err := s.data.GetUserByClerkUserID(ctx, user.ClerkUserID)
+		if err != nil {
+			return err
+		}
+
+		action := auditModels.ActionUserUpdated
+		if before.IsBanned != after.IsBanned {
+			action = auditModels.ActionUserUnbanned
+			if after.IsBanned {
+				action = auditModels.ActionUserBanned
+			}
+		}
+		return s.record(ctx, action, before, after)
+	})
 	if err != nil {
 		return false, err
 	}
@@ -105,6 +175,41 @@ func (s *ServiceImpl) GetUserByID(ctx context.Context, id string) (*models.User,
 	return user, nil
 }
 
+// GetUserAsUser returns user id to the caller when it is the caller or shares
+// one of their organizations. Any other user fails with gorm.ErrRecordNotFound,
+// so the response doesn't tell whether the user exists.
+func (s *ServiceImpl) GetUserAsUser(ctx context.Context, clerkUserID string, id string) (*models.User, error) {
+	l := s.log.WithContext(ctx).With("operation", "GetUserAsUser")
+
+	if err := assertions.AssertNonEmptyString(id); err != nil {
+		l.Debug("failed to validate user id", "error", err)
+		return &models.User{}, err
+	}
+
+	caller, err := Caller(ctx, s.data, clerkUserID)
+	if err != nil {
+		return &models.User{}, err
+	}
+	if caller.ID == id {
+		return caller, nil
+	}
+
+	shared, err := s.memberships.SharesOrganization(ctx, caller.ID, id)
+	if err != nil {
+		return &models.User{}, err
+	}
+	if !shared {
+		l.Debug("user shares no organization with the caller", "user_id", id)
+		return &models.User{}, gorm.ErrRecordNotFound
+	}
+
+	return s.GetUserByID(ctx, id)
+}
+
+func (s *ServiceImpl) ListUsers(ctx context.Context, filter models.UserFilter, page pagination.Request) ([]models.User, string, error) {
+	return s.data.ListUsers(ctx, filter, page)
+}
+
 func (s *ServiceImpl) DeleteUser(ctx context.Context, clerkID string) (bool, error) {
 	l := s.log.WithContext(ctx).With("operation", "DeleteUser")
 
@@ -113,7 +218,22 @@ func (s *ServiceImpl) DeleteUser(ctx context.Context, clerkID string) (bool, err
 		return false, err
 	}
 
-	deleted, err := s.data.DeleteUserByClerkID(ctx, clerkID)
+	var deleted bool
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		before, err := s.data.GetUserByClerkUserID(ctx, clerkID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			deleted = false
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if deleted, err = s.data.DeleteUserByClerkID(ctx, clerkID); err != nil || !deleted {
+			return err
+		}
+		return s.record(ctx, auditModels.ActionUserDeleted, before, nil)
+	})
 	if err != nil {
 		return false, err
 	}
@@ -141,3 +261,144 @@ func (s *ServiceImpl) UpdateUserOrganization(ctx context.Context, clerkUserID st
 
 	return updated, nil
 }
+
+// UpdateProfile applies update to the user; a non-zero version must be the
+// user's current one, else it fails with concurrency.ErrVersionConflict
+func (s *ServiceImpl) UpdateProfile(ctx context.Context, clerkUserID string, update models.UserProfileUpdate, version int64) (*models.User, error) {
+	l := s.log.WithContext(ctx).With("operation", "UpdateProfile")
+
+	if err := assertions.AssertNonEmptyString(clerkUserID); err != nil {
+		l.Debug("failed to validate clerk user id", "error", err)
+		return &models.User{}, err
+	}
+
+	var user *models.User
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		before, err := s.data.GetUserByClerkUserID(ctx, clerkUserID)
+		if err != nil {
+			return err
+		}
+		if user, err = s.data.UpdateUserProfile(ctx, clerkUserID, update, version); err != nil {
+			return err
+		}
+		return s.record(ctx, auditModels.ActionUserProfileUpdated, before, user)
+	})
+	if err != nil {
+		return &models.User{}, err
+	}
+
+	return user, nil
+}
+
+func (s *ServiceImpl) ListDeletedUsers(ctx context.Context) ([]models.User, error) {
+	return s.data.ListDeletedUsers(ctx)
+}
+
+func (s *ServiceImpl) RestoreUser(ctx context.Context, clerkID string) (bool, error) {
+	l := s.log.WithContext(ctx).With("operation", "RestoreUser")
+
+	if err := assertions.AssertNonEmptyString(clerkID); err != nil {
+		l.Debug("failed to validate clerk user id", "error", err)
+		return false, err
+	}
+
+	var restored bool
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		before, err := s.data.GetDeletedUserByClerkID(ctx, clerkID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			restored = false
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if restored, err = s.data.RestoreUserByClerkID(ctx, clerkID); err != nil || !restored {
+			return err
+		}
+		after, err := s.data.GetUserByClerkUserID(ctx, clerkID)
+		if err != nil {
+			return err
+		}
+		return s.record(ctx, auditModels.ActionUserRestored, before, after)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return restored, nil
+}
+
+func (s *ServiceImpl) PurgeUser(ctx context.Context, clerkID string) (bool, error) {
+	l := s.log.WithContext(ctx).With("operation", "PurgeUser")
+
+	if err := assertions.AssertNonEmptyString(clerkID); err != nil {
+		l.Debug("failed to validate clerk user id", "error", err)
+		return false, err
+	}
+
+	var purged bool
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		before, err := s.data.GetDeletedUserByClerkID(ctx, clerkID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			purged = false
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if purged, err = s.data.PurgeUserByClerkID(ctx, clerkID); err != nil || !purged {
+			return err
+		}
+		return s.record(ctx, auditModels.ActionUserPurged, before, nil)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return purged, nil
+}
+
+// PurgeDeletedUsers permanently deletes the users soft-deleted longer than
+// retention ago. The purge isn't audited per user; each deletion already was.
+func (s *ServiceImpl) PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int64, error) {
+	l := s.log.WithContext(ctx).With("operation", "PurgeDeletedUsers")
+
+	if retention <= 0 {
+		l.Debug("invalid retention", "retention", retention.String())
+		return 0, fmt.Errorf("retention must be positive, got %s", retention)
+	}
+
+	return s.data.PurgeDeletedUsersBefore(ctx, time.Now().Add(-retention))
+}
+
+// deletedUser is what the audit event of a deleted or purged user keeps: its
+// ids, without the personal data being deleted
+type deletedUser struct {
+	ID             string `json:"id"`
+	ClerkUserID    string `json:"clerk_user_id"`
+	OrganizationID string `json:"organization_id"`
+}
+
+// personalFields are the user fields whose values are kept out of audit events;
+// only the fact that they changed is recorded, so purging a user leaves none behind
+var personalFields = []string{"email", "first_name", "last_name", "profile_image_url"}
+
+// record audits a change to a user; before is nil for a created user and after
+// for a deleted one
+func (s *ServiceImpl) record(ctx context.Context, action string, before, after *models.User) error {
+	entry := auditModels.Entry{Action: action, ResourceType: auditModels.ResourceUser, Personal: personalFields}
+	if before != nil {
+		entry.Before = before
+		entry.ResourceID = before.ID
+		if after == nil {
+			entry.Before = &deletedUser{ID: before.ID, ClerkUserID: before.ClerkUserID, OrganizationID: before.OrganizationID}
+		}
+	}
+	if after != nil {
+		entry.After = after
+		entry.ResourceID = after.ID
+	}
+	return s.audit.Record(ctx, entry)
+}